@@ -0,0 +1,104 @@
+// Command systemd-local runs the systemd agent without faros-hub. It applies
+// the Systemd manifests in a directory and writes their status next to them,
+// e.g. nginx.status.yaml for nginx.yaml:
+//
+//	systemd-local --dir /etc/faros/systemd
+//
+// With --once the manifests are applied a single time, reconciled again while
+// units are becoming ready, and the command exits non-zero if any unit did not
+// converge.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/faroshq/plugin-services/pkg/agent/local"
+	"github.com/faroshq/plugin-services/pkg/agent/systemd"
+	servicesv1alpha1 "github.com/faroshq/plugin-services/pkg/apis/services/v1alpha1"
+	"github.com/faroshq/plugin-services/pkg/util/proc"
+)
+
+const shutdownTimeout = 30 * time.Second
+
+func main() {
+	dir := flag.String("dir", "", "directory with Systemd manifests")
+	once := flag.Bool("once", false, "apply the manifests once and exit, non-zero if any unit did not converge")
+	readyTimeout := flag.Duration("ready-timeout", 0, "with --once, how long to wait for units to become ready (default 15m)")
+	transport := flag.String("transport", string(servicesv1alpha1.TransportAuto), "how to connect to systemd: auto, system, private, user or address")
+	busAddress := flag.String("bus-address", "", "dbus address for the address transport, or an alternative socket for private")
+	resyncPeriod := flag.Duration("resync-period", 0, "how often converged manifests are applied again (default 5m)")
+	klog.InitFlags(nil)
+	flag.Parse()
+
+	if *dir == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(*dir, *once, *readyTimeout, servicesv1alpha1.Transport(*transport), *busAddress, *resyncPeriod); err != nil {
+		klog.Error(err)
+		os.Exit(1)
+	}
+}
+
+func run(dir string, once bool, readyTimeout time.Duration, transport servicesv1alpha1.Transport, busAddress string, resyncPeriod time.Duration) error {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	ctx = log.IntoContext(ctx, klog.NewKlogr())
+
+	dial, err := systemd.NewDialer(transport, busAddress)
+	if err != nil {
+		return err
+	}
+	conn := systemd.NewConnection(dial)
+	defer conn.Close()
+	userConns := systemd.NewUserConnections()
+	defer userConns.Close()
+
+	processes, err := proc.NewReader("")
+	if err != nil {
+		return err
+	}
+
+	reconciler := &systemd.Reconciler{
+		Connection:      conn,
+		UserConnections: userConns,
+		Processes:       processes,
+	}
+	reconciler.SetSettings(systemd.Settings{ResyncPeriod: resyncPeriod})
+	// Let a unit job started before the signal finish, like the plugin does.
+	defer func() {
+		waitCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := reconciler.Wait(waitCtx); err != nil {
+			reconciler.Abort()
+		}
+	}()
+
+	runner := &local.Runner{
+		Dir:          dir,
+		Reconciler:   reconciler,
+		ReadyTimeout: readyTimeout,
+	}
+	if !once {
+		return runner.Run(ctx)
+	}
+
+	converged, err := runner.RunOnce(ctx)
+	if err != nil {
+		return err
+	}
+	if !converged {
+		return fmt.Errorf("units in %s did not converge, see the status files for details", dir)
+	}
+	return nil
+}
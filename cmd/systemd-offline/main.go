@@ -0,0 +1,75 @@
+// Command systemd-offline applies Systemd manifests to the unit files of an
+// unbooted root filesystem, e.g. while building a device image:
+//
+//	systemd-offline --root /mnt/image -f systemd.yaml
+//
+// Only enablement and masking are applied, units are not started or stopped.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/faroshq/plugin-services/pkg/agent/systemd"
+)
+
+func main() {
+	root := flag.String("root", "", "directory the root filesystem to provision is mounted at")
+	file := flag.String("f", "", "file with Systemd manifests, - reads stdin")
+	flag.Parse()
+
+	if *root == "" || *file == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ok, err := run(context.Background(), *root, *file)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if !ok {
+		os.Exit(1)
+	}
+}
+
+// run applies the manifests in file to root. It reports whether all units
+// converged.
+func run(ctx context.Context, root, file string) (bool, error) {
+	var r io.Reader = os.Stdin
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return false, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	objects, err := systemd.ReadManifests(r)
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s: %w", file, err)
+	}
+
+	ok := true
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	defer w.Flush()
+	fmt.Fprintln(w, "OBJECT\tUNIT\tDESIRED\tSTATE\tERROR")
+	for _, object := range objects {
+		statuses, err := systemd.ApplyOffline(ctx, systemd.Rootfs{Root: root}, object.Spec)
+		if err != nil {
+			return false, fmt.Errorf("invalid Systemd %s: %w", object.Name, err)
+		}
+		for _, status := range statuses {
+			if status.Error != "" {
+				ok = false
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", object.Name, status.Name, status.DesiredStatus, status.Status, status.Error)
+		}
+	}
+	return ok, nil
+}
@@ -10,7 +10,7 @@ func main() {
 	plugin.Serve(&plugin.ServeConfig{
 		HandshakeConfig: shared.Handshake,
 		Plugins: map[string]plugin.Plugin{
-			"plugin": &shared.DevicePlugin{Impl: &farosplugin.SystemD{}},
+			"plugin": &shared.DevicePlugin{Impl: &farosplugin.SystemD{Options: farosplugin.OptionsFromEnv()}},
 		},
 
 		// A non-nil value here enables gRPC serving for this plugin...
@@ -0,0 +1,283 @@
+package local
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	conditionsv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/apis/conditions/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/util/conditions"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/yaml"
+
+	"github.com/faroshq/plugin-services/pkg/agent/systemd"
+	servicesv1alpha1 "github.com/faroshq/plugin-services/pkg/apis/services/v1alpha1"
+)
+
+const (
+	// statusSuffix is added to the name of a manifest, before its extension,
+	// to get the name of the file its status is written to.
+	statusSuffix = ".status"
+	// settleDelay is how long to wait after a change in the directory before
+	// reconciling, editors often write a file in several steps.
+	settleDelay = 500 * time.Millisecond
+	// retryPeriod is how soon manifests are reconciled again after an error.
+	retryPeriod = 10 * time.Second
+	// defaultReadyTimeout is how long RunOnce waits for units which are not
+	// ready yet, longer than the longest stable period of a readiness policy.
+	defaultReadyTimeout = 15 * time.Minute
+)
+
+// Runner reconciles Systemd manifests read from a directory, for devices which
+// are not managed through faros-hub. The status of the objects in a manifest
+// is written to a sibling file, e.g. nginx.status.yaml for nginx.yaml.
+type Runner struct {
+	// Dir holds the manifests, *.yaml, *.yml or *.json files with any number
+	// of Systemd objects each.
+	Dir        string
+	Reconciler *systemd.Reconciler
+	// ReadyTimeout bounds how long RunOnce waits for units with a readiness
+	// policy to become ready. Defaults to 15m.
+	ReadyTimeout time.Duration
+}
+
+// RunOnce applies every manifest, and reconciles them again while units are
+// still becoming ready, until ReadyTimeout. It reports whether all units
+// converged to their desired state.
+func (r *Runner) RunOnce(ctx context.Context) (bool, error) {
+	timeout := r.ReadyTimeout
+	if timeout <= 0 {
+		timeout = defaultReadyTimeout
+	}
+	deadline := time.Now().Add(timeout)
+
+	for {
+		failed, pending, requeueAfter, err := r.reconcileAll(ctx)
+		if err != nil || failed || !pending {
+			return !failed && !pending, err
+		}
+		if time.Now().Add(requeueAfter).After(deadline) {
+			return false, nil
+		}
+		timer := time.NewTimer(requeueAfter)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return false, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Run reconciles the manifests, again whenever a manifest changes and when
+// the reconciler asks for a resync, until ctx is done.
+func (r *Runner) Run(ctx context.Context) error {
+	logger := log.FromContext(ctx)
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+	if err := watcher.Add(r.Dir); err != nil {
+		return fmt.Errorf("failed to watch %s: %w", r.Dir, err)
+	}
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if !isManifest(event.Name) {
+				continue
+			}
+			if event.Op&(fsnotify.Remove|fsnotify.Rename) != 0 {
+				// Units are left as they are, like when a Systemd object is deleted.
+				if err := os.Remove(statusPath(event.Name)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+					logger.Error(err, "failed to remove status of removed manifest", "manifest", event.Name)
+				}
+			}
+			resetTimer(timer, settleDelay)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			logger.Error(err, "error watching manifests", "dir", r.Dir)
+		case <-timer.C:
+			_, _, requeueAfter, err := r.reconcileAll(ctx)
+			if err != nil {
+				logger.Error(err, "failed to reconcile manifests", "dir", r.Dir)
+			}
+			timer.Reset(requeueAfter)
+		}
+	}
+}
+
+// reconcileAll reconciles every manifest in the directory. It returns whether
+// units failed to converge, whether units are still becoming ready, and when
+// the manifests should be reconciled again.
+func (r *Runner) reconcileAll(ctx context.Context) (bool, bool, time.Duration, error) {
+	entries, err := os.ReadDir(r.Dir)
+	if err != nil {
+		return true, false, retryPeriod, err
+	}
+	var manifests []string
+	for _, entry := range entries {
+		if !entry.IsDir() && isManifest(entry.Name()) {
+			manifests = append(manifests, filepath.Join(r.Dir, entry.Name()))
+		}
+	}
+	sort.Strings(manifests)
+
+	failed, pending := false, false
+	var requeueAfter time.Duration
+	var errs []error
+	for _, manifest := range manifests {
+		fileFailed, filePending, after, err := r.reconcileFile(ctx, manifest)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", manifest, err))
+		}
+		failed = failed || fileFailed
+		pending = pending || filePending
+		if after > 0 && (requeueAfter == 0 || after < requeueAfter) {
+			requeueAfter = after
+		}
+	}
+	if requeueAfter == 0 {
+		// Nothing to reconcile, resync in case a manifest change was missed.
+		requeueAfter = retryPeriod
+	}
+	if len(errs) > 0 {
+		return true, pending, retryPeriod, utilerrors.NewAggregate(errs)
+	}
+	return failed, pending, requeueAfter, nil
+}
+
+// reconcileFile reconciles the objects of one manifest and writes their status.
+// It returns whether units failed to converge or are still becoming ready.
+func (r *Runner) reconcileFile(ctx context.Context, manifest string) (bool, bool, time.Duration, error) {
+	logger := log.FromContext(ctx).WithValues("manifest", manifest)
+
+	objects, err := readManifest(manifest)
+	if err != nil {
+		return true, false, retryPeriod, err
+	}
+	// The previous status keeps transition times and drift detection working
+	// across restarts.
+	previous, err := readManifest(statusPath(manifest))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		logger.Error(err, "ignoring unreadable status file")
+	}
+
+	failed, pending := false, false
+	var requeueAfter time.Duration
+	for i := range objects {
+		systemd := &objects[i]
+		for _, prev := range previous {
+			if prev.Name == systemd.Name && prev.Namespace == systemd.Namespace {
+				systemd.Status = prev.Status
+			}
+		}
+
+		// Failures are recorded in the Ready condition.
+		result, err := r.Reconciler.Apply(log.IntoContext(ctx, logger.WithValues("name", systemd.Name)), systemd)
+		if err != nil || result.Requeue {
+			result.RequeueAfter = retryPeriod
+		}
+		switch {
+		case conditions.IsTrue(systemd, conditionsv1alpha1.ReadyCondition):
+		case conditions.GetReason(systemd, conditionsv1alpha1.ReadyCondition) == servicesv1alpha1.UnitsNotReadyReason:
+			pending = true
+		default:
+			failed = true
+		}
+		if result.RequeueAfter > 0 && (requeueAfter == 0 || result.RequeueAfter < requeueAfter) {
+			requeueAfter = result.RequeueAfter
+		}
+	}
+
+	if err := writeStatus(statusPath(manifest), objects); err != nil {
+		return true, pending, retryPeriod, err
+	}
+	return failed, pending, requeueAfter, nil
+}
+
+func readManifest(path string) ([]servicesv1alpha1.Systemd, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return systemd.ReadManifests(f)
+}
+
+// writeStatus replaces the status file at path with objects.
+func writeStatus(path string, objects []servicesv1alpha1.Systemd) error {
+	var buf bytes.Buffer
+	for i := range objects {
+		data, err := yaml.Marshal(&objects[i])
+		if err != nil {
+			return err
+		}
+		buf.WriteString("---\n")
+		buf.Write(data)
+	}
+
+	// Write to a hidden file first, so readers never see a partial status and
+	// the watch ignores it.
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// isManifest reports whether the file at path is a manifest, as opposed to a
+// status or hidden file.
+func isManifest(path string) bool {
+	name := filepath.Base(path)
+	ext := filepath.Ext(name)
+	switch ext {
+	case ".yaml", ".yml", ".json":
+	default:
+		return false
+	}
+	return !strings.HasPrefix(name, ".") && !strings.HasSuffix(strings.TrimSuffix(name, ext), statusSuffix)
+}
+
+// statusPath returns the path of the status file of a manifest.
+func statusPath(manifest string) string {
+	return strings.TrimSuffix(manifest, filepath.Ext(manifest)) + statusSuffix + ".yaml"
+}
+
+func resetTimer(timer *time.Timer, d time.Duration) {
+	if !timer.Stop() {
+		select {
+		case <-timer.C:
+		default:
+		}
+	}
+	timer.Reset(d)
+}
@@ -0,0 +1,523 @@
+package process
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/coreos/go-systemd/v22/dbus"
+	"github.com/go-logr/logr"
+	conditionsv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/apis/conditions/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/util/conditions"
+	"github.com/kcp-dev/logicalcluster/v2"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	"github.com/faroshq/plugin-services/pkg/agent/systemd"
+	servicesv1alpha1 "github.com/faroshq/plugin-services/pkg/apis/services/v1alpha1"
+	"github.com/faroshq/plugin-services/pkg/util/proc"
+)
+
+const (
+	// sampleInterval is how often the processes watched by a guard are sampled.
+	sampleInterval = 10 * time.Second
+	defaultWindow  = time.Minute
+	minWindow      = 30 * time.Second
+	maxRunaways    = 50
+	restartTimeout = time.Minute
+)
+
+// Event reasons emitted on RunawayGuard objects, restarts are recorded with
+// systemd.ReasonRestarted.
+const (
+	ReasonRunawayDetected = "RunawayDetected"
+	ReasonTerminated      = "Terminated"
+)
+
+// GuardReconciler samples the processes selected by RunawayGuard objects and
+// acts on those exceeding a limit for the whole window.
+type GuardReconciler struct {
+	client.Client
+	Recorder record.EventRecorder
+	// Processes reads the process list.
+	Processes *proc.Reader
+	// Connection and UserConnections restart the units owning runaway
+	// processes. RestartUnit fails without them.
+	Connection      *systemd.Connection
+	UserConnections *systemd.UserConnections
+	// ProtectedUnits returns the units protected by the plugin configuration,
+	// their processes are never terminated.
+	ProtectedUnits func() sets.String
+
+	lock   sync.Mutex
+	guards map[guardKey]map[int]*processSample
+	// now is the clock samples are taken with, time.Now when nil.
+	now func() time.Time
+}
+
+type guardKey struct {
+	cluster, namespace, name string
+}
+
+// processSample is what a guard remembers about a watched process between
+// samples.
+type processSample struct {
+	startTime time.Time
+	cpuTime   time.Duration
+	sampled   time.Time
+	// exceeded holds when the process started exceeding each limit, and the
+	// usage at the time it became a runaway.
+	exceeded map[servicesv1alpha1.RunawayResource]time.Time
+	detected map[servicesv1alpha1.RunawayResource]int64
+	// action is the last action taken on the process.
+	action     servicesv1alpha1.RunawayAction
+	actionTime time.Time
+	actionErr  string
+}
+
+// +kubebuilder:rbac:groups=services.plugins.faros.sh,resources=runawayguards,verbs=get;list;watch
+// +kubebuilder:rbac:groups=services.plugins.faros.sh,resources=runawayguards/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+
+// Reconcile reconciles a RunawayGuard object
+func (r *GuardReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	logger = logger.WithValues("clusterName", req.ClusterName).WithValues("namespace", req.Namespace).WithValues("name", req.Name)
+	ctx = logicalcluster.WithCluster(ctx, logicalcluster.New(req.ClusterName))
+	key := guardKey{cluster: req.ClusterName, namespace: req.Namespace, name: req.Name}
+
+	var guard servicesv1alpha1.RunawayGuard
+	if err := r.Get(ctx, req.NamespacedName, &guard); err != nil {
+		if apierrors.IsNotFound(err) {
+			r.forget(key)
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+	if !guard.DeletionTimestamp.IsZero() {
+		r.forget(key)
+		return ctrl.Result{}, nil
+	}
+
+	patch := client.MergeFrom(guard.DeepCopy())
+	guard.Status.ObservedGeneration = guard.Generation
+
+	m, errs := validateGuard(guard.Spec)
+	if len(errs) > 0 {
+		err := errs.ToAggregate()
+		logger.Info("invalid runaway guard", "error", err.Error())
+		r.forget(key)
+		conditions.MarkFalse(&guard, conditionsv1alpha1.ReadyCondition, "InvalidSpec", conditionsv1alpha1.ConditionSeverityError, "%v", err)
+		conditions.Delete(&guard, servicesv1alpha1.RunawayDetectedCondition)
+		guard.Status.Runaways = nil
+		return ctrl.Result{}, r.Status().Patch(ctx, &guard, patch)
+	}
+
+	processes, err := r.Processes.List()
+	if err != nil {
+		logger.Error(err, "failed to read processes")
+		conditions.MarkFalse(&guard, conditionsv1alpha1.ReadyCondition, "FailedToReadProcesses", conditionsv1alpha1.ConditionSeverityError, "%v", err)
+		if err := r.Status().Patch(ctx, &guard, patch); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: sampleInterval}, nil
+	}
+
+	var watched []proc.Process
+	for _, p := range processes {
+		if m.selects(p) {
+			watched = append(watched, p)
+		}
+	}
+	runaways := r.sample(ctx, logger, key, &guard, watched)
+
+	guard.Status.WatchedProcesses = int32(len(watched))
+	guard.Status.Runaways = runaways
+	if len(runaways) > maxRunaways {
+		guard.Status.Runaways = runaways[:maxRunaways]
+	}
+	if len(runaways) > 0 {
+		conditions.MarkTrue(&guard, servicesv1alpha1.RunawayDetectedCondition)
+	} else {
+		conditions.MarkFalse(&guard, servicesv1alpha1.RunawayDetectedCondition, "WithinLimits", conditionsv1alpha1.ConditionSeverityInfo, "No process exceeds the limits")
+	}
+	conditions.MarkTrue(&guard, conditionsv1alpha1.ReadyCondition)
+	if err := r.Status().Patch(ctx, &guard, patch); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: sampleInterval}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager. The status update
+// of every sample does not trigger another reconcile, so samples are taken
+// every sampleInterval and cpu usage is measured over it.
+func (r *GuardReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&servicesv1alpha1.RunawayGuard{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Complete(r)
+}
+
+// sample records the usage of the watched processes, acts on runaways and
+// returns them ordered by pid. The actions run without holding the lock, a
+// restart waits for its systemd job.
+func (r *GuardReconciler) sample(ctx context.Context, logger logr.Logger, key guardKey, guard *servicesv1alpha1.RunawayGuard, watched []proc.Process) []servicesv1alpha1.RunawayProcess {
+	runaways, actions := r.record(logger, key, guard, watched)
+
+	restarted := sets.NewString()
+	for _, a := range actions {
+		var actionErr string
+		if err := r.act(ctx, logger, guard, a.process, restarted); err != nil {
+			actionErr = err.Error()
+		}
+		r.lock.Lock()
+		a.sample.actionErr = actionErr
+		r.lock.Unlock()
+		for i := range runaways {
+			if runaways[i].PID == int32(a.process.PID) {
+				runaways[i].ActionError = actionErr
+			}
+		}
+	}
+	return runaways
+}
+
+// pendingAction is an action to take on a runaway process once the lock is
+// released.
+type pendingAction struct {
+	process proc.Process
+	sample  *processSample
+}
+
+// record records the usage of the watched processes and returns the runaways
+// ordered by pid, and the actions to take on them.
+func (r *GuardReconciler) record(logger logr.Logger, key guardKey, guard *servicesv1alpha1.RunawayGuard, watched []proc.Process) ([]servicesv1alpha1.RunawayProcess, []pendingAction) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.guards == nil {
+		r.guards = map[guardKey]map[int]*processSample{}
+	}
+	previous := r.guards[key]
+	samples := make(map[int]*processSample, len(watched))
+	r.guards[key] = samples
+
+	window := guardWindow(guard.Spec)
+	now := time.Now()
+	if r.now != nil {
+		now = r.now()
+	}
+
+	var runaways []servicesv1alpha1.RunawayProcess
+	var actions []pendingAction
+	for _, p := range watched {
+		s, ok := previous[p.PID]
+		// A new process reusing the pid starts over.
+		if !ok || !s.startTime.Equal(p.StartTime) {
+			ok = false
+			s = &processSample{
+				startTime: p.StartTime,
+				exceeded:  map[servicesv1alpha1.RunawayResource]time.Time{},
+				detected:  map[servicesv1alpha1.RunawayResource]int64{},
+			}
+		}
+		usage := r.usage(guard.Spec, p, s, ok, now)
+		s.cpuTime, s.sampled = p.CPUTime, now
+		samples[p.PID] = s
+
+		var exceeded []servicesv1alpha1.RunawayResource
+		for _, resource := range []servicesv1alpha1.RunawayResource{
+			servicesv1alpha1.RunawayResourceCPU, servicesv1alpha1.RunawayResourceMemory, servicesv1alpha1.RunawayResourceOpenFDs,
+		} {
+			value, measured := usage[resource]
+			limit, limited := guardLimit(guard.Spec, resource)
+			if !measured || !limited || value <= limit {
+				delete(s.exceeded, resource)
+				delete(s.detected, resource)
+				continue
+			}
+			if _, ok := s.exceeded[resource]; !ok {
+				s.exceeded[resource] = now
+			}
+			if now.Sub(s.exceeded[resource]) < window {
+				continue
+			}
+			if _, ok := s.detected[resource]; !ok {
+				s.detected[resource] = value
+				logger.Info("runaway process detected", "pid", p.PID, "command", p.Comm, "unit", p.Unit, "resource", resource, "value", value, "limit", limit)
+				r.eventf(guard, corev1.EventTypeWarning, ReasonRunawayDetected, "Process %d (%s) exceeded the %s limit of %d for %s: %d",
+					p.PID, p.Comm, resource, limit, window, value)
+			}
+			exceeded = append(exceeded, resource)
+		}
+		if len(exceeded) == 0 {
+			continue
+		}
+
+		if s.actionTime.IsZero() || now.Sub(s.actionTime) >= window {
+			s.action, s.actionTime, s.actionErr = guard.Spec.Action, now, ""
+			actions = append(actions, pendingAction{process: p, sample: s})
+		}
+		for _, resource := range exceeded {
+			limit, _ := guardLimit(guard.Spec, resource)
+			runaway := servicesv1alpha1.RunawayProcess{
+				PID:         int32(p.PID),
+				Command:     p.Comm,
+				Unit:        owningUnit(p),
+				Resource:    resource,
+				Value:       s.detected[resource],
+				Limit:       limit,
+				Since:       metav1.NewTime(s.exceeded[resource]),
+				Action:      s.action,
+				ActionError: s.actionErr,
+			}
+			if !s.actionTime.IsZero() {
+				actionTime := metav1.NewTime(s.actionTime)
+				runaway.ActionTime = &actionTime
+			}
+			runaways = append(runaways, runaway)
+		}
+	}
+
+	sort.SliceStable(runaways, func(i, j int) bool {
+		return runaways[i].PID < runaways[j].PID
+	})
+	return runaways, actions
+}
+
+// usage returns the usage of p for every limit of the guard, cpu usage is only
+// known when the process was sampled before.
+func (r *GuardReconciler) usage(spec servicesv1alpha1.RunawayGuardSpec, p proc.Process, prev *processSample, sampled bool, now time.Time) map[servicesv1alpha1.RunawayResource]int64 {
+	usage := map[servicesv1alpha1.RunawayResource]int64{
+		servicesv1alpha1.RunawayResourceMemory: int64(p.RSS),
+	}
+	if sampled && now.After(prev.sampled) {
+		usage[servicesv1alpha1.RunawayResourceCPU] = int64((p.CPUTime - prev.cpuTime) * 100 / now.Sub(prev.sampled))
+	}
+	if spec.MaxOpenFDs != nil {
+		if fds, err := r.Processes.OpenFiles(p.PID); err == nil {
+			usage[servicesv1alpha1.RunawayResourceOpenFDs] = int64(fds)
+		}
+	}
+	return usage
+}
+
+// act takes the action of guard on the runaway process p. Units in restarted
+// were already restarted for another process and are skipped.
+func (r *GuardReconciler) act(ctx context.Context, logger logr.Logger, guard *servicesv1alpha1.RunawayGuard, p proc.Process, restarted sets.String) error {
+	switch guard.Spec.Action {
+	case servicesv1alpha1.RunawayActionTerminate:
+		// Never take down the device or the agent itself.
+		if protected(p, protectedUnits(r.ProtectedUnits)) {
+			return fmt.Errorf("refusing to terminate process %d", p.PID)
+		}
+		if err := syscall.Kill(p.PID, syscall.SIGTERM); err != nil && !errors.Is(err, syscall.ESRCH) {
+			r.eventf(guard, corev1.EventTypeWarning, ReasonFailed, "Failed to terminate runaway process %d (%s): %v", p.PID, p.Comm, err)
+			return err
+		}
+		logger.Info("terminated runaway process", "pid", p.PID, "command", p.Comm)
+		r.eventf(guard, corev1.EventTypeNormal, ReasonTerminated, "Sent SIGTERM to runaway process %d (%s)", p.PID, p.Comm)
+
+	case servicesv1alpha1.RunawayActionRestartUnit:
+		unit := owningUnit(p)
+		if unit == "" {
+			err := fmt.Errorf("process %d is not owned by a unit", p.PID)
+			r.eventf(guard, corev1.EventTypeWarning, ReasonFailed, "Failed to restart unit of runaway process %d (%s): %v", p.PID, p.Comm, err)
+			return err
+		}
+		// Restarting a scope stops it for good, and a user manager or a
+		// protected unit takes down more than the runaway service.
+		if !restartable(p, protectedUnits(r.ProtectedUnits)) {
+			err := fmt.Errorf("refusing to restart unit %s", unit)
+			r.eventf(guard, corev1.EventTypeWarning, ReasonFailed, "Failed to restart unit of runaway process %d (%s): %v", p.PID, p.Comm, err)
+			return err
+		}
+		restartKey := p.Unit + "/" + unit
+		if restarted.Has(restartKey) {
+			return nil
+		}
+		restarted.Insert(restartKey)
+		if err := r.restart(ctx, p, unit); err != nil {
+			r.eventf(guard, corev1.EventTypeWarning, ReasonFailed, "Failed to restart unit %s of runaway process %d (%s): %v", unit, p.PID, p.Comm, err)
+			return err
+		}
+		logger.Info("restarted unit of runaway process", "pid", p.PID, "command", p.Comm, "unit", unit)
+		r.eventf(guard, corev1.EventTypeNormal, systemd.ReasonRestarted, "Restarted unit %s of runaway process %d (%s)", unit, p.PID, p.Comm)
+	}
+	return nil
+}
+
+// restartable reports whether the unit owning p may be restarted: a user
+// service, or a system service which is neither a user manager, critical nor
+// in protectedUnits.
+func restartable(p proc.Process, protectedUnits sets.String) bool {
+	if p.UserUnit != "" {
+		return strings.HasSuffix(p.UserUnit, ".service")
+	}
+	return restartableUnit(p.Unit) && !protectedUnits.Has(p.Unit)
+}
+
+// restartableUnit reports whether a guard may restart the system unit.
+func restartableUnit(unit string) bool {
+	return strings.HasSuffix(unit, ".service") && !strings.HasPrefix(unit, "user@") && !criticalUnits.Has(unit)
+}
+
+// restart restarts unit in the manager owning p, the user manager for
+// processes in user units, and waits for the job to finish.
+func (r *GuardReconciler) restart(ctx context.Context, p proc.Process, unit string) error {
+	var conn *dbus.Conn
+	var err error
+	switch {
+	case p.UserUnit != "" && r.UserConnections != nil:
+		conn, err = r.UserConnections.Get(p.User)
+	case p.UserUnit != "":
+		err = errors.New("user units are not supported by this agent")
+	case r.Connection != nil:
+		conn, err = r.Connection.Get()
+	default:
+		err = errors.New("not connected to systemd")
+	}
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, restartTimeout)
+	defer cancel()
+	ch := make(chan string, 1)
+	if _, err := conn.RestartUnitContext(ctx, unit, "replace", ch); err != nil {
+		return err
+	}
+	select {
+	case result := <-ch:
+		if result != "done" {
+			return fmt.Errorf("restart job finished with result: %s", result)
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *GuardReconciler) forget(key guardKey) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	delete(r.guards, key)
+}
+
+func (r *GuardReconciler) eventf(guard *servicesv1alpha1.RunawayGuard, eventType, reason, messageFmt string, args ...interface{}) {
+	if r.Recorder == nil {
+		return
+	}
+	r.Recorder.Eventf(guard, eventType, reason, messageFmt, args...)
+}
+
+// guardMatcher selects the processes watched by a guard.
+type guardMatcher struct {
+	units sets.String
+	match *matcher
+}
+
+func (m guardMatcher) selects(p proc.Process) bool {
+	if p.KernelThread() {
+		return false
+	}
+	if m.units != nil && !m.units.Has(p.Unit) && !m.units.Has(p.UserUnit) {
+		return false
+	}
+	return m.match == nil || m.match.matches(p)
+}
+
+func validateGuard(spec servicesv1alpha1.RunawayGuardSpec) (guardMatcher, field.ErrorList) {
+	var errs field.ErrorList
+	path := field.NewPath("spec")
+
+	var m guardMatcher
+	if len(spec.Units) > 0 {
+		m.units = sets.NewString(spec.Units...)
+	}
+	if spec.Match != nil {
+		match, matchErrs := newMatcher(*spec.Match)
+		errs = append(errs, matchErrs...)
+		m.match = &match
+	}
+	switch {
+	case m.units == nil && m.match == nil:
+		errs = append(errs, field.Required(path, "units or match is required"))
+	case m.units == nil && !narrows(*spec.Match):
+		errs = append(errs, field.Required(path.Child("match"), fmt.Sprintf("an executable or a commandPattern requiring at least %d literal characters is required without units", minCommandLiterals)))
+	}
+	if spec.Action == servicesv1alpha1.RunawayActionRestartUnit {
+		for i, unit := range spec.Units {
+			if !restartableUnit(unit) {
+				errs = append(errs, field.Forbidden(path.Child("units").Index(i), "must be a service which is neither a user manager nor critical to the device when the action is RestartUnit"))
+			}
+		}
+	}
+
+	if spec.CPUPercent == nil && spec.MaxRSSBytes == nil && spec.MaxOpenFDs == nil {
+		errs = append(errs, field.Required(path, "at least one of cpuPercent, maxRSSBytes or maxOpenFDs is required"))
+	}
+	if spec.CPUPercent != nil && *spec.CPUPercent < 1 {
+		errs = append(errs, field.Invalid(path.Child("cpuPercent"), *spec.CPUPercent, "must be at least 1"))
+	}
+	if spec.MaxRSSBytes != nil && *spec.MaxRSSBytes < 1 {
+		errs = append(errs, field.Invalid(path.Child("maxRSSBytes"), *spec.MaxRSSBytes, "must be at least 1"))
+	}
+	if spec.MaxOpenFDs != nil && *spec.MaxOpenFDs < 1 {
+		errs = append(errs, field.Invalid(path.Child("maxOpenFDs"), *spec.MaxOpenFDs, "must be at least 1"))
+	}
+	if spec.Window != nil && spec.Window.Duration < minWindow {
+		errs = append(errs, field.Invalid(path.Child("window"), spec.Window.Duration.String(), "must be at least "+minWindow.String()))
+	}
+
+	switch spec.Action {
+	case "", servicesv1alpha1.RunawayActionEvent, servicesv1alpha1.RunawayActionTerminate, servicesv1alpha1.RunawayActionRestartUnit:
+	default:
+		errs = append(errs, field.NotSupported(path.Child("action"), spec.Action, []string{
+			string(servicesv1alpha1.RunawayActionEvent), string(servicesv1alpha1.RunawayActionTerminate), string(servicesv1alpha1.RunawayActionRestartUnit),
+		}))
+	}
+	return m, errs
+}
+
+func guardWindow(spec servicesv1alpha1.RunawayGuardSpec) time.Duration {
+	if spec.Window == nil {
+		return defaultWindow
+	}
+	return spec.Window.Duration
+}
+
+// guardLimit returns the limit of the guard for resource, if it has one.
+func guardLimit(spec servicesv1alpha1.RunawayGuardSpec, resource servicesv1alpha1.RunawayResource) (int64, bool) {
+	switch {
+	case resource == servicesv1alpha1.RunawayResourceCPU && spec.CPUPercent != nil:
+		return int64(*spec.CPUPercent), true
+	case resource == servicesv1alpha1.RunawayResourceMemory && spec.MaxRSSBytes != nil:
+		return *spec.MaxRSSBytes, true
+	case resource == servicesv1alpha1.RunawayResourceOpenFDs && spec.MaxOpenFDs != nil:
+		return int64(*spec.MaxOpenFDs), true
+	}
+	return 0, false
+}
+
+// owningUnit returns the unit to restart for p, the user unit for processes
+// of a user manager.
+func owningUnit(p proc.Process) string {
+	if p.UserUnit != "" {
+		return p.UserUnit
+	}
+	return p.Unit
+}
@@ -0,0 +1,302 @@
+package process
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	servicesv1alpha1 "github.com/faroshq/plugin-services/pkg/apis/services/v1alpha1"
+	"github.com/faroshq/plugin-services/pkg/util/proc"
+)
+
+func TestGuardSample(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	// process returns a process started seconds after start which used
+	// cpu seconds of cpu time and rss bytes of memory.
+	process := func(pid int, started, cpu int, rss uint64) proc.Process {
+		return proc.Process{
+			PID:       pid,
+			Comm:      "app",
+			Unit:      "app.service",
+			StartTime: start.Add(time.Duration(started) * time.Second),
+			CPUTime:   time.Duration(cpu) * time.Second,
+			RSS:       rss,
+		}
+	}
+	int32Ptr := func(i int32) *int32 { return &i }
+	int64Ptr := func(i int64) *int64 { return &i }
+
+	type step struct {
+		// at is the time of the sample, in seconds after start.
+		at        int
+		processes []proc.Process
+		want      []string
+	}
+
+	for _, tc := range []struct {
+		name  string
+		spec  servicesv1alpha1.RunawayGuardSpec
+		steps []step
+	}{{
+		name: "memory within the limit",
+		spec: servicesv1alpha1.RunawayGuardSpec{MaxRSSBytes: int64Ptr(100)},
+		steps: []step{
+			{at: 0, processes: []proc.Process{process(10, 0, 0, 100)}},
+			{at: 60, processes: []proc.Process{process(10, 0, 0, 100)}},
+			{at: 120, processes: []proc.Process{process(10, 0, 0, 50)}},
+		},
+	}, {
+		name: "memory over the limit for the whole window",
+		spec: servicesv1alpha1.RunawayGuardSpec{MaxRSSBytes: int64Ptr(100), Window: &metav1.Duration{Duration: 30 * time.Second}},
+		steps: []step{
+			{at: 0, processes: []proc.Process{process(10, 0, 0, 200)}},
+			{at: 10, processes: []proc.Process{process(10, 0, 0, 200)}},
+			{at: 30, processes: []proc.Process{process(10, 0, 0, 200)}, want: []string{"10 memory 200 since 0s, Event at 30s"}},
+			// The usage at detection is reported, the action is not repeated
+			// within the window.
+			{at: 40, processes: []proc.Process{process(10, 0, 0, 300)}, want: []string{"10 memory 200 since 0s, Event at 30s"}},
+			{at: 60, processes: []proc.Process{process(10, 0, 0, 300)}, want: []string{"10 memory 200 since 0s, Event at 1m0s"}},
+		},
+	}, {
+		name: "default window",
+		spec: servicesv1alpha1.RunawayGuardSpec{MaxRSSBytes: int64Ptr(100)},
+		steps: []step{
+			{at: 0, processes: []proc.Process{process(10, 0, 0, 200)}},
+			{at: 50, processes: []proc.Process{process(10, 0, 0, 200)}},
+			{at: 60, processes: []proc.Process{process(10, 0, 0, 200)}, want: []string{"10 memory 200 since 0s, Event at 1m0s"}},
+		},
+	}, {
+		name: "dropping below the limit starts over",
+		spec: servicesv1alpha1.RunawayGuardSpec{MaxRSSBytes: int64Ptr(100), Window: &metav1.Duration{Duration: 30 * time.Second}},
+		steps: []step{
+			{at: 0, processes: []proc.Process{process(10, 0, 0, 200)}},
+			{at: 20, processes: []proc.Process{process(10, 0, 0, 50)}},
+			{at: 40, processes: []proc.Process{process(10, 0, 0, 200)}},
+			{at: 60, processes: []proc.Process{process(10, 0, 0, 200)}},
+			{at: 70, processes: []proc.Process{process(10, 0, 0, 250)}, want: []string{"10 memory 250 since 40s, Event at 1m10s"}},
+			{at: 80, processes: []proc.Process{process(10, 0, 0, 50)}},
+		},
+	}, {
+		name: "cpu usage is measured between samples",
+		spec: servicesv1alpha1.RunawayGuardSpec{CPUPercent: int32Ptr(50), Window: &metav1.Duration{Duration: 30 * time.Second}},
+		steps: []step{
+			// The first sample has nothing to compare with.
+			{at: 0, processes: []proc.Process{process(10, 0, 100, 0)}},
+			{at: 10, processes: []proc.Process{process(10, 0, 108, 0)}},
+			{at: 20, processes: []proc.Process{process(10, 0, 116, 0)}},
+			{at: 40, processes: []proc.Process{process(10, 0, 132, 0)}, want: []string{"10 cpu 80 since 10s, Event at 40s"}},
+			{at: 50, processes: []proc.Process{process(10, 0, 133, 0)}},
+		},
+	}, {
+		name: "a new process reusing the pid starts over",
+		spec: servicesv1alpha1.RunawayGuardSpec{CPUPercent: int32Ptr(50), MaxRSSBytes: int64Ptr(100), Window: &metav1.Duration{Duration: 30 * time.Second}},
+		steps: []step{
+			{at: 0, processes: []proc.Process{process(10, 0, 0, 200)}},
+			{at: 30, processes: []proc.Process{process(10, 25, 50, 200)}},
+			{at: 60, processes: []proc.Process{process(10, 25, 50, 200)}, want: []string{"10 memory 200 since 30s, Event at 1m0s"}},
+		},
+	}, {
+		name: "exited processes are forgotten",
+		spec: servicesv1alpha1.RunawayGuardSpec{MaxRSSBytes: int64Ptr(100), Window: &metav1.Duration{Duration: 30 * time.Second}},
+		steps: []step{
+			{at: 0, processes: []proc.Process{process(10, 0, 0, 200)}},
+			{at: 10},
+			{at: 30, processes: []proc.Process{process(10, 0, 0, 200)}},
+			{at: 60, processes: []proc.Process{process(10, 0, 0, 200)}, want: []string{"10 memory 200 since 30s, Event at 1m0s"}},
+		},
+	}, {
+		name: "runaways are ordered by pid",
+		spec: servicesv1alpha1.RunawayGuardSpec{CPUPercent: int32Ptr(50), MaxRSSBytes: int64Ptr(100), Window: &metav1.Duration{Duration: 30 * time.Second}},
+		steps: []step{
+			{at: 0, processes: []proc.Process{process(20, 0, 0, 200), process(10, 0, 0, 200), process(30, 0, 0, 50)}},
+			{at: 10, processes: []proc.Process{process(20, 0, 0, 200), process(10, 0, 10, 200), process(30, 0, 10, 50)}},
+			{at: 40, processes: []proc.Process{process(20, 0, 0, 200), process(10, 0, 40, 200), process(30, 0, 20, 50)}, want: []string{
+				"10 cpu 100 since 10s, Event at 40s",
+				"10 memory 200 since 0s, Event at 40s",
+				"20 memory 200 since 0s, Event at 40s",
+			}},
+		},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			var now time.Time
+			r := &GuardReconciler{now: func() time.Time { return now }}
+			tc.spec.Action = servicesv1alpha1.RunawayActionEvent
+			guard := &servicesv1alpha1.RunawayGuard{Spec: tc.spec}
+			key := guardKey{cluster: "root", namespace: "default", name: "guard"}
+
+			for _, s := range tc.steps {
+				now = start.Add(time.Duration(s.at) * time.Second)
+				var got []string
+				for _, runaway := range r.sample(context.Background(), logr.Discard(), key, guard, s.processes) {
+					summary := fmt.Sprintf("%d %s %d since %s", runaway.PID, runaway.Resource, runaway.Value, runaway.Since.Sub(start))
+					if runaway.ActionTime != nil {
+						summary += fmt.Sprintf(", %s at %s", runaway.Action, runaway.ActionTime.Sub(start))
+					}
+					got = append(got, summary)
+				}
+				if !reflect.DeepEqual(got, s.want) {
+					t.Errorf("at %ds got %q, want %q", s.at, got, s.want)
+				}
+			}
+		})
+	}
+}
+
+func TestGuardRestart(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	process := func(pid int, unit, userUnit string) proc.Process {
+		return proc.Process{PID: pid, Comm: "app", Unit: unit, UserUnit: userUnit, StartTime: start, RSS: 200}
+	}
+	int64Ptr := func(i int64) *int64 { return &i }
+
+	// Without a systemd connection, restarting a unit fails with "not
+	// connected to systemd" once it was not refused.
+	const notConnected = "not connected to systemd"
+	for _, tc := range []struct {
+		name      string
+		processes []proc.Process
+		// want is the action error reported for each pid.
+		want map[int32]string
+	}{{
+		name:      "service",
+		processes: []proc.Process{process(10, "app.service", "")},
+		want:      map[int32]string{10: notConnected},
+	}, {
+		name:      "user service",
+		processes: []proc.Process{process(10, "user@1000.service", "app.service")},
+		want:      map[int32]string{10: "user units are not supported by this agent"},
+	}, {
+		name:      "unit restarted once for all its processes",
+		processes: []proc.Process{process(10, "app.service", ""), process(11, "app.service", ""), process(12, "other.service", "")},
+		want:      map[int32]string{10: notConnected, 11: "", 12: notConnected},
+	}, {
+		name:      "same unit name of other managers",
+		processes: []proc.Process{process(10, "app.service", ""), process(11, "user@1000.service", "app.service")},
+		want:      map[int32]string{10: notConnected, 11: "user units are not supported by this agent"},
+	}, {
+		name:      "scope",
+		processes: []proc.Process{process(10, "session-1.scope", "")},
+		want:      map[int32]string{10: "refusing to restart unit session-1.scope"},
+	}, {
+		name:      "user scope",
+		processes: []proc.Process{process(10, "user@1000.service", "app-1.scope")},
+		want:      map[int32]string{10: "refusing to restart unit app-1.scope"},
+	}, {
+		name:      "user manager",
+		processes: []proc.Process{process(10, "user@1000.service", "")},
+		want:      map[int32]string{10: "refusing to restart unit user@1000.service"},
+	}, {
+		name:      "critical unit",
+		processes: []proc.Process{process(10, "systemd-journald.service", "")},
+		want:      map[int32]string{10: "refusing to restart unit systemd-journald.service"},
+	}, {
+		name:      "protected unit",
+		processes: []proc.Process{process(10, "vendord.service", "")},
+		want:      map[int32]string{10: "refusing to restart unit vendord.service"},
+	}, {
+		name:      "process without unit",
+		processes: []proc.Process{process(10, "", "")},
+		want:      map[int32]string{10: "process 10 is not owned by a unit"},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			var now time.Time
+			r := &GuardReconciler{
+				now:            func() time.Time { return now },
+				ProtectedUnits: func() sets.String { return sets.NewString("vendord.service") },
+			}
+			guard := &servicesv1alpha1.RunawayGuard{Spec: servicesv1alpha1.RunawayGuardSpec{
+				MaxRSSBytes: int64Ptr(100),
+				Window:      &metav1.Duration{Duration: 30 * time.Second},
+				Action:      servicesv1alpha1.RunawayActionRestartUnit,
+			}}
+			key := guardKey{cluster: "root", namespace: "default", name: "guard"}
+
+			now = start
+			r.sample(context.Background(), logr.Discard(), key, guard, tc.processes)
+			now = start.Add(30 * time.Second)
+			got := map[int32]string{}
+			for _, runaway := range r.sample(context.Background(), logr.Discard(), key, guard, tc.processes) {
+				if runaway.Action != servicesv1alpha1.RunawayActionRestartUnit {
+					t.Errorf("process %d got action %q", runaway.PID, runaway.Action)
+				}
+				got[runaway.PID] = runaway.ActionError
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+			// The outcome is remembered until the action is repeated.
+			now = start.Add(40 * time.Second)
+			for _, runaway := range r.sample(context.Background(), logr.Discard(), key, guard, tc.processes) {
+				if runaway.ActionError != tc.want[runaway.PID] {
+					t.Errorf("process %d later reported %q, want %q", runaway.PID, runaway.ActionError, tc.want[runaway.PID])
+				}
+			}
+		})
+	}
+}
+
+func TestValidateGuard(t *testing.T) {
+	int64Ptr := func(i int64) *int64 { return &i }
+
+	for _, tc := range []struct {
+		name string
+		spec servicesv1alpha1.RunawayGuardSpec
+		want []string
+	}{{
+		name: "units",
+		spec: servicesv1alpha1.RunawayGuardSpec{Units: []string{"app.service"}, MaxRSSBytes: int64Ptr(100), Action: servicesv1alpha1.RunawayActionRestartUnit},
+	}, {
+		name: "narrowing match",
+		spec: servicesv1alpha1.RunawayGuardSpec{Match: &servicesv1alpha1.ProcessMatcher{CommandPattern: "vendord"}, MaxRSSBytes: int64Ptr(100), Action: servicesv1alpha1.RunawayActionTerminate},
+	}, {
+		name: "any command of units",
+		spec: servicesv1alpha1.RunawayGuardSpec{Units: []string{"app.service"}, Match: &servicesv1alpha1.ProcessMatcher{CommandPattern: "."}, MaxRSSBytes: int64Ptr(100), Action: servicesv1alpha1.RunawayActionTerminate},
+	}, {
+		name: "nothing selected",
+		spec: servicesv1alpha1.RunawayGuardSpec{MaxRSSBytes: int64Ptr(100)},
+		want: []string{"FieldValueRequired spec"},
+	}, {
+		name: "user only",
+		spec: servicesv1alpha1.RunawayGuardSpec{Match: &servicesv1alpha1.ProcessMatcher{User: "root"}, MaxRSSBytes: int64Ptr(100), Action: servicesv1alpha1.RunawayActionRestartUnit},
+		want: []string{"FieldValueRequired spec.match"},
+	}, {
+		name: "any command",
+		spec: servicesv1alpha1.RunawayGuardSpec{Match: &servicesv1alpha1.ProcessMatcher{CommandPattern: "."}, MaxRSSBytes: int64Ptr(100), Action: servicesv1alpha1.RunawayActionEvent},
+		want: []string{"FieldValueRequired spec.match"},
+	}, {
+		name: "units which are never restarted",
+		spec: servicesv1alpha1.RunawayGuardSpec{
+			Units:       []string{"app.service", "session-1.scope", "user@1000.service", "dbus.service"},
+			MaxRSSBytes: int64Ptr(100),
+			Action:      servicesv1alpha1.RunawayActionRestartUnit,
+		},
+		want: []string{"FieldValueForbidden spec.units[1]", "FieldValueForbidden spec.units[2]", "FieldValueForbidden spec.units[3]"},
+	}, {
+		name: "units which are never restarted are watched",
+		spec: servicesv1alpha1.RunawayGuardSpec{Units: []string{"session-1.scope", "dbus.service"}, MaxRSSBytes: int64Ptr(100), Action: servicesv1alpha1.RunawayActionEvent},
+	}, {
+		name: "no limit",
+		spec: servicesv1alpha1.RunawayGuardSpec{Units: []string{"app.service"}},
+		want: []string{"FieldValueRequired spec"},
+	}, {
+		name: "short window",
+		spec: servicesv1alpha1.RunawayGuardSpec{Units: []string{"app.service"}, MaxRSSBytes: int64Ptr(100), Window: &metav1.Duration{Duration: time.Second}},
+		want: []string{"FieldValueInvalid spec.window"},
+	}, {
+		name: "unknown action",
+		spec: servicesv1alpha1.RunawayGuardSpec{Units: []string{"app.service"}, MaxRSSBytes: int64Ptr(100), Action: "Reboot"},
+		want: []string{"FieldValueNotSupported spec.action"},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, errs := validateGuard(tc.spec); !reflect.DeepEqual(errorStrings(errs), tc.want) {
+				t.Errorf("got %q, want %q", errorStrings(errs), tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,292 @@
+package process
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	conditionsv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/apis/conditions/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/util/conditions"
+	"github.com/kcp-dev/logicalcluster/v2"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	servicesv1alpha1 "github.com/faroshq/plugin-services/pkg/apis/services/v1alpha1"
+	"github.com/faroshq/plugin-services/pkg/util/proc"
+	utilstrings "github.com/faroshq/plugin-services/pkg/util/strings"
+)
+
+const (
+	defaultRefreshPeriod = time.Minute
+	minRefreshPeriod     = 10 * time.Second
+	defaultMaxEntries    = 200
+	maxEntries           = 1000
+	// maxCmdlineLength bounds the command line of a listed process, they can
+	// be very long and the status has to fit in an object.
+	maxCmdlineLength = 256
+)
+
+// InventoryReconciler fills the status of ProcessInventory objects with the
+// processes running on the device.
+type InventoryReconciler struct {
+	client.Client
+	// Processes reads the process list.
+	Processes *proc.Reader
+}
+
+// +kubebuilder:rbac:groups=services.plugins.faros.sh,resources=processinventories,verbs=get;list;watch
+// +kubebuilder:rbac:groups=services.plugins.faros.sh,resources=processinventories/status,verbs=get;update;patch
+
+// Reconcile reconciles a ProcessInventory object
+func (r *InventoryReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	logger = logger.WithValues("clusterName", req.ClusterName).WithValues("namespace", req.Namespace).WithValues("name", req.Name)
+	ctx = logicalcluster.WithCluster(ctx, logicalcluster.New(req.ClusterName))
+
+	var inventory servicesv1alpha1.ProcessInventory
+	if err := r.Get(ctx, req.NamespacedName, &inventory); err != nil {
+		if apierrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+	if !inventory.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	patch := client.MergeFrom(inventory.DeepCopy())
+	inventory.Status.ObservedGeneration = inventory.Generation
+
+	filter, errs := newFilter(inventory.Spec.Filter)
+	errs = append(errs, validateInventory(inventory.Spec)...)
+	if len(errs) > 0 {
+		err := errs.ToAggregate()
+		logger.Info("invalid process inventory", "error", err.Error())
+		conditions.MarkFalse(&inventory, conditionsv1alpha1.ReadyCondition, "InvalidSpec", conditionsv1alpha1.ConditionSeverityError, "%v", err)
+		return ctrl.Result{}, r.Status().Patch(ctx, &inventory, patch)
+	}
+	period := refreshPeriod(inventory.Spec)
+
+	processes, err := r.Processes.List()
+	if err != nil {
+		logger.Error(err, "failed to read processes")
+		conditions.MarkFalse(&inventory, conditionsv1alpha1.ReadyCondition, "FailedToReadProcesses", conditionsv1alpha1.ConditionSeverityError, "%v", err)
+		if err := r.Status().Patch(ctx, &inventory, patch); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: period}, nil
+	}
+
+	fillInventory(&inventory.Status, processes, filter, inventory.Spec)
+	if inventory.Spec.ListeningSockets {
+		if err := r.addListening(&inventory.Status); err != nil {
+			// The process list is still worth reporting without the sockets.
+			logger.Info("failed to read listening sockets", "error", err.Error())
+		}
+	}
+	conditions.MarkTrue(&inventory, conditionsv1alpha1.ReadyCondition)
+	if err := r.Status().Patch(ctx, &inventory, patch); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: period}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *InventoryReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&servicesv1alpha1.ProcessInventory{}).
+		Complete(r)
+}
+
+func validateInventory(spec servicesv1alpha1.ProcessInventorySpec) field.ErrorList {
+	var errs field.ErrorList
+	path := field.NewPath("spec")
+
+	if spec.RefreshPeriod != nil && spec.RefreshPeriod.Duration < minRefreshPeriod {
+		errs = append(errs, field.Invalid(path.Child("refreshPeriod"), spec.RefreshPeriod.Duration.String(), "must be at least "+minRefreshPeriod.String()))
+	}
+	if spec.MaxEntries < 0 || spec.MaxEntries > maxEntries {
+		errs = append(errs, field.Invalid(path.Child("maxEntries"), spec.MaxEntries, "must be between 1 and 1000"))
+	}
+	switch spec.SortBy {
+	case "", servicesv1alpha1.ProcessSortMemory, servicesv1alpha1.ProcessSortCPU, servicesv1alpha1.ProcessSortPID:
+	default:
+		errs = append(errs, field.NotSupported(path.Child("sortBy"), spec.SortBy, []string{
+			string(servicesv1alpha1.ProcessSortMemory), string(servicesv1alpha1.ProcessSortCPU), string(servicesv1alpha1.ProcessSortPID),
+		}))
+	}
+	return errs
+}
+
+func refreshPeriod(spec servicesv1alpha1.ProcessInventorySpec) time.Duration {
+	if spec.RefreshPeriod == nil {
+		return defaultRefreshPeriod
+	}
+	return spec.RefreshPeriod.Duration
+}
+
+// filter is a compiled ProcessFilter.
+type filter struct {
+	users         sets.String
+	units         sets.String
+	command       *regexp.Regexp
+	minRSS        uint64
+	kernelThreads bool
+}
+
+func newFilter(spec *servicesv1alpha1.ProcessFilter) (filter, field.ErrorList) {
+	if spec == nil {
+		return filter{}, nil
+	}
+	var errs field.ErrorList
+	path := field.NewPath("spec", "filter")
+
+	f := filter{
+		kernelThreads: spec.IncludeKernelThreads,
+	}
+	if len(spec.Users) > 0 {
+		f.users = sets.NewString(spec.Users...)
+	}
+	if len(spec.Units) > 0 {
+		f.units = sets.NewString(spec.Units...)
+	}
+	if spec.CommandPattern != "" {
+		command, err := regexp.Compile(spec.CommandPattern)
+		if err != nil {
+			errs = append(errs, field.Invalid(path.Child("commandPattern"), spec.CommandPattern, err.Error()))
+		}
+		f.command = command
+	}
+	if spec.MinRSSBytes < 0 {
+		errs = append(errs, field.Invalid(path.Child("minRSSBytes"), spec.MinRSSBytes, "must not be negative"))
+	} else {
+		f.minRSS = uint64(spec.MinRSSBytes)
+	}
+	return f, errs
+}
+
+func (f filter) matches(p proc.Process) bool {
+	if p.KernelThread() && !f.kernelThreads {
+		return false
+	}
+	if f.users != nil && !f.users.Has(p.User) {
+		return false
+	}
+	if f.units != nil && !f.units.Has(p.Unit) && !f.units.Has(p.UserUnit) {
+		return false
+	}
+	if f.command != nil && !f.command.MatchString(commandLine(p)) {
+		return false
+	}
+	return p.RSS >= f.minRSS
+}
+
+// fillInventory records the processes matching f in status, sorted and capped
+// as the spec asks.
+func fillInventory(status *servicesv1alpha1.ProcessInventoryStatus, processes []proc.Process, f filter, spec servicesv1alpha1.ProcessInventorySpec) {
+	var matched []proc.Process
+	for _, p := range processes {
+		if f.matches(p) {
+			matched = append(matched, p)
+		}
+	}
+	sortProcesses(matched, spec.SortBy)
+
+	limit := int(spec.MaxEntries)
+	if limit <= 0 {
+		limit = defaultMaxEntries
+	}
+	now := metav1.Now()
+	status.LastUpdateTime = &now
+	status.TotalProcesses = int32(len(processes))
+	status.MatchedProcesses = int32(len(matched))
+	status.Truncated = len(matched) > limit
+	if status.Truncated {
+		matched = matched[:limit]
+	}
+
+	status.Processes = make([]servicesv1alpha1.ProcessInfo, 0, len(matched))
+	for _, p := range matched {
+		status.Processes = append(status.Processes, processInfo(p))
+	}
+}
+
+// addListening records the sockets each listed process listens on.
+func (r *InventoryReconciler) addListening(status *servicesv1alpha1.ProcessInventoryStatus) error {
+	pids := make([]int, 0, len(status.Processes))
+	index := make(map[int]int, len(status.Processes))
+	for i, p := range status.Processes {
+		pids = append(pids, int(p.PID))
+		index[int(p.PID)] = i
+	}
+	sockets, err := r.Processes.ListeningSockets(pids)
+	if err != nil {
+		return err
+	}
+	for _, s := range sockets {
+		p := &status.Processes[index[s.PID]]
+		p.Listening = append(p.Listening, servicesv1alpha1.ListeningSocket{
+			Protocol: s.Protocol,
+			Address:  s.Address,
+			Port:     int32(s.Port),
+			PID:      int32(s.PID),
+		})
+	}
+	return nil
+}
+
+func sortProcesses(processes []proc.Process, order servicesv1alpha1.ProcessSortOrder) {
+	var less func(a, b proc.Process) bool
+	switch order {
+	case servicesv1alpha1.ProcessSortCPU:
+		less = func(a, b proc.Process) bool { return a.CPUTime > b.CPUTime }
+	case servicesv1alpha1.ProcessSortPID:
+		less = func(a, b proc.Process) bool { return a.PID < b.PID }
+	default:
+		less = func(a, b proc.Process) bool { return a.RSS > b.RSS }
+	}
+	// The pid keeps the order stable between refreshes when values are equal.
+	sort.SliceStable(processes, func(i, j int) bool {
+		a, b := processes[i], processes[j]
+		if less(a, b) {
+			return true
+		}
+		if less(b, a) {
+			return false
+		}
+		return a.PID < b.PID
+	})
+}
+
+func processInfo(p proc.Process) servicesv1alpha1.ProcessInfo {
+	startTime := metav1.NewTime(p.StartTime)
+	cmdline := utilstrings.Truncate(commandLine(p), maxCmdlineLength)
+	return servicesv1alpha1.ProcessInfo{
+		PID:                 int32(p.PID),
+		PPID:                int32(p.PPID),
+		User:                p.User,
+		Command:             p.Comm,
+		Cmdline:             cmdline,
+		StartTime:           &startTime,
+		CPUTimeMilliseconds: p.CPUTime.Milliseconds(),
+		RSSBytes:            int64(p.RSS),
+		Unit:                p.Unit,
+		UserUnit:            p.UserUnit,
+	}
+}
+
+// commandLine returns the command line of p, or its command name in brackets
+// like ps when it has none, e.g. for kernel threads.
+func commandLine(p proc.Process) string {
+	if len(p.Cmdline) == 0 {
+		return "[" + p.Comm + "]"
+	}
+	return strings.Join(p.Cmdline, " ")
+}
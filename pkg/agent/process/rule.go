@@ -0,0 +1,425 @@
+package process
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/go-logr/logr"
+	conditionsv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/apis/conditions/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/util/conditions"
+	"github.com/kcp-dev/logicalcluster/v2"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	servicesv1alpha1 "github.com/faroshq/plugin-services/pkg/apis/services/v1alpha1"
+	"github.com/faroshq/plugin-services/pkg/util/proc"
+)
+
+const (
+	defaultCheckPeriod = 30 * time.Second
+	minCheckPeriod     = 10 * time.Second
+	// maxRulePIDs bounds the pids listed in the status of a rule.
+	maxRulePIDs = 50
+	// minCommandLiterals is the number of literal characters a command
+	// pattern must require to select the processes to kill.
+	minCommandLiterals = 3
+)
+
+// criticalUnits are system units whose processes are never signalled, the
+// device is unusable or unreachable without them.
+var criticalUnits = sets.NewString(
+	"init.scope",
+	"dbus.service",
+	"dbus-broker.service",
+	"systemd-journald.service",
+	"systemd-logind.service",
+	"systemd-udevd.service",
+	"systemd-networkd.service",
+	"systemd-resolved.service",
+	"NetworkManager.service",
+	"ssh.service",
+	"sshd.service",
+)
+
+// Event reasons emitted on ProcessRule objects.
+const (
+	ReasonProcessKilled = "ProcessKilled"
+	ReasonFailed        = "Failed"
+)
+
+// RuleReconciler evaluates ProcessRule objects against the processes running
+// on the device.
+type RuleReconciler struct {
+	client.Client
+	Recorder record.EventRecorder
+	// Processes reads the process list.
+	Processes *proc.Reader
+	// ProtectedUnits returns the units protected by the plugin configuration,
+	// their processes are never signalled.
+	ProtectedUnits func() sets.String
+}
+
+// +kubebuilder:rbac:groups=services.plugins.faros.sh,resources=processrules,verbs=get;list;watch
+// +kubebuilder:rbac:groups=services.plugins.faros.sh,resources=processrules/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+
+// Reconcile reconciles a ProcessRule object
+func (r *RuleReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	logger = logger.WithValues("clusterName", req.ClusterName).WithValues("namespace", req.Namespace).WithValues("name", req.Name)
+	ctx = logicalcluster.WithCluster(ctx, logicalcluster.New(req.ClusterName))
+
+	var rule servicesv1alpha1.ProcessRule
+	if err := r.Get(ctx, req.NamespacedName, &rule); err != nil {
+		if apierrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+	if !rule.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	patch := client.MergeFrom(rule.DeepCopy())
+	rule.Status.ObservedGeneration = rule.Generation
+
+	matcher, errs := newMatcher(rule.Spec.Match)
+	errs = append(errs, validateRule(rule.Spec)...)
+	if len(errs) > 0 {
+		err := errs.ToAggregate()
+		logger.Info("invalid process rule", "error", err.Error())
+		conditions.MarkFalse(&rule, conditionsv1alpha1.ReadyCondition, "InvalidSpec", conditionsv1alpha1.ConditionSeverityError, "%v", err)
+		conditions.Delete(&rule, servicesv1alpha1.RuleSatisfiedCondition)
+		return ctrl.Result{}, r.Status().Patch(ctx, &rule, patch)
+	}
+	period := checkPeriod(rule.Spec)
+
+	processes, err := r.Processes.List()
+	if err != nil {
+		logger.Error(err, "failed to read processes")
+		conditions.MarkFalse(&rule, conditionsv1alpha1.ReadyCondition, "FailedToReadProcesses", conditionsv1alpha1.ConditionSeverityError, "%v", err)
+		if err := r.Status().Patch(ctx, &rule, patch); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: period}, nil
+	}
+
+	var matched []proc.Process
+	for _, p := range processes {
+		if matcher.matches(p) {
+			matched = append(matched, p)
+		}
+	}
+	now := metav1.Now()
+	rule.Status.LastCheckTime = &now
+	rule.Status.MatchedProcesses = int32(len(matched))
+	rule.Status.PIDs = nil
+	for i, p := range matched {
+		if i == maxRulePIDs {
+			break
+		}
+		rule.Status.PIDs = append(rule.Status.PIDs, int32(p.PID))
+	}
+
+	r.evaluate(logger, &rule, matched)
+	conditions.MarkTrue(&rule, conditionsv1alpha1.ReadyCondition)
+	if err := r.Status().Patch(ctx, &rule, patch); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: period}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *RuleReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&servicesv1alpha1.ProcessRule{}).
+		Complete(r)
+}
+
+// evaluate records in the RuleSatisfied condition whether matched meet the
+// expectation of rule, killing forbidden processes when asked to.
+func (r *RuleReconciler) evaluate(logger logr.Logger, rule *servicesv1alpha1.ProcessRule, matched []proc.Process) {
+	expectation := rule.Spec.Expectation
+	count := int32(len(matched))
+
+	switch expectation.Type {
+	case servicesv1alpha1.ProcessMustRun:
+		minCount := int32(1)
+		if expectation.MinCount != nil {
+			minCount = *expectation.MinCount
+		}
+		switch {
+		case count < minCount:
+			conditions.MarkFalse(rule, servicesv1alpha1.RuleSatisfiedCondition, "TooFewProcesses", conditionsv1alpha1.ConditionSeverityWarning,
+				"%d matching processes running, at least %d expected", count, minCount)
+		case expectation.MaxCount != nil && count > *expectation.MaxCount:
+			conditions.MarkFalse(rule, servicesv1alpha1.RuleSatisfiedCondition, "TooManyProcesses", conditionsv1alpha1.ConditionSeverityWarning,
+				"%d matching processes running, at most %d expected", count, *expectation.MaxCount)
+		default:
+			conditions.MarkTrue(rule, servicesv1alpha1.RuleSatisfiedCondition)
+		}
+
+	case servicesv1alpha1.ProcessMustNotRun:
+		if count == 0 {
+			conditions.MarkTrue(rule, servicesv1alpha1.RuleSatisfiedCondition)
+			return
+		}
+		if !expectation.Kill {
+			conditions.MarkFalse(rule, servicesv1alpha1.RuleSatisfiedCondition, "ForbiddenProcessRunning", conditionsv1alpha1.ConditionSeverityWarning,
+				"%d forbidden processes running: %s", count, describe(matched))
+			return
+		}
+		killed, err := r.kill(logger, rule, matched)
+		if err != nil {
+			conditions.MarkFalse(rule, servicesv1alpha1.RuleSatisfiedCondition, "FailedToKill", conditionsv1alpha1.ConditionSeverityError,
+				"%d forbidden processes running, failed to kill: %v", count, err)
+			return
+		}
+		// The processes may ignore the signal, the next check tells.
+		conditions.MarkFalse(rule, servicesv1alpha1.RuleSatisfiedCondition, "ForbiddenProcessKilled", conditionsv1alpha1.ConditionSeverityWarning,
+			"Sent %s to %d forbidden processes: %s", killSignalName(expectation), len(killed), describe(killed))
+	}
+}
+
+// kill sends the kill signal of rule to processes. It returns the processes
+// signalled, processes which exited meanwhile are skipped.
+func (r *RuleReconciler) kill(logger logr.Logger, rule *servicesv1alpha1.ProcessRule, processes []proc.Process) ([]proc.Process, error) {
+	name := killSignalName(rule.Spec.Expectation)
+	signal, _ := proc.ParseSignal(name)
+
+	protectedUnits := protectedUnits(r.ProtectedUnits)
+	var killed []proc.Process
+	var errs []string
+	for _, p := range processes {
+		// Never take down the device or the agent enforcing the rule.
+		if protected(p, protectedUnits) {
+			continue
+		}
+		err := syscall.Kill(p.PID, signal)
+		if errors.Is(err, syscall.ESRCH) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%d: %v", p.PID, err))
+			r.eventf(rule, corev1.EventTypeWarning, ReasonFailed, "Failed to send %s to process %d (%s): %v", name, p.PID, p.Comm, err)
+			continue
+		}
+		logger.Info("killed forbidden process", "pid", p.PID, "command", p.Comm, "signal", name)
+		r.eventf(rule, corev1.EventTypeNormal, ReasonProcessKilled, "Sent %s to forbidden process %d (%s)", name, p.PID, p.Comm)
+		killed = append(killed, p)
+	}
+	if len(errs) > 0 {
+		return killed, errors.New(strings.Join(errs, ", "))
+	}
+	return killed, nil
+}
+
+// protected reports whether p must never be signalled: init, the agent, its
+// parent and its process group, processes directly in the root slice, which
+// belong to the system rather than to a service, and processes of critical
+// units or of units in protectedUnits.
+func protected(p proc.Process, protectedUnits sets.String) bool {
+	switch {
+	case p.PID == 1, p.PID == os.Getpid(), p.PID == os.Getppid(), p.PGID == syscall.Getpgrp():
+		return true
+	}
+	return p.Cgroup == "/" || criticalUnits.Has(p.Unit) || protectedUnits.Has(p.Unit)
+}
+
+// protectedUnits returns the units of get, none when it is nil.
+func protectedUnits(get func() sets.String) sets.String {
+	if get == nil {
+		return sets.NewString()
+	}
+	return get()
+}
+
+func (r *RuleReconciler) eventf(obj runtime.Object, eventType, reason, messageFmt string, args ...interface{}) {
+	if r.Recorder == nil {
+		return
+	}
+	r.Recorder.Eventf(obj, eventType, reason, messageFmt, args...)
+}
+
+func validateRule(spec servicesv1alpha1.ProcessRuleSpec) field.ErrorList {
+	var errs field.ErrorList
+	path := field.NewPath("spec")
+
+	if spec.CheckPeriod != nil && spec.CheckPeriod.Duration < minCheckPeriod {
+		errs = append(errs, field.Invalid(path.Child("checkPeriod"), spec.CheckPeriod.Duration.String(), "must be at least "+minCheckPeriod.String()))
+	}
+
+	expectation := spec.Expectation
+	expectationPath := path.Child("expectation")
+	switch expectation.Type {
+	case servicesv1alpha1.ProcessMustRun:
+		if expectation.Kill {
+			errs = append(errs, field.Invalid(expectationPath.Child("kill"), expectation.Kill, "only applies to MustNotRun"))
+		}
+		if expectation.MinCount != nil && *expectation.MinCount < 1 {
+			errs = append(errs, field.Invalid(expectationPath.Child("minCount"), *expectation.MinCount, "must be at least 1"))
+		}
+		if expectation.MaxCount != nil {
+			minCount := int32(1)
+			if expectation.MinCount != nil {
+				minCount = *expectation.MinCount
+			}
+			if *expectation.MaxCount < minCount {
+				errs = append(errs, field.Invalid(expectationPath.Child("maxCount"), *expectation.MaxCount, "must not be less than minCount"))
+			}
+		}
+	case servicesv1alpha1.ProcessMustNotRun:
+		if expectation.MinCount != nil || expectation.MaxCount != nil {
+			errs = append(errs, field.Forbidden(expectationPath, "minCount and maxCount only apply to MustRun"))
+		}
+		if expectation.Kill && !narrows(spec.Match) {
+			errs = append(errs, field.Required(path.Child("match"), fmt.Sprintf("an executable or a commandPattern requiring at least %d literal characters is required when kill is enabled", minCommandLiterals)))
+		}
+	default:
+		errs = append(errs, field.NotSupported(expectationPath.Child("type"), expectation.Type, []string{
+			string(servicesv1alpha1.ProcessMustRun), string(servicesv1alpha1.ProcessMustNotRun),
+		}))
+	}
+	if _, ok := proc.ParseSignal(killSignalName(expectation)); !ok {
+		errs = append(errs, field.NotSupported(expectationPath.Child("killSignal"), expectation.KillSignal, proc.SignalNames()))
+	}
+	return errs
+}
+
+func checkPeriod(spec servicesv1alpha1.ProcessRuleSpec) time.Duration {
+	if spec.CheckPeriod == nil {
+		return defaultCheckPeriod
+	}
+	return spec.CheckPeriod.Duration
+}
+
+func killSignalName(expectation servicesv1alpha1.ProcessExpectation) string {
+	if expectation.KillSignal == "" {
+		return "SIGTERM"
+	}
+	return expectation.KillSignal.String()
+}
+
+// matcher is a compiled ProcessMatcher.
+type matcher struct {
+	executable string
+	command    *regexp.Regexp
+	user       string
+}
+
+func newMatcher(spec servicesv1alpha1.ProcessMatcher) (matcher, field.ErrorList) {
+	var errs field.ErrorList
+	path := field.NewPath("spec", "match")
+
+	if spec.Executable == "" && spec.CommandPattern == "" && spec.User == "" {
+		errs = append(errs, field.Required(path, "at least one of executable, commandPattern or user is required"))
+	}
+	switch {
+	case spec.Executable == "":
+	case !filepath.IsAbs(spec.Executable):
+		errs = append(errs, field.Invalid(path.Child("executable"), spec.Executable, "must be an absolute path"))
+	case strings.ContainsAny(spec.Executable, "*?["):
+		errs = append(errs, field.Invalid(path.Child("executable"), spec.Executable, "must be a path, patterns are not supported"))
+	}
+	m := matcher{
+		executable: spec.Executable,
+		user:       spec.User,
+	}
+	if spec.CommandPattern != "" {
+		command, err := regexp.Compile(spec.CommandPattern)
+		if err != nil {
+			errs = append(errs, field.Invalid(path.Child("commandPattern"), spec.CommandPattern, err.Error()))
+		}
+		m.command = command
+	}
+	return m, errs
+}
+
+// narrows reports whether spec selects processes by their executable or by a
+// command pattern requiring at least minCommandLiterals literal characters.
+// A user alone, or a pattern like "." or "^/u", selects about every
+// process of the device.
+func narrows(spec servicesv1alpha1.ProcessMatcher) bool {
+	if spec.Executable != "" {
+		return true
+	}
+	if spec.CommandPattern == "" {
+		return false
+	}
+	re, err := syntax.Parse(spec.CommandPattern, syntax.Perl)
+	return err == nil && literals(re.Simplify()) >= minCommandLiterals
+}
+
+// literals returns the least number of literal characters a string matching
+// re contains.
+func literals(re *syntax.Regexp) int {
+	switch re.Op {
+	case syntax.OpLiteral:
+		return len(re.Rune)
+	case syntax.OpCapture, syntax.OpPlus:
+		return literals(re.Sub[0])
+	case syntax.OpRepeat:
+		return re.Min * literals(re.Sub[0])
+	case syntax.OpConcat:
+		n := 0
+		for _, sub := range re.Sub {
+			n += literals(sub)
+		}
+		return n
+	case syntax.OpAlternate:
+		n := -1
+		for _, sub := range re.Sub {
+			if l := literals(sub); n < 0 || l < n {
+				n = l
+			}
+		}
+		if n < 0 {
+			return 0
+		}
+		return n
+	}
+	return 0
+}
+
+func (m matcher) matches(p proc.Process) bool {
+	if p.KernelThread() {
+		return false
+	}
+	if m.executable != "" && p.Executable != m.executable {
+		return false
+	}
+	if m.user != "" && p.User != m.user {
+		return false
+	}
+	return m.command == nil || m.command.MatchString(commandLine(p))
+}
+
+// describe lists processes for a condition message, e.g. "1234 (vendord)".
+func describe(processes []proc.Process) string {
+	const maxDescribed = 10
+	var described []string
+	for i, p := range processes {
+		if i == maxDescribed {
+			described = append(described, fmt.Sprintf("and %d more", len(processes)-maxDescribed))
+			break
+		}
+		described = append(described, fmt.Sprintf("%d (%s)", p.PID, p.Comm))
+	}
+	return strings.Join(described, ", ")
+}
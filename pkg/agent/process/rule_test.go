@@ -0,0 +1,263 @@
+package process
+
+import (
+	"os"
+	"reflect"
+	"syscall"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	servicesv1alpha1 "github.com/faroshq/plugin-services/pkg/apis/services/v1alpha1"
+	"github.com/faroshq/plugin-services/pkg/util/proc"
+)
+
+func TestValidateRule(t *testing.T) {
+	int32Ptr := func(i int32) *int32 { return &i }
+	mustNotRun := func(match servicesv1alpha1.ProcessMatcher, kill bool) servicesv1alpha1.ProcessRuleSpec {
+		return servicesv1alpha1.ProcessRuleSpec{
+			Match:       match,
+			Expectation: servicesv1alpha1.ProcessExpectation{Type: servicesv1alpha1.ProcessMustNotRun, Kill: kill},
+		}
+	}
+
+	for _, tc := range []struct {
+		name string
+		spec servicesv1alpha1.ProcessRuleSpec
+		want []string
+	}{{
+		name: "must run",
+		spec: servicesv1alpha1.ProcessRuleSpec{
+			Match:       servicesv1alpha1.ProcessMatcher{Executable: "/usr/sbin/nginx"},
+			Expectation: servicesv1alpha1.ProcessExpectation{Type: servicesv1alpha1.ProcessMustRun, MinCount: int32Ptr(2), MaxCount: int32Ptr(4)},
+		},
+	}, {
+		name: "min count below 1",
+		spec: servicesv1alpha1.ProcessRuleSpec{
+			Match:       servicesv1alpha1.ProcessMatcher{Executable: "/usr/sbin/nginx"},
+			Expectation: servicesv1alpha1.ProcessExpectation{Type: servicesv1alpha1.ProcessMustRun, MinCount: int32Ptr(0)},
+		},
+		want: []string{"FieldValueInvalid spec.expectation.minCount"},
+	}, {
+		name: "max count below min count",
+		spec: servicesv1alpha1.ProcessRuleSpec{
+			Match:       servicesv1alpha1.ProcessMatcher{Executable: "/usr/sbin/nginx"},
+			Expectation: servicesv1alpha1.ProcessExpectation{Type: servicesv1alpha1.ProcessMustRun, MinCount: int32Ptr(3), MaxCount: int32Ptr(2)},
+		},
+		want: []string{"FieldValueInvalid spec.expectation.maxCount"},
+	}, {
+		name: "max count below the default min count",
+		spec: servicesv1alpha1.ProcessRuleSpec{
+			Match:       servicesv1alpha1.ProcessMatcher{Executable: "/usr/sbin/nginx"},
+			Expectation: servicesv1alpha1.ProcessExpectation{Type: servicesv1alpha1.ProcessMustRun, MaxCount: int32Ptr(0)},
+		},
+		want: []string{"FieldValueInvalid spec.expectation.maxCount"},
+	}, {
+		name: "kill only applies to must not run",
+		spec: servicesv1alpha1.ProcessRuleSpec{
+			Match:       servicesv1alpha1.ProcessMatcher{Executable: "/usr/sbin/nginx"},
+			Expectation: servicesv1alpha1.ProcessExpectation{Type: servicesv1alpha1.ProcessMustRun, Kill: true},
+		},
+		want: []string{"FieldValueInvalid spec.expectation.kill"},
+	}, {
+		name: "counts only apply to must run",
+		spec: servicesv1alpha1.ProcessRuleSpec{
+			Match:       servicesv1alpha1.ProcessMatcher{Executable: "/usr/sbin/nginx"},
+			Expectation: servicesv1alpha1.ProcessExpectation{Type: servicesv1alpha1.ProcessMustNotRun, MinCount: int32Ptr(1)},
+		},
+		want: []string{"FieldValueForbidden spec.expectation"},
+	}, {
+		name: "unknown expectation",
+		spec: servicesv1alpha1.ProcessRuleSpec{
+			Match:       servicesv1alpha1.ProcessMatcher{Executable: "/usr/sbin/nginx"},
+			Expectation: servicesv1alpha1.ProcessExpectation{Type: "MayRun"},
+		},
+		want: []string{"FieldValueNotSupported spec.expectation.type"},
+	}, {
+		name: "unknown kill signal",
+		spec: servicesv1alpha1.ProcessRuleSpec{
+			Match:       servicesv1alpha1.ProcessMatcher{Executable: "/usr/sbin/nginx"},
+			Expectation: servicesv1alpha1.ProcessExpectation{Type: servicesv1alpha1.ProcessMustNotRun, Kill: true, KillSignal: "SIGFOO"},
+		},
+		want: []string{"FieldValueNotSupported spec.expectation.killSignal"},
+	}, {
+		name: "check period too short",
+		spec: servicesv1alpha1.ProcessRuleSpec{
+			CheckPeriod: &metav1.Duration{Duration: time.Second},
+			Match:       servicesv1alpha1.ProcessMatcher{Executable: "/usr/sbin/nginx"},
+			Expectation: servicesv1alpha1.ProcessExpectation{Type: servicesv1alpha1.ProcessMustRun},
+		},
+		want: []string{"FieldValueInvalid spec.checkPeriod"},
+	}, {
+		name: "kill by executable",
+		spec: mustNotRun(servicesv1alpha1.ProcessMatcher{Executable: "/usr/bin/miner"}, true),
+	}, {
+		name: "kill by command pattern",
+		spec: mustNotRun(servicesv1alpha1.ProcessMatcher{CommandPattern: "^/opt/vendor/bin/updater( |$)"}, true),
+	}, {
+		name: "kill by user only",
+		spec: mustNotRun(servicesv1alpha1.ProcessMatcher{User: "root"}, true),
+		want: []string{"FieldValueRequired spec.match"},
+	}, {
+		name: "kill by any command",
+		spec: mustNotRun(servicesv1alpha1.ProcessMatcher{CommandPattern: "."}, true),
+		want: []string{"FieldValueRequired spec.match"},
+	}, {
+		name: "kill by any command of a user",
+		spec: mustNotRun(servicesv1alpha1.ProcessMatcher{CommandPattern: ".*", User: "root"}, true),
+		want: []string{"FieldValueRequired spec.match"},
+	}, {
+		name: "report any command without killing",
+		spec: mustNotRun(servicesv1alpha1.ProcessMatcher{CommandPattern: "."}, false),
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := errorStrings(validateRule(tc.spec)); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNewMatcher(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		spec servicesv1alpha1.ProcessMatcher
+		want []string
+	}{{
+		name: "executable",
+		spec: servicesv1alpha1.ProcessMatcher{Executable: "/usr/sbin/nginx"},
+	}, {
+		name: "empty",
+		want: []string{"FieldValueRequired spec.match"},
+	}, {
+		name: "relative executable",
+		spec: servicesv1alpha1.ProcessMatcher{Executable: "nginx"},
+		want: []string{"FieldValueInvalid spec.match.executable"},
+	}, {
+		name: "executable pattern",
+		spec: servicesv1alpha1.ProcessMatcher{Executable: "/usr/sbin/*"},
+		want: []string{"FieldValueInvalid spec.match.executable"},
+	}, {
+		name: "invalid command pattern",
+		spec: servicesv1alpha1.ProcessMatcher{CommandPattern: "(nginx"},
+		want: []string{"FieldValueInvalid spec.match.commandPattern"},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, errs := newMatcher(tc.spec); !reflect.DeepEqual(errorStrings(errs), tc.want) {
+				t.Errorf("got %q, want %q", errorStrings(errs), tc.want)
+			}
+		})
+	}
+}
+
+func TestNarrows(t *testing.T) {
+	for _, tc := range []struct {
+		spec servicesv1alpha1.ProcessMatcher
+		want bool
+	}{
+		{servicesv1alpha1.ProcessMatcher{}, false},
+		{servicesv1alpha1.ProcessMatcher{User: "root"}, false},
+		{servicesv1alpha1.ProcessMatcher{Executable: "/usr/bin/miner"}, true},
+		{servicesv1alpha1.ProcessMatcher{Executable: "/usr/bin/miner", User: "root"}, true},
+		{servicesv1alpha1.ProcessMatcher{CommandPattern: ""}, false},
+		{servicesv1alpha1.ProcessMatcher{CommandPattern: "."}, false},
+		{servicesv1alpha1.ProcessMatcher{CommandPattern: ".*"}, false},
+		{servicesv1alpha1.ProcessMatcher{CommandPattern: "^/"}, false},
+		{servicesv1alpha1.ProcessMatcher{CommandPattern: "^/u"}, false},
+		{servicesv1alpha1.ProcessMatcher{CommandPattern: "[a-z]+"}, false},
+		{servicesv1alpha1.ProcessMatcher{CommandPattern: "miner|."}, false},
+		{servicesv1alpha1.ProcessMatcher{CommandPattern: "(miner)?"}, false},
+		{servicesv1alpha1.ProcessMatcher{CommandPattern: "(", User: "root"}, false},
+		{servicesv1alpha1.ProcessMatcher{CommandPattern: "^/usr/"}, true},
+		{servicesv1alpha1.ProcessMatcher{CommandPattern: "miner"}, true},
+		{servicesv1alpha1.ProcessMatcher{CommandPattern: "(?i)MINER"}, true},
+		{servicesv1alpha1.ProcessMatcher{CommandPattern: "xmr|miner"}, true},
+		{servicesv1alpha1.ProcessMatcher{CommandPattern: "(ab)+c"}, true},
+		{servicesv1alpha1.ProcessMatcher{CommandPattern: "a{3}"}, true},
+		{servicesv1alpha1.ProcessMatcher{CommandPattern: "a.b.c"}, true},
+	} {
+		if got := narrows(tc.spec); got != tc.want {
+			t.Errorf("narrows(%+v) = %v, want %v", tc.spec, got, tc.want)
+		}
+	}
+}
+
+func TestMatcherMatches(t *testing.T) {
+	nginx := proc.Process{PID: 100, PPID: 1, User: "www-data", Comm: "nginx", Cmdline: []string{"/usr/sbin/nginx", "-g", "daemon off;"}, Executable: "/usr/sbin/nginx"}
+	zombie := proc.Process{PID: 101, PPID: 1, User: "root", Comm: "defunct"}
+	kthread := proc.Process{PID: 102, PPID: 2, User: "root", Comm: "kworker/0:1"}
+
+	for _, tc := range []struct {
+		name    string
+		spec    servicesv1alpha1.ProcessMatcher
+		process proc.Process
+		want    bool
+	}{
+		{"executable", servicesv1alpha1.ProcessMatcher{Executable: "/usr/sbin/nginx"}, nginx, true},
+		{"other executable", servicesv1alpha1.ProcessMatcher{Executable: "/usr/sbin/apache2"}, nginx, false},
+		{"user", servicesv1alpha1.ProcessMatcher{User: "www-data"}, nginx, true},
+		{"other user", servicesv1alpha1.ProcessMatcher{User: "root"}, nginx, false},
+		{"command pattern", servicesv1alpha1.ProcessMatcher{CommandPattern: "daemon off"}, nginx, true},
+		{"command pattern not matching", servicesv1alpha1.ProcessMatcher{CommandPattern: "^nginx"}, nginx, false},
+		{"all fields", servicesv1alpha1.ProcessMatcher{Executable: "/usr/sbin/nginx", CommandPattern: "-g", User: "www-data"}, nginx, true},
+		{"one field differs", servicesv1alpha1.ProcessMatcher{Executable: "/usr/sbin/nginx", CommandPattern: "-g", User: "root"}, nginx, false},
+		{"process without command line", servicesv1alpha1.ProcessMatcher{CommandPattern: `^\[defunct\]$`}, zombie, true},
+		{"kernel thread", servicesv1alpha1.ProcessMatcher{User: "root"}, kthread, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			m, errs := newMatcher(tc.spec)
+			if len(errs) > 0 {
+				t.Fatal(errs.ToAggregate())
+			}
+			if got := m.matches(tc.process); got != tc.want {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestProtected(t *testing.T) {
+	// pid and pgid are neither the test process nor its group.
+	const pid, pgid = 1 << 22, 1 << 22
+	process := func(unit string) proc.Process {
+		return proc.Process{PID: pid, PGID: pgid, Cgroup: "/system.slice/" + unit, Unit: unit}
+	}
+	configured := sets.NewString("vendord.service")
+
+	for _, tc := range []struct {
+		name    string
+		process proc.Process
+		want    bool
+	}{
+		{"service", process("nginx.service"), false},
+		{"init", proc.Process{PID: 1, PGID: 1, Cgroup: "/init.scope", Unit: "init.scope"}, true},
+		{"agent", proc.Process{PID: os.Getpid(), PGID: pgid, Cgroup: "/system.slice/faros.service", Unit: "faros.service"}, true},
+		{"agent parent", proc.Process{PID: os.Getppid(), PGID: pgid, Cgroup: "/system.slice/faros.service", Unit: "faros.service"}, true},
+		{"agent process group", proc.Process{PID: pid, PGID: syscall.Getpgrp(), Cgroup: "/system.slice/faros.service", Unit: "faros.service"}, true},
+		{"root cgroup", proc.Process{PID: pid, PGID: pgid, Cgroup: "/"}, true},
+		{"init scope", process("init.scope"), true},
+		{"journald", process("systemd-journald.service"), true},
+		{"dbus", process("dbus.service"), true},
+		{"sshd", process("sshd.service"), true},
+		{"configured unit", process("vendord.service"), true},
+		{"user unit of a protected name", proc.Process{PID: pid, PGID: pgid, Cgroup: "/user.slice/user-1000.slice/user@1000.service/app.slice/vendord.service", Unit: "user@1000.service", UserUnit: "vendord.service"}, false},
+		{"session", process("session-1.scope"), false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := protected(tc.process, configured); got != tc.want {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func errorStrings(errs field.ErrorList) []string {
+	var out []string
+	for _, err := range errs {
+		out = append(out, string(err.Type)+" "+err.Field)
+	}
+	return out
+}
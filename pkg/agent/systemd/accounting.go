@@ -0,0 +1,89 @@
+package systemd
+
+import (
+	"context"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/coreos/go-systemd/v22/dbus"
+	godbus "github.com/godbus/dbus/v5"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	servicesv1alpha1 "github.com/faroshq/plugin-services/pkg/apis/services/v1alpha1"
+)
+
+// resourcesUpdateInterval is how often the resource usage in the status of a
+// unit is refreshed. Reconciles in between keep the previous values, so
+// frequent reconciles do not turn into a stream of status updates.
+const resourcesUpdateInterval = time.Minute
+
+// accountingProperties are the properties turning on the accounting the
+// reported resources depend on.
+var accountingProperties = []string{"CPUAccounting", "MemoryAccounting", "TasksAccounting", "IOAccounting", "IPAccounting"}
+
+// enableAccounting turns on the accounting properties which are off for the
+// named unit. typeProps are its type specific properties.
+func (r *Reconciler) enableAccounting(ctx context.Context, conn *dbus.Conn, systemd *servicesv1alpha1.Systemd, name string, runtime bool, typeProps map[string]interface{}) error {
+	var properties []dbus.Property
+	var names []string
+	for _, property := range accountingProperties {
+		if enabled, ok := typeProps[property].(bool); ok && !enabled {
+			properties = append(properties, dbus.Property{Name: property, Value: godbus.MakeVariant(true)})
+			names = append(names, property)
+		}
+	}
+	if len(properties) == 0 {
+		return nil
+	}
+
+	if err := observeOperation("set_properties", conn.SetUnitPropertiesContext(ctx, name, runtime, properties...)); err != nil {
+		r.eventf(systemd, corev1.EventTypeWarning, ReasonFailed, "Failed to enable accounting for unit %s: %v", name, err)
+		return err
+	}
+	r.eventf(systemd, corev1.EventTypeNormal, ReasonAccountingEnabled, "Enabled %s for unit %s", strings.Join(names, ", "), name)
+	return nil
+}
+
+// unitResources returns the resource usage from the type specific properties
+// of a unit, or nil for units without accounting.
+func unitResources(typeProps map[string]interface{}) *servicesv1alpha1.UnitResources {
+	if typeProps == nil {
+		return nil
+	}
+	now := metav1.Now()
+	return &servicesv1alpha1.UnitResources{
+		CPUUsageNSec:       accounted(typeProps, "CPUUsageNSec"),
+		MemoryCurrentBytes: accounted(typeProps, "MemoryCurrent"),
+		MemoryPeakBytes:    accounted(typeProps, "MemoryPeak"),
+		TasksCurrent:       accounted(typeProps, "TasksCurrent"),
+		IPIngressBytes:     accounted(typeProps, "IPIngressBytes"),
+		IPEgressBytes:      accounted(typeProps, "IPEgressBytes"),
+		IOReadBytes:        accounted(typeProps, "IOReadBytes"),
+		LastUpdateTime:     &now,
+	}
+}
+
+// accounted returns the value of an accounting property, or nil when systemd
+// does not know it. systemd reports unknown values as UINT64_MAX.
+func accounted(typeProps map[string]interface{}, name string) *int64 {
+	value, ok := typeProps[name].(uint64)
+	if !ok || value == math.MaxUint64 || value > math.MaxInt64 {
+		return nil
+	}
+	v := int64(value)
+	return &v
+}
+
+// resourcesForStatus returns current, unless the previous status of the unit
+// was refreshed within resourcesUpdateInterval.
+func resourcesForStatus(current *servicesv1alpha1.UnitResources, prev *servicesv1alpha1.UnitStatus) *servicesv1alpha1.UnitResources {
+	if current == nil || prev == nil || prev.Resources == nil || prev.Resources.LastUpdateTime == nil {
+		return current
+	}
+	if time.Since(prev.Resources.LastUpdateTime.Time) < resourcesUpdateInterval {
+		return prev.Resources
+	}
+	return current
+}
@@ -0,0 +1,232 @@
+package systemd
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	servicesv1alpha1 "github.com/faroshq/plugin-services/pkg/apis/services/v1alpha1"
+)
+
+// Cache persists the last seen Systemd objects and their status on the
+// device, so the desired state can be enforced after a reboot while the hub
+// is unreachable, and status computed meanwhile is reported once it is back.
+// All methods are safe for concurrent use, every change is written to disk
+// before it returns.
+type Cache struct {
+	path string
+
+	lock     sync.Mutex
+	entries  map[cacheKey]*cacheEntry
+	revision uint64
+}
+
+type cacheKey struct {
+	Cluster   string `json:"cluster,omitempty"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+type cacheEntry struct {
+	cacheKey
+	Object servicesv1alpha1.Systemd `json:"object"`
+	// Pending is set when Object has a status the hub has not seen yet.
+	Pending bool `json:"pending,omitempty"`
+	// revision is unique for every store, to detect concurrent updates.
+	revision uint64
+}
+
+// CachedObject is a Systemd object held by the cache.
+type CachedObject struct {
+	Cluster string
+	Object  *servicesv1alpha1.Systemd
+	Pending bool
+	// Revision identifies this version of the entry, see StoreIfUnchanged.
+	Revision uint64
+}
+
+type cacheFile struct {
+	Entries []*cacheEntry `json:"entries"`
+}
+
+// NewCache returns a Cache stored at path, loading its previous content.
+func NewCache(path string) (*Cache, error) {
+	c := &Cache{
+		path:    path,
+		entries: map[cacheKey]*cacheEntry{},
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var file cacheFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	for _, entry := range file.Entries {
+		c.entries[entry.cacheKey] = entry
+	}
+	return c, nil
+}
+
+// Objects returns copies of all cached objects.
+func (c *Cache) Objects() []CachedObject {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	objects := make([]CachedObject, 0, len(c.entries))
+	for _, entry := range c.sortedEntries() {
+		objects = append(objects, CachedObject{
+			Cluster:  entry.Cluster,
+			Object:   entry.Object.DeepCopy(),
+			Pending:  entry.Pending,
+			Revision: entry.revision,
+		})
+	}
+	return objects
+}
+
+// Get returns a copy of the cached object, if any.
+func (c *Cache) Get(cluster, namespace, name string) (CachedObject, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	entry, ok := c.entries[cacheKey{Cluster: cluster, Namespace: namespace, Name: name}]
+	if !ok {
+		return CachedObject{}, false
+	}
+	return CachedObject{
+		Cluster:  entry.Cluster,
+		Object:   entry.Object.DeepCopy(),
+		Pending:  entry.Pending,
+		Revision: entry.revision,
+	}, true
+}
+
+// Store records systemd as last seen in cluster. pending marks a status the
+// hub has not seen yet.
+func (c *Cache) Store(cluster string, systemd *servicesv1alpha1.Systemd, pending bool) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.store(cluster, systemd, pending)
+	return c.save()
+}
+
+// StoreIfUnchanged is Store, unless the entry changed since revision was
+// returned by Objects. It reports whether systemd was stored.
+func (c *Cache) StoreIfUnchanged(cluster string, systemd *servicesv1alpha1.Systemd, pending bool, revision uint64) (bool, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	key := cacheKey{Cluster: cluster, Namespace: systemd.Namespace, Name: systemd.Name}
+	if entry, ok := c.entries[key]; !ok || entry.revision != revision {
+		return false, nil
+	}
+	c.store(cluster, systemd, pending)
+	return true, c.save()
+}
+
+// RecordSignal records in the cached status of systemd the outcome of the
+// signal request of a unit of user. Other cached status is kept, the object
+// is cached as given when it was not cached yet.
+func (c *Cache) RecordSignal(cluster string, systemd *servicesv1alpha1.Systemd, user, unit string, signal *servicesv1alpha1.SignalStatus) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	key := cacheKey{Cluster: cluster, Namespace: systemd.Namespace, Name: systemd.Name}
+	entry, ok := c.entries[key]
+	if !ok {
+		c.store(cluster, systemd, false)
+		entry = c.entries[key]
+	}
+	status := findUnitStatus(entry.Object.Status.Units, user, unit)
+	if status == nil {
+		entry.Object.Status.Units = append(entry.Object.Status.Units, servicesv1alpha1.UnitStatus{Name: unit, User: user})
+		status = &entry.Object.Status.Units[len(entry.Object.Status.Units)-1]
+	}
+	status.Signal = signal.DeepCopy()
+	c.revision++
+	entry.revision = c.revision
+	return c.save()
+}
+
+// Delete forgets an object, e.g. after it was deleted on the hub.
+func (c *Cache) Delete(cluster, namespace, name string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	key := cacheKey{Cluster: cluster, Namespace: namespace, Name: name}
+	if _, ok := c.entries[key]; !ok {
+		return nil
+	}
+	delete(c.entries, key)
+	return c.save()
+}
+
+func (c *Cache) store(cluster string, systemd *servicesv1alpha1.Systemd, pending bool) {
+	key := cacheKey{Cluster: cluster, Namespace: systemd.Namespace, Name: systemd.Name}
+	c.revision++
+	c.entries[key] = &cacheEntry{
+		cacheKey: key,
+		Object:   *systemd.DeepCopy(),
+		Pending:  pending,
+		revision: c.revision,
+	}
+}
+
+func (c *Cache) sortedEntries() []*cacheEntry {
+	entries := make([]*cacheEntry, 0, len(c.entries))
+	for _, entry := range c.entries {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i].cacheKey, entries[j].cacheKey
+		if a.Cluster != b.Cluster {
+			return a.Cluster < b.Cluster
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+	return entries
+}
+
+// save writes the cache to disk, replacing the previous file atomically.
+func (c *Cache) save() error {
+	data, err := json.Marshal(cacheFile{Entries: c.sortedEntries()})
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), "."+filepath.Base(c.path))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	// The file is what survives a power loss, make sure it is complete.
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), c.path)
+}
@@ -0,0 +1,229 @@
+package systemd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	servicesv1alpha1 "github.com/faroshq/plugin-services/pkg/apis/services/v1alpha1"
+)
+
+func cachedSystemd(namespace, name string, generation int64) *servicesv1alpha1.Systemd {
+	return &servicesv1alpha1.Systemd{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, Generation: generation},
+		Spec: servicesv1alpha1.SystemdSpec{Units: []servicesv1alpha1.Unit{{
+			Name:          "nginx.service",
+			DesiredStatus: servicesv1alpha1.ServiceStatusStarted,
+		}}},
+	}
+}
+
+// cacheSummary describes cached objects by cluster, name, generation and
+// whether they are pending.
+type cacheSummary struct {
+	cluster, namespace, name string
+	generation               int64
+	pending                  bool
+}
+
+func summarize(objects []CachedObject) []cacheSummary {
+	var out []cacheSummary
+	for _, o := range objects {
+		out = append(out, cacheSummary{o.Cluster, o.Object.Namespace, o.Object.Name, o.Object.Generation, o.Pending})
+	}
+	return out
+}
+
+func TestCacheLoad(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		data    *string
+		update  func(*Cache) error
+		want    []cacheSummary
+		wantErr bool
+	}{{
+		name: "missing file",
+	}, {
+		name:    "corrupt file",
+		data:    stringPtr("{not json"),
+		wantErr: true,
+	}, {
+		name: "stored objects",
+		update: func(c *Cache) error {
+			if err := c.Store("root:b", cachedSystemd("default", "web", 1), false); err != nil {
+				return err
+			}
+			if err := c.Store("root:a", cachedSystemd("default", "web", 2), true); err != nil {
+				return err
+			}
+			return c.Store("root:a", cachedSystemd("default", "db", 3), false)
+		},
+		want: []cacheSummary{
+			{"root:a", "default", "db", 3, false},
+			{"root:a", "default", "web", 2, true},
+			{"root:b", "default", "web", 1, false},
+		},
+	}, {
+		name: "replaced and deleted objects",
+		update: func(c *Cache) error {
+			if err := c.Store("root", cachedSystemd("default", "web", 1), true); err != nil {
+				return err
+			}
+			if err := c.Store("root", cachedSystemd("default", "web", 2), false); err != nil {
+				return err
+			}
+			if err := c.Store("root", cachedSystemd("default", "db", 1), false); err != nil {
+				return err
+			}
+			if err := c.Delete("root", "default", "unknown"); err != nil {
+				return err
+			}
+			return c.Delete("root", "default", "db")
+		},
+		want: []cacheSummary{{"root", "default", "web", 2, false}},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "state", "cache.json")
+			if tc.data != nil {
+				if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(*tc.data), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			c, err := NewCache(path)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+			if tc.update != nil {
+				if err := tc.update(c); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := summarize(c.Objects()); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+
+			loaded, err := NewCache(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := loaded.Objects()
+			if !reflect.DeepEqual(summarize(got), tc.want) {
+				t.Errorf("loaded %v, want %v", summarize(got), tc.want)
+			}
+			for i, o := range c.Objects() {
+				if !reflect.DeepEqual(o.Object.Spec, got[i].Object.Spec) {
+					t.Errorf("loaded spec %v, want %v", got[i].Object.Spec, o.Object.Spec)
+				}
+			}
+		})
+	}
+}
+
+func TestCacheStoreIfUnchanged(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		// prepare changes the cache after the revision was read.
+		prepare   func(*Cache) error
+		cached    bool
+		want      bool
+		wantGen   int64
+		wantCount int
+	}{{
+		name:      "unchanged",
+		cached:    true,
+		want:      true,
+		wantGen:   2,
+		wantCount: 1,
+	}, {
+		name:   "stored meanwhile",
+		cached: true,
+		prepare: func(c *Cache) error {
+			return c.Store("root", cachedSystemd("default", "web", 3), false)
+		},
+		wantGen:   3,
+		wantCount: 1,
+	}, {
+		name:   "deleted meanwhile",
+		cached: true,
+		prepare: func(c *Cache) error {
+			return c.Delete("root", "default", "web")
+		},
+	}, {
+		name:   "signal recorded meanwhile",
+		cached: true,
+		prepare: func(c *Cache) error {
+			return c.RecordSignal("root", cachedSystemd("default", "web", 1), "", "nginx.service", &servicesv1alpha1.SignalStatus{RequestID: "1"})
+		},
+		wantGen:   1,
+		wantCount: 1,
+	}, {
+		name: "not cached",
+	}, {
+		name:   "other object stored meanwhile",
+		cached: true,
+		prepare: func(c *Cache) error {
+			return c.Store("root", cachedSystemd("default", "db", 5), false)
+		},
+		want:      true,
+		wantGen:   2,
+		wantCount: 2,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := NewCache(filepath.Join(t.TempDir(), "cache.json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var revision uint64
+			if tc.cached {
+				if err := c.Store("root", cachedSystemd("default", "web", 1), true); err != nil {
+					t.Fatal(err)
+				}
+				cached, ok := c.Get("root", "default", "web")
+				if !ok {
+					t.Fatal("stored object not found")
+				}
+				revision = cached.Revision
+			}
+			if tc.prepare != nil {
+				if err := tc.prepare(c); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			stored, err := c.StoreIfUnchanged("root", cachedSystemd("default", "web", 2), false, revision)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stored != tc.want {
+				t.Errorf("got stored %v, want %v", stored, tc.want)
+			}
+			if n := len(c.Objects()); n != tc.wantCount {
+				t.Errorf("got %d objects, want %d", n, tc.wantCount)
+			}
+			current, ok := c.Get("root", "default", "web")
+			if ok != (tc.wantGen != 0) {
+				t.Fatalf("got cached %v, want %v", ok, tc.wantGen != 0)
+			}
+			if ok && current.Object.Generation != tc.wantGen {
+				t.Errorf("got generation %d, want %d", current.Object.Generation, tc.wantGen)
+			}
+			if ok && stored && (current.Pending || current.Revision == revision) {
+				t.Errorf("stored entry is pending %v with revision %d", current.Pending, current.Revision)
+			}
+		})
+	}
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
@@ -0,0 +1,166 @@
+package systemd
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	conditionsv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/apis/conditions/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/util/conditions"
+	"github.com/kcp-dev/logicalcluster/v2"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	servicesv1alpha1 "github.com/faroshq/plugin-services/pkg/apis/services/v1alpha1"
+)
+
+const minResyncPeriod = 10 * time.Second
+
+// ConfigReconciler applies the SystemdPluginConfig named after the plugin to
+// the running agent. Objects with other names are ignored.
+type ConfigReconciler struct {
+	client.Client
+
+	// Name and Namespace of the configuration object.
+	Name      string
+	Namespace string
+	// Defaults are the settings the plugin process was started with. They are
+	// used for fields the object does not set, or when it is deleted.
+	Defaults servicesv1alpha1.SystemdPluginConfigSpec
+
+	Reconciler *Reconciler
+	Connection *Connection
+
+	lock    sync.Mutex
+	applied *servicesv1alpha1.SystemdPluginConfigSpec
+}
+
+// +kubebuilder:rbac:groups=services.plugins.faros.sh,resources=systemdpluginconfigs,verbs=get;list;watch
+// +kubebuilder:rbac:groups=services.plugins.faros.sh,resources=systemdpluginconfigs/status,verbs=get;update;patch
+
+// Reconcile reconciles a SystemdPluginConfig object
+func (c *ConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	if req.Name != c.Name || req.Namespace != c.Namespace {
+		return ctrl.Result{}, nil
+	}
+
+	logger := log.FromContext(ctx)
+	logger = logger.WithValues("clusterName", req.ClusterName).WithValues("namespace", req.Namespace).WithValues("name", req.Name)
+	ctx = logicalcluster.WithCluster(ctx, logicalcluster.New(req.ClusterName))
+
+	var config servicesv1alpha1.SystemdPluginConfig
+	if err := c.Get(ctx, req.NamespacedName, &config); err != nil {
+		if apierrors.IsNotFound(err) {
+			logger.Info("plugin configuration removed, reverting to defaults")
+			return ctrl.Result{}, c.Apply(c.Defaults)
+		}
+		return ctrl.Result{}, err
+	}
+
+	patch := client.MergeFrom(config.DeepCopy())
+	config.Status.ObservedGeneration = config.Generation
+
+	spec := MergeConfig(c.Defaults, config.Spec)
+	if errs := ValidateConfig(spec); len(errs) > 0 {
+		err := errs.ToAggregate()
+		logger.Info("invalid plugin configuration, keeping current settings", "error", err.Error())
+		conditions.MarkFalse(&config, conditionsv1alpha1.ReadyCondition, "InvalidConfig", conditionsv1alpha1.ConditionSeverityError, "%v", err)
+		return ctrl.Result{}, c.Status().Patch(ctx, &config, patch)
+	}
+
+	if err := c.Apply(spec); err != nil {
+		conditions.MarkFalse(&config, conditionsv1alpha1.ReadyCondition, "FailedToApply", conditionsv1alpha1.ConditionSeverityError, "%v", err)
+		return ctrl.Result{}, c.Status().Patch(ctx, &config, patch)
+	}
+	logger.Info("applied plugin configuration")
+
+	conditions.MarkTrue(&config, conditionsv1alpha1.ReadyCondition)
+	return ctrl.Result{}, c.Status().Patch(ctx, &config, patch)
+}
+
+// Apply applies a validated configuration to the reconciler and connection.
+// The connection only reconnects when the transport changed.
+func (c *ConfigReconciler) Apply(spec servicesv1alpha1.SystemdPluginConfigSpec) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.applied == nil || c.applied.Transport != spec.Transport || c.applied.BusAddress != spec.BusAddress {
+		dial, err := NewDialer(spec.Transport, spec.BusAddress)
+		if err != nil {
+			return err
+		}
+		c.Connection.SetDialer(dial)
+	}
+
+	c.Reconciler.SetSettings(SettingsFromConfig(spec))
+	c.applied = spec.DeepCopy()
+	return nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (c *ConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&servicesv1alpha1.SystemdPluginConfig{}).
+		Complete(c)
+}
+
+// MergeConfig returns defaults with every field set in overrides replaced.
+func MergeConfig(defaults, overrides servicesv1alpha1.SystemdPluginConfigSpec) servicesv1alpha1.SystemdPluginConfigSpec {
+	merged := *defaults.DeepCopy()
+	if overrides.ResyncPeriod != nil {
+		merged.ResyncPeriod = overrides.ResyncPeriod.DeepCopy()
+	}
+	if overrides.ProtectedUnits != nil {
+		merged.ProtectedUnits = append([]string(nil), overrides.ProtectedUnits...)
+	}
+	if overrides.Transport != "" {
+		merged.Transport = overrides.Transport
+		merged.BusAddress = overrides.BusAddress
+	}
+	if overrides.MaxConcurrentReconciles != 0 {
+		merged.MaxConcurrentReconciles = overrides.MaxConcurrentReconciles
+	}
+	if overrides.MetricsBindAddress != "" {
+		merged.MetricsBindAddress = overrides.MetricsBindAddress
+	}
+	return merged
+}
+
+// ValidateConfig checks a plugin configuration before it is applied.
+func ValidateConfig(spec servicesv1alpha1.SystemdPluginConfigSpec) field.ErrorList {
+	var errs field.ErrorList
+	path := field.NewPath("spec")
+
+	if spec.ResyncPeriod != nil && spec.ResyncPeriod.Duration < minResyncPeriod {
+		errs = append(errs, field.Invalid(path.Child("resyncPeriod"), spec.ResyncPeriod.Duration.String(), "must be at least "+minResyncPeriod.String()))
+	}
+	for i, name := range spec.ProtectedUnits {
+		if !unitNameRegexp.MatchString(name) {
+			errs = append(errs, field.Invalid(path.Child("protectedUnits").Index(i), name, "must be a valid systemd unit name including the type suffix"))
+		}
+	}
+	if _, err := NewDialer(spec.Transport, spec.BusAddress); err != nil {
+		errs = append(errs, field.Invalid(path.Child("transport"), spec.Transport, err.Error()))
+	}
+	if spec.MaxConcurrentReconciles < 0 || spec.MaxConcurrentReconciles > MaxConcurrentReconciles {
+		errs = append(errs, field.Invalid(path.Child("maxConcurrentReconciles"), spec.MaxConcurrentReconciles, fmt.Sprintf("must be between 1 and %d", MaxConcurrentReconciles)))
+	}
+	return errs
+}
+
+// SettingsFromConfig returns the reconciler settings of a plugin configuration.
+func SettingsFromConfig(spec servicesv1alpha1.SystemdPluginConfigSpec) Settings {
+	settings := Settings{
+		ProtectedUnits:          sets.NewString(spec.ProtectedUnits...),
+		MaxConcurrentReconciles: spec.MaxConcurrentReconciles,
+	}
+	if spec.ResyncPeriod != nil {
+		settings.ResyncPeriod = spec.ResyncPeriod.Duration
+	}
+	return settings.withDefaults()
+}
@@ -0,0 +1,140 @@
+package systemd
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	servicesv1alpha1 "github.com/faroshq/plugin-services/pkg/apis/services/v1alpha1"
+)
+
+func TestMergeConfig(t *testing.T) {
+	defaults := servicesv1alpha1.SystemdPluginConfigSpec{
+		ResyncPeriod:            &metav1.Duration{Duration: time.Minute},
+		ProtectedUnits:          []string{"sshd.service"},
+		Transport:               servicesv1alpha1.TransportPrivate,
+		BusAddress:              "unix:path=/run/systemd/private",
+		MaxConcurrentReconciles: 2,
+		MetricsBindAddress:      ":8080",
+	}
+
+	for _, tc := range []struct {
+		name      string
+		overrides servicesv1alpha1.SystemdPluginConfigSpec
+		want      servicesv1alpha1.SystemdPluginConfigSpec
+	}{{
+		name: "no overrides",
+		want: defaults,
+	}, {
+		name: "all overrides",
+		overrides: servicesv1alpha1.SystemdPluginConfigSpec{
+			ResyncPeriod:            &metav1.Duration{Duration: time.Hour},
+			ProtectedUnits:          []string{"network.target", "sshd.service"},
+			Transport:               servicesv1alpha1.TransportAddress,
+			BusAddress:              "unix:path=/run/dbus/system_bus_socket",
+			MaxConcurrentReconciles: 4,
+			MetricsBindAddress:      ":9090",
+		},
+		want: servicesv1alpha1.SystemdPluginConfigSpec{
+			ResyncPeriod:            &metav1.Duration{Duration: time.Hour},
+			ProtectedUnits:          []string{"network.target", "sshd.service"},
+			Transport:               servicesv1alpha1.TransportAddress,
+			BusAddress:              "unix:path=/run/dbus/system_bus_socket",
+			MaxConcurrentReconciles: 4,
+			MetricsBindAddress:      ":9090",
+		},
+	}, {
+		name:      "empty protected units clear the defaults",
+		overrides: servicesv1alpha1.SystemdPluginConfigSpec{ProtectedUnits: []string{}},
+		want: servicesv1alpha1.SystemdPluginConfigSpec{
+			ResyncPeriod:            defaults.ResyncPeriod,
+			ProtectedUnits:          nil,
+			Transport:               defaults.Transport,
+			BusAddress:              defaults.BusAddress,
+			MaxConcurrentReconciles: defaults.MaxConcurrentReconciles,
+			MetricsBindAddress:      defaults.MetricsBindAddress,
+		},
+	}, {
+		name:      "transport replaces the bus address",
+		overrides: servicesv1alpha1.SystemdPluginConfigSpec{Transport: servicesv1alpha1.TransportSystem},
+		want: servicesv1alpha1.SystemdPluginConfigSpec{
+			ResyncPeriod:            defaults.ResyncPeriod,
+			ProtectedUnits:          defaults.ProtectedUnits,
+			Transport:               servicesv1alpha1.TransportSystem,
+			MaxConcurrentReconciles: defaults.MaxConcurrentReconciles,
+			MetricsBindAddress:      defaults.MetricsBindAddress,
+		},
+	}, {
+		name:      "bus address without transport is ignored",
+		overrides: servicesv1alpha1.SystemdPluginConfigSpec{BusAddress: "unix:path=/tmp/bus"},
+		want:      defaults,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := MergeConfig(defaults, tc.overrides)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+
+	t.Run("does not alias its inputs", func(t *testing.T) {
+		overrides := servicesv1alpha1.SystemdPluginConfigSpec{ProtectedUnits: []string{"network.target"}}
+		merged := MergeConfig(defaults, overrides)
+		merged.ResyncPeriod.Duration = time.Second
+		merged.ProtectedUnits[0] = "changed.service"
+		if defaults.ResyncPeriod.Duration != time.Minute || overrides.ProtectedUnits[0] != "network.target" {
+			t.Errorf("MergeConfig result shares memory with its inputs")
+		}
+	})
+}
+
+func TestValidateConfig(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		spec servicesv1alpha1.SystemdPluginConfigSpec
+		want []string
+	}{{
+		name: "empty",
+	}, {
+		name: "valid",
+		spec: servicesv1alpha1.SystemdPluginConfigSpec{
+			ResyncPeriod:            &metav1.Duration{Duration: minResyncPeriod},
+			ProtectedUnits:          []string{"sshd.service", "getty@tty1.service"},
+			Transport:               servicesv1alpha1.TransportAddress,
+			BusAddress:              "unix:path=/run/dbus/system_bus_socket",
+			MaxConcurrentReconciles: MaxConcurrentReconciles,
+		},
+	}, {
+		name: "resync period too short",
+		spec: servicesv1alpha1.SystemdPluginConfigSpec{ResyncPeriod: &metav1.Duration{Duration: time.Second}},
+		want: []string{"FieldValueInvalid spec.resyncPeriod"},
+	}, {
+		name: "invalid protected unit",
+		spec: servicesv1alpha1.SystemdPluginConfigSpec{ProtectedUnits: []string{"sshd.service", "sshd"}},
+		want: []string{"FieldValueInvalid spec.protectedUnits[1]"},
+	}, {
+		name: "unknown transport",
+		spec: servicesv1alpha1.SystemdPluginConfigSpec{Transport: "tcp"},
+		want: []string{"FieldValueInvalid spec.transport"},
+	}, {
+		name: "address transport without address",
+		spec: servicesv1alpha1.SystemdPluginConfigSpec{Transport: servicesv1alpha1.TransportAddress},
+		want: []string{"FieldValueInvalid spec.transport"},
+	}, {
+		name: "negative concurrency",
+		spec: servicesv1alpha1.SystemdPluginConfigSpec{MaxConcurrentReconciles: -1},
+		want: []string{"FieldValueInvalid spec.maxConcurrentReconciles"},
+	}, {
+		name: "concurrency too high",
+		spec: servicesv1alpha1.SystemdPluginConfigSpec{MaxConcurrentReconciles: MaxConcurrentReconciles + 1},
+		want: []string{"FieldValueInvalid spec.maxConcurrentReconciles"},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := errorStrings(ValidateConfig(tc.spec)); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got errors %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
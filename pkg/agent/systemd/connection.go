@@ -0,0 +1,222 @@
+package systemd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-systemd/v22/dbus"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	minReconnectDelay     = time.Second
+	maxReconnectDelay     = 2 * time.Minute
+	connectionCheckPeriod = 10 * time.Second
+)
+
+var (
+	errConnectionClosed = errors.New("systemd connection is closed")
+	errNoDialer         = errors.New("no systemd transport configured")
+)
+
+// Dialer opens a new connection to systemd. The connection is closed once ctx
+// is done.
+type Dialer func(ctx context.Context) (*dbus.Conn, error)
+
+// Connection owns the dbus connection to systemd shared by all reconciles.
+// A dropped connection, e.g. after `systemctl daemon-reexec` or a dbus
+// restart, is replaced on next use, with exponential backoff between failed
+// attempts.
+type Connection struct {
+	dial Dialer
+	// ctx bounds the lifetime of dialed connections, it is cancelled by Close.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	lock    sync.Mutex
+	conn    *dbus.Conn
+	err     error
+	delay   time.Duration
+	retryAt time.Time
+	closed  bool
+	// dialing is closed once the dial in progress, if any, finished. Dials
+	// run without holding lock, so a hanging dbus does not block callers which
+	// only check the connection.
+	dialing chan struct{}
+	// dialer counts SetDialer calls, to drop connections of a replaced dialer.
+	dialer uint64
+}
+
+// NewConnection returns a Connection which connects lazily using dial. dial
+// may be nil if it is set later with SetDialer.
+func NewConnection(dial Dialer) *Connection {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Connection{
+		dial:   dial,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Get returns the shared connection, reconnecting if it was lost. Callers must
+// not close the returned connection.
+func (c *Connection) Get() (*dbus.Conn, error) {
+	for {
+		c.lock.Lock()
+		if c.closed {
+			c.lock.Unlock()
+			return nil, errConnectionClosed
+		}
+		if c.conn != nil {
+			if c.conn.Connected() {
+				conn := c.conn
+				c.lock.Unlock()
+				return conn, nil
+			}
+			c.conn.Close()
+			c.conn = nil
+			c.err = errors.New("connection to systemd lost")
+		}
+		if wait := time.Until(c.retryAt); wait > 0 {
+			err := c.err
+			c.lock.Unlock()
+			return nil, fmt.Errorf("not connected to systemd, retrying in %s: %w", wait.Round(time.Second), err)
+		}
+		if c.dial == nil {
+			c.lock.Unlock()
+			return nil, errNoDialer
+		}
+		if dialing := c.dialing; dialing != nil {
+			// Use the outcome of the dial in progress.
+			c.lock.Unlock()
+			<-dialing
+			continue
+		}
+
+		dial, dialer, dialing := c.dial, c.dialer, make(chan struct{})
+		c.dialing = dialing
+		c.lock.Unlock()
+
+		conn, err := dial(c.ctx)
+		if conn, retry, err := c.dialed(conn, err, dialer, dialing); !retry {
+			return conn, err
+		}
+	}
+}
+
+// dialed records the outcome of a dial. It reports whether to get the
+// connection again, because the dialer was replaced meanwhile.
+func (c *Connection) dialed(conn *dbus.Conn, err error, dialer uint64, dialing chan struct{}) (*dbus.Conn, bool, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.dialing = nil
+	close(dialing)
+
+	if c.closed || dialer != c.dialer {
+		if conn != nil {
+			conn.Close()
+		}
+		if c.closed {
+			return nil, false, errConnectionClosed
+		}
+		return nil, true, nil
+	}
+	if err != nil {
+		c.err = err
+		c.delay *= 2
+		if c.delay < minReconnectDelay {
+			c.delay = minReconnectDelay
+		}
+		if c.delay > maxReconnectDelay {
+			c.delay = maxReconnectDelay
+		}
+		c.retryAt = time.Now().Add(c.delay)
+		return nil, false, err
+	}
+
+	c.conn = conn
+	c.err = nil
+	c.delay = 0
+	c.retryAt = time.Time{}
+	return conn, false, nil
+}
+
+// SetDialer switches to a different transport. The current connection is
+// closed and the next Get dials with the new dialer right away.
+func (c *Connection) SetDialer(dial Dialer) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.dial = dial
+	c.dialer++
+	if c.conn != nil {
+		c.conn.Close()
+		c.conn = nil
+	}
+	c.err = nil
+	c.delay = 0
+	c.retryAt = time.Time{}
+}
+
+// RetryIn returns how long until the next connection attempt is allowed.
+func (c *Connection) RetryIn() time.Duration {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if wait := time.Until(c.retryAt); wait > 0 {
+		return wait
+	}
+	return minReconnectDelay
+}
+
+// Close closes the connection. Get fails afterwards.
+func (c *Connection) Close() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.closed = true
+	c.cancel()
+	if c.conn != nil {
+		c.conn.Close()
+		c.conn = nil
+	}
+}
+
+// Start periodically checks the connection and reconnects when it was lost,
+// so the connection is back before the next reconcile needs it. It implements
+// manager.Runnable.
+func (c *Connection) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("systemd-connection")
+
+	ticker := time.NewTicker(connectionCheckPeriod)
+	defer ticker.Stop()
+
+	connected := true
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		_, err := c.Get()
+		switch {
+		case errors.Is(err, errConnectionClosed):
+			return nil
+		case err != nil && connected:
+			logger.Error(err, "lost connection to systemd")
+		case err == nil && !connected:
+			logger.Info("reconnected to systemd")
+		}
+		connected = err == nil
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable. Every agent
+// manages its own device.
+func (c *Connection) NeedLeaderElection() bool {
+	return false
+}
@@ -0,0 +1,186 @@
+package systemd
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/coreos/go-systemd/v22/dbus"
+	conditionsv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/apis/conditions/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/util/conditions"
+	corev1 "k8s.io/api/core/v1"
+
+	servicesv1alpha1 "github.com/faroshq/plugin-services/pkg/apis/services/v1alpha1"
+)
+
+const (
+	defaultCrashLoopWindow   = 5 * time.Minute
+	minCrashLoopWindow       = time.Minute
+	maxCrashLoopWindow       = time.Hour
+	defaultCrashLoopRestarts = 3
+	// crashLoopRecheck is how often a service which restarted within the
+	// window is checked again, so restarts are counted close to their time.
+	crashLoopRecheck = 30 * time.Second
+)
+
+// restartSample is the restart counter of a service at some time.
+type restartSample struct {
+	time  time.Time
+	count uint32
+}
+
+// handleCrashLoop counts the restarts of a service within the crash loop
+// window, records them and the CrashLooping condition in s, and stops the
+// service when the policy asks for it.
+func (r *Reconciler) handleCrashLoop(ctx context.Context, conn *dbus.Conn, systemd *servicesv1alpha1.Systemd, unit *servicesv1alpha1.Unit, prev *servicesv1alpha1.UnitStatus, s *status, key unitKey, restarts uint32) {
+	var policy servicesv1alpha1.CrashLoopPolicy
+	if unit.CrashLoop != nil {
+		policy = *unit.CrashLoop
+	}
+	window := crashLoopWindow(policy)
+	threshold := policy.Restarts
+	if threshold <= 0 {
+		threshold = defaultCrashLoopRestarts
+	}
+
+	recent := r.recordRestarts(key, restarts, window)
+	s.Restarts = int32(restarts)
+	s.RecentRestarts = recent
+	if recent > 0 {
+		s.checkIn(crashLoopRecheck)
+	}
+
+	hash := unitHash(unit)
+	if crashLoopHeld(prev, hash) {
+		s.CrashLoopHold = hash
+		s.Error = fmt.Errorf("unit %s was stopped after crash looping, change the unit to start it again", unit.Name)
+		setUnitCondition(&s.Conditions, prev, &conditionsv1alpha1.Condition{
+			Type:     servicesv1alpha1.CrashLoopingCondition,
+			Status:   corev1.ConditionTrue,
+			Severity: conditionsv1alpha1.ConditionSeverityError,
+			Reason:   "Stopped",
+			Message:  s.Error.Error(),
+		})
+		return
+	}
+	if recent < threshold {
+		setUnitCondition(&s.Conditions, prev, &conditionsv1alpha1.Condition{
+			Type:   servicesv1alpha1.CrashLoopingCondition,
+			Status: corev1.ConditionFalse,
+			Reason: "NotCrashLooping",
+		})
+		return
+	}
+
+	message := fmt.Sprintf("Restarted %d times within %s, %s", recent, window, describeExit(s.MainExit))
+	if prev == nil || !isTrue(prev.Conditions, servicesv1alpha1.CrashLoopingCondition) {
+		r.eventf(systemd, corev1.EventTypeWarning, ReasonCrashLooping, "Unit %s is crash looping: %s", unit.Name, message)
+	}
+	if policy.StopAfter != nil && recent >= *policy.StopAfter && s.Error == nil {
+		s.Error = r.runJob(ctx, systemd, unit.Name, "stop", ReasonStopped, func(ch chan<- string) (int, error) {
+			return conn.StopUnitContext(ctx, unit.Name, unit.ActivationMode.String(), ch)
+		})
+		if s.Error == nil {
+			s.CrashLoopHold = hash
+			s.Error = fmt.Errorf("unit %s was stopped after crash looping, change the unit to start it again", unit.Name)
+			message += ", stopped the unit"
+		}
+	}
+	setUnitCondition(&s.Conditions, prev, &conditionsv1alpha1.Condition{
+		Type:     servicesv1alpha1.CrashLoopingCondition,
+		Status:   corev1.ConditionTrue,
+		Severity: conditionsv1alpha1.ConditionSeverityWarning,
+		Reason:   "RestartedTooOften",
+		Message:  message,
+	})
+}
+
+// recordRestarts records the restart counter of a service and returns the
+// number of restarts within window. Only changes of the counter are kept, the
+// newest sample taken before the window is the baseline.
+func (r *Reconciler) recordRestarts(key unitKey, count uint32, window time.Duration) int32 {
+	r.crashLoopLock.Lock()
+	defer r.crashLoopLock.Unlock()
+	if r.restartSamples == nil {
+		r.restartSamples = map[unitKey][]restartSample{}
+	}
+
+	now := time.Now()
+	samples := r.restartSamples[key]
+	if n := len(samples); n > 0 && count < samples[n-1].count {
+		// systemd reset the counter, e.g. on reset-failed.
+		samples = nil
+	}
+	if n := len(samples); n == 0 || samples[n-1].count != count {
+		samples = append(samples, restartSample{time: now, count: count})
+	}
+	base := 0
+	for i, sample := range samples {
+		if now.Sub(sample.time) >= window {
+			base = i
+		}
+	}
+	samples = samples[base:]
+	r.restartSamples[key] = samples
+	return int32(count - samples[0].count)
+}
+
+// crashLoopHeld reports whether the agent stopped the unit after it crash
+// looped according to prev, and the unit was not changed since. The hold is
+// kept in the status, so it survives restarts of the agent.
+func crashLoopHeld(prev *servicesv1alpha1.UnitStatus, hash string) bool {
+	return prev != nil && prev.CrashLoopHoldUnitHash != "" && prev.CrashLoopHoldUnitHash == hash
+}
+
+// forgetCrashLoop drops the restart history of a unit which is no longer managed.
+func (r *Reconciler) forgetCrashLoop(key unitKey) {
+	r.crashLoopLock.Lock()
+	defer r.crashLoopLock.Unlock()
+	delete(r.restartSamples, key)
+}
+
+// setCrashLoopingCondition reports in the CrashLooping condition which units
+// are crash looping.
+func setCrashLoopingCondition(systemd *servicesv1alpha1.Systemd) {
+	var units []string
+	for _, unit := range systemd.Status.Units {
+		if isTrue(unit.Conditions, servicesv1alpha1.CrashLoopingCondition) {
+			units = append(units, fmt.Sprintf("%s (%d restarts)", describeUnit(unit.User, unit.Name), unit.RecentRestarts))
+		}
+	}
+	if len(units) == 0 {
+		conditions.Set(systemd, &conditionsv1alpha1.Condition{
+			Type:   servicesv1alpha1.CrashLoopingCondition,
+			Status: corev1.ConditionFalse,
+			Reason: "NotCrashLooping",
+		})
+		return
+	}
+	conditions.Set(systemd, &conditionsv1alpha1.Condition{
+		Type:     servicesv1alpha1.CrashLoopingCondition,
+		Status:   corev1.ConditionTrue,
+		Severity: conditionsv1alpha1.ConditionSeverityWarning,
+		Reason:   "UnitsCrashLooping",
+		Message:  "Crash looping units: " + strings.Join(units, ", "),
+	})
+}
+
+// unitHash returns a hash of the spec of a unit. Unlike the generation of the
+// object it changes only with the unit, and is also meaningful for objects
+// read from manifests or the state cache.
+func unitHash(unit *servicesv1alpha1.Unit) string {
+	data, _ := json.Marshal(unit)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+func crashLoopWindow(policy servicesv1alpha1.CrashLoopPolicy) time.Duration {
+	if policy.Window == nil {
+		return defaultCrashLoopWindow
+	}
+	return policy.Window.Duration
+}
@@ -0,0 +1,198 @@
+package systemd
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+
+	servicesv1alpha1 "github.com/faroshq/plugin-services/pkg/apis/services/v1alpha1"
+)
+
+func TestRecordRestarts(t *testing.T) {
+	key := unitKey{cluster: "root", namespace: "default", name: "web", unit: "nginx.service"}
+	type sample struct {
+		ago   time.Duration
+		count uint32
+	}
+
+	for _, tc := range []struct {
+		name    string
+		samples []sample
+		count   uint32
+		window  time.Duration
+		want    int32
+		// wantCounts are the counters kept afterwards, oldest first.
+		wantCounts []uint32
+	}{{
+		name:       "first sample",
+		count:      5,
+		window:     time.Minute,
+		want:       0,
+		wantCounts: []uint32{5},
+	}, {
+		name:       "unchanged counter",
+		samples:    []sample{{10 * time.Second, 5}},
+		count:      5,
+		window:     time.Minute,
+		want:       0,
+		wantCounts: []uint32{5},
+	}, {
+		name:       "restarts within the window",
+		samples:    []sample{{50 * time.Second, 1}, {30 * time.Second, 2}, {10 * time.Second, 3}},
+		count:      4,
+		window:     time.Minute,
+		want:       3,
+		wantCounts: []uint32{1, 2, 3, 4},
+	}, {
+		name:       "baseline is the newest sample before the window",
+		samples:    []sample{{5 * time.Minute, 1}, {2 * time.Minute, 4}, {30 * time.Second, 6}},
+		count:      7,
+		window:     time.Minute,
+		want:       3,
+		wantCounts: []uint32{4, 6, 7},
+	}, {
+		name:       "all samples before the window",
+		samples:    []sample{{5 * time.Minute, 1}, {2 * time.Minute, 4}},
+		count:      4,
+		window:     time.Minute,
+		want:       0,
+		wantCounts: []uint32{4},
+	}, {
+		name:       "sample at the window edge is the baseline",
+		samples:    []sample{{2 * time.Minute, 1}, {time.Minute, 2}},
+		count:      3,
+		window:     time.Minute,
+		want:       1,
+		wantCounts: []uint32{2, 3},
+	}, {
+		name:       "counter reset",
+		samples:    []sample{{30 * time.Second, 8}, {10 * time.Second, 9}},
+		count:      1,
+		window:     time.Minute,
+		want:       0,
+		wantCounts: []uint32{1},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := &Reconciler{}
+			if tc.samples != nil {
+				now := time.Now()
+				var samples []restartSample
+				for _, s := range tc.samples {
+					samples = append(samples, restartSample{time: now.Add(-s.ago), count: s.count})
+				}
+				r.restartSamples = map[unitKey][]restartSample{key: samples}
+			}
+
+			if got := r.recordRestarts(key, tc.count, tc.window); got != tc.want {
+				t.Errorf("got %d restarts, want %d", got, tc.want)
+			}
+			var counts []uint32
+			for _, s := range r.restartSamples[key] {
+				counts = append(counts, s.count)
+			}
+			if !reflect.DeepEqual(counts, tc.wantCounts) {
+				t.Errorf("kept counters %v, want %v", counts, tc.wantCounts)
+			}
+		})
+	}
+
+	t.Run("units are counted separately", func(t *testing.T) {
+		r := &Reconciler{}
+		other := key
+		other.user = "alice"
+		r.recordRestarts(key, 1, time.Minute)
+		r.recordRestarts(other, 10, time.Minute)
+		if got := r.recordRestarts(key, 3, time.Minute); got != 2 {
+			t.Errorf("got %d restarts, want 2", got)
+		}
+		if got := r.recordRestarts(other, 11, time.Minute); got != 1 {
+			t.Errorf("got %d restarts for another user's unit, want 1", got)
+		}
+	})
+}
+
+func TestCrashLoopHeld(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		prev *servicesv1alpha1.UnitStatus
+		hash string
+		want bool
+	}{
+		{"no status", nil, "abc", false},
+		{"not held", &servicesv1alpha1.UnitStatus{}, "abc", false},
+		{"not held and no hash", &servicesv1alpha1.UnitStatus{}, "", false},
+		{"held", &servicesv1alpha1.UnitStatus{CrashLoopHoldUnitHash: "abc"}, "abc", true},
+		{"unit changed", &servicesv1alpha1.UnitStatus{CrashLoopHoldUnitHash: "abc"}, "def", false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := crashLoopHeld(tc.prev, tc.hash); got != tc.want {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestHandleCrashLoop(t *testing.T) {
+	systemd := cachedSystemd("default", "web", 1)
+	held := unitHash(&systemd.Spec.Units[0])
+
+	for _, tc := range []struct {
+		name string
+		// hold is the hash held in the previous status.
+		hold       string
+		wantStatus corev1.ConditionStatus
+		wantReason string
+		wantHold   string
+		wantErr    bool
+	}{{
+		name:       "not crash looping",
+		wantStatus: corev1.ConditionFalse,
+		wantReason: "NotCrashLooping",
+	}, {
+		// After a restart of the agent nothing is known in memory, the hold
+		// is read back from the status.
+		name:       "held",
+		hold:       held,
+		wantStatus: corev1.ConditionTrue,
+		wantReason: "Stopped",
+		wantHold:   held,
+		wantErr:    true,
+	}, {
+		name:       "unit changed since",
+		hold:       "changed",
+		wantStatus: corev1.ConditionFalse,
+		wantReason: "NotCrashLooping",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := &Reconciler{Recorder: record.NewFakeRecorder(10)}
+			unit := systemd.Spec.Units[0].DeepCopy()
+			key := newUnitKey(context.Background(), systemd, unit.User, unit.Name)
+			prev := &servicesv1alpha1.UnitStatus{Name: unit.Name, CrashLoopHoldUnitHash: tc.hold}
+			s := &status{Name: unit.Name}
+
+			// Without a systemd connection stopping the unit panics.
+			r.handleCrashLoop(context.Background(), nil, systemd, unit, prev, s, key, 0)
+
+			var gotStatus corev1.ConditionStatus
+			var gotReason string
+			for _, c := range s.Conditions {
+				if c.Type == servicesv1alpha1.CrashLoopingCondition {
+					gotStatus, gotReason = c.Status, c.Reason
+				}
+			}
+			if gotStatus != tc.wantStatus || gotReason != tc.wantReason {
+				t.Errorf("got CrashLooping %s %s, want %s %s", gotStatus, gotReason, tc.wantStatus, tc.wantReason)
+			}
+			if s.CrashLoopHold != tc.wantHold {
+				t.Errorf("got hold %q, want %q", s.CrashLoopHold, tc.wantHold)
+			}
+			if (s.Error != nil) != tc.wantErr {
+				t.Errorf("got error %v, want error %v", s.Error, tc.wantErr)
+			}
+		})
+	}
+}
@@ -3,16 +3,21 @@ package systemd
 import (
 	"context"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/coreos/go-systemd/v22/dbus"
 	"github.com/davecgh/go-spew/spew"
-	"github.com/faroshq/plugin-services/pkg/apis/services/v1alpha1"
 	servicesv1alpha1 "github.com/faroshq/plugin-services/pkg/apis/services/v1alpha1"
 	"github.com/go-logr/logr"
 	conditionsv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/apis/conditions/v1alpha1"
 	"github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/util/conditions"
+	"github.com/kcp-dev/logicalcluster/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
 const (
@@ -22,22 +27,75 @@ const (
 
 func (r *Reconciler) createOrUpdate(ctx context.Context, logger logr.Logger, systemd *servicesv1alpha1.Systemd) (ctrl.Result, error) {
 	patch := client.MergeFrom(systemd.DeepCopy())
+	result, err := r.apply(ctx, logger, systemd)
+	if err != nil {
+		return result, err
+	}
+	// The status is current, replacing any reported from the cache.
+	conditions.Delete(systemd, servicesv1alpha1.DisconnectedCondition)
+	if err := r.Status().Patch(ctx, systemd, patch); err != nil {
+		r.cacheStatus(ctx, logger, systemd, true, err)
+		return ctrl.Result{}, err
+	}
+	r.cacheStatus(ctx, logger, systemd, false, nil)
+	return result, nil
+}
+
+// Apply moves the units of systemd to their desired state and records the
+// outcome in its status, without persisting the status. It is the core of
+// Reconcile, for agents which do not read their objects from the API server,
+// so the defaults of the API types are applied to the spec first.
+func (r *Reconciler) Apply(ctx context.Context, systemd *servicesv1alpha1.Systemd) (ctrl.Result, error) {
+	ctx, done := r.begin(ctx)
+	defer done()
+
+	setDefaults(&systemd.Spec)
+
+	return r.apply(ctx, log.FromContext(ctx), systemd)
+}
+
+func (r *Reconciler) apply(ctx context.Context, logger logr.Logger, systemd *servicesv1alpha1.Systemd) (ctrl.Result, error) {
 	conditions.MarkTrue(systemd, conditionsv1alpha1.ReadyCondition)
+	systemd.Status.ObservedGeneration = systemd.Generation
+
+	previous := systemd.Status.Units
+	systemd.Status.Units = make([]servicesv1alpha1.UnitStatus, 0, len(systemd.Spec.Units))
+
+	settings := r.settings()
 
-	systemd.Status.Units = make([]servicesv1alpha1.UnitStatus, len(systemd.Spec.Units))
+	if errs := validateSpec(systemd.Spec, settings.ProtectedUnits); len(errs) > 0 {
+		err := errs.ToAggregate()
+		logger.Info("invalid spec", "error", err.Error())
+		conditions.MarkFalse(systemd, conditionsv1alpha1.ReadyCondition, "InvalidSpec", conditionsv1alpha1.ConditionSeverityError, "%v", err)
+		// Requeueing will not help until the spec is fixed, which triggers a new reconcile.
+		return ctrl.Result{}, nil
+	}
 
-	conn, err := dbus.NewWithContext(ctx)
+	conn, err := r.Connection.Get()
 	if err != nil {
 		logger.Error(err, "failed to connect to systemd")
-		conditions.MarkFalse(systemd, conditionsv1alpha1.ReadyCondition, "FailedToConnect", "Failed to connect to systemd", err.Error())
-		return ctrl.Result{
-			Requeue: true,
-		}, err
+		conditions.MarkFalse(systemd, servicesv1alpha1.SystemdConnectedCondition, "Disconnected", conditionsv1alpha1.ConditionSeverityError, "%v", err)
+		conditions.MarkFalse(systemd, conditionsv1alpha1.ReadyCondition, "FailedToConnect", conditionsv1alpha1.ConditionSeverityError, "Failed to connect to systemd")
+		// Keep the status from the last successful reconcile, it is still the best
+		// information available.
+		systemd.Status.Units = previous
+		return ctrl.Result{RequeueAfter: r.Connection.RetryIn()}, nil
+	}
+	conditions.MarkTrue(systemd, servicesv1alpha1.SystemdConnectedCondition)
+
+	for _, prev := range previous {
+		if findUnit(systemd.Spec.Units, prev.User, prev.Name) == nil {
+			key := newUnitKey(ctx, systemd, prev.User, prev.Name)
+			deleteUnitMetrics(key)
+			r.forgetCrashLoop(key)
+			r.stopProber(key)
+		}
 	}
-	defer conn.Close()
 
+	requeueAfter := settings.ResyncPeriod
+	var failed, pending []string
 	for _, unit := range systemd.Spec.Units {
-		status, err := r.handleUnit(ctx, logger, conn, unit)
+		status, err := r.handleUnit(ctx, logger, conn, systemd, unit, findUnitStatus(previous, unit.User, unit.Name))
 		if err != nil {
 			logger.Error(err, "failed to handle unit", "unit", spew.Sdump(unit))
 			conditions.MarkFalse(systemd, conditionsv1alpha1.ReadyCondition, "FailedToHandleUnit", "Failed to handle unit", err.Error())
@@ -45,120 +103,383 @@ func (r *Reconciler) createOrUpdate(ctx context.Context, logger logr.Logger, sys
 				Requeue: true,
 			}, err
 		}
-		systemd.Status.Units = append(systemd.Status.Units, v1alpha1.UnitStatus{
-			Name:          unit.Name,
-			Status:        status.Status,
-			Error:         status.Error.Error(),
-			DesiredStatus: unit.DesiredStatus.String(),
-		})
+
+		now := metav1.Now()
+		unitStatus := servicesv1alpha1.UnitStatus{
+			Name:                  unit.Name,
+			User:                  unit.User,
+			Status:                status.Status,
+			DesiredStatus:         unit.DesiredStatus.String(),
+			LastTransitionTime:    &now,
+			ProcessCount:          status.Processes.count,
+			Processes:             status.Processes.list,
+			Zombies:               status.Processes.zombies,
+			Orphans:               status.Processes.orphans,
+			Listening:             status.Processes.listening,
+			Signal:                status.Signal,
+			Conditions:            status.Conditions,
+			Probes:                status.Probes,
+			MainExit:              status.MainExit,
+			Restarts:              status.Restarts,
+			RecentRestarts:        status.RecentRestarts,
+			CrashLoopHoldUnitHash: status.CrashLoopHold,
+			Recovery:              status.Recovery,
+		}
+		if status.Error != nil {
+			unitStatus.Error = status.Error.Error()
+			failed = append(failed, describeUnit(unit.User, unit.Name))
+		}
+		if status.RetryAfter > 0 && status.RetryAfter < requeueAfter {
+			requeueAfter = status.RetryAfter
+		}
+		if status.CheckIn > 0 && status.CheckIn < requeueAfter {
+			requeueAfter = status.CheckIn
+		}
+		if status.Pending {
+			pending = append(pending, describeUnit(unit.User, unit.Name))
+		}
+		unitStatus.Resources = resourcesForStatus(status.Resources, findUnitStatus(previous, unit.User, unit.Name))
+		prev := findUnitStatus(previous, unit.User, unit.Name)
+		if prev != nil && prev.LastTransitionTime != nil &&
+			prev.Status == unitStatus.Status && prev.Error == unitStatus.Error {
+			unitStatus.LastTransitionTime = prev.LastTransitionTime
+		}
+		switch {
+		case status.Applied:
+			unitStatus.LastAppliedTime = &now
+		case prev != nil:
+			unitStatus.LastAppliedTime = prev.LastAppliedTime
+		}
+		systemd.Status.Units = append(systemd.Status.Units, unitStatus)
 	}
 
-	if err := r.Status().Patch(ctx, systemd, patch); err != nil {
-		return ctrl.Result{}, err
+	if r.Processes != nil {
+		setZombiesCondition(systemd)
+	}
+	setCrashLoopingCondition(systemd)
+
+	switch {
+	case len(failed) > 0:
+		conditions.MarkFalse(systemd, conditionsv1alpha1.ReadyCondition, "UnitsFailed", conditionsv1alpha1.ConditionSeverityWarning,
+			"Failed to converge units: %s", strings.Join(failed, ", "))
+	case len(pending) > 0:
+		conditions.MarkFalse(systemd, conditionsv1alpha1.ReadyCondition, servicesv1alpha1.UnitsNotReadyReason, conditionsv1alpha1.ConditionSeverityInfo,
+			"Waiting for units to become ready: %s", strings.Join(pending, ", "))
 	}
-	return ctrl.Result{}, nil
+
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// findUnitStatus returns the status reported for the named unit of user, if
+// any.
+func findUnitStatus(units []servicesv1alpha1.UnitStatus, user, name string) *servicesv1alpha1.UnitStatus {
+	for i := range units {
+		if units[i].User == user && units[i].Name == name {
+			return &units[i]
+		}
+	}
+	return nil
+}
+
+// findUnit returns the spec of the named unit of user, if any.
+func findUnit(units []servicesv1alpha1.Unit, user, name string) *servicesv1alpha1.Unit {
+	for i := range units {
+		if units[i].User == user && units[i].Name == name {
+			return &units[i]
+		}
+	}
+	return nil
+}
+
+// unitKey identifies a unit of a Systemd object, for state the agent keeps in
+// memory.
+type unitKey struct {
+	cluster, namespace, name, user, unit string
+}
+
+func newUnitKey(ctx context.Context, systemd *servicesv1alpha1.Systemd, user, unit string) unitKey {
+	cluster, _ := logicalcluster.ClusterFromContext(ctx)
+	return unitKey{cluster: cluster.String(), namespace: systemd.Namespace, name: systemd.Name, user: user, unit: unit}
+}
+
+// describeUnit names a unit in messages, with its user for user units.
+func describeUnit(user, name string) string {
+	if user == "" {
+		return name
+	}
+	return name + " of user " + user
+}
+
+// setUnitCondition sets c in the conditions of a unit, keeping the transition
+// time reported before when the condition did not change.
+func setUnitCondition(to *conditionsv1alpha1.Conditions, prev *servicesv1alpha1.UnitStatus, c *conditionsv1alpha1.Condition) {
+	c.LastTransitionTime = metav1.Now()
+	if prev != nil {
+		for _, old := range prev.Conditions {
+			if old.Type == c.Type && old.Status == c.Status && old.Reason == c.Reason {
+				c.LastTransitionTime = old.LastTransitionTime
+			}
+		}
+	}
+	*to = append(*to, *c)
 }
 
 type status struct {
 	Name   string
 	Status string
 	Error  error
+	// RetryAfter is set when the unit could not be reached yet and should be
+	// retried before the next resync.
+	RetryAfter time.Duration
+	// Processes are the processes in the control group of the unit.
+	Processes unitProcesses
+	// Resources is the resource usage of the unit, nil for unit types without
+	// accounting.
+	Resources *servicesv1alpha1.UnitResources
+	// Signal is the outcome of the last signal request.
+	Signal *servicesv1alpha1.SignalStatus
+	// Conditions of the unit.
+	Conditions conditionsv1alpha1.Conditions
+	// Probes are the results of the probes of the unit.
+	Probes []servicesv1alpha1.ProbeStatus
+	// MainExit is how the main process of the unit last exited.
+	MainExit *servicesv1alpha1.ProcessExit
+	// Recovery records the attempts to start the unit again after it failed.
+	Recovery *servicesv1alpha1.RecoveryStatus
+	// Restarts is the restart counter of a service, RecentRestarts the
+	// restarts within the crash loop window.
+	Restarts, RecentRestarts int32
+	// CrashLoopHold is the hash of the unit when the agent stopped it after
+	// it crash looped, empty when the unit is not held.
+	CrashLoopHold string
+	// Applied is set when the unit had to be changed to reach its desired
+	// state.
+	Applied bool
+	// Pending is set while a unit with a readiness policy is not ready yet.
+	Pending bool
+	// CheckIn is when the health or readiness of the unit is due to be
+	// checked again.
+	CheckIn time.Duration
+}
+
+// checkIn requeues the unit after d unless it is requeued earlier already.
+func (s *status) checkIn(d time.Duration) {
+	if d > 0 && (s.CheckIn == 0 || d < s.CheckIn) {
+		s.CheckIn = d
+	}
 }
 
 // handleUnit handles a single unit. It returns error if overall operation failed.
 // It will return individual service status in status object and it should be handled by caller.
-func (r *Reconciler) handleUnit(ctx context.Context, logger logr.Logger, conn *dbus.Conn, unit servicesv1alpha1.Unit) (*status, error) {
+// Only the operations needed to move the unit from its current state to the
+// desired one are issued, so a periodic resync corrects drift without
+// restarting healthy services.
+func (r *Reconciler) handleUnit(ctx context.Context, logger logr.Logger, conn *dbus.Conn, systemd *servicesv1alpha1.Systemd, unit servicesv1alpha1.Unit, prev *servicesv1alpha1.UnitStatus) (*status, error) {
 	u := unit.DeepCopy()
 	if u.ActivationMode == "" {
 		u.ActivationMode = defaultActivationMode
 	}
+	if u.EnableMode == "" {
+		u.EnableMode = defaultEnableMode
+	}
 
 	s := &status{
 		Name: u.Name,
 	}
 
-	persistent := u.EnableMode == servicesv1alpha1.EnableModePersistent
-	if u.EnableMode == "" {
-		u.EnableMode = defaultEnableMode
-	}
-
-	switch unit.DesiredStatus {
-	case servicesv1alpha1.ServiceStatusEnabled:
-		_, _, err := conn.EnableUnitFilesContext(ctx, []string{unit.Name}, persistent, false)
+	if u.User != "" {
+		logger = logger.WithValues("user", u.User)
+		userConn, err := r.userConnection(ctx, systemd, u)
 		if err != nil {
+			logger.Error(err, "failed to connect to user manager")
 			s.Error = err
-		}
-	case servicesv1alpha1.ServiceStatusDisabled:
-		_, err := conn.DisableUnitFilesContext(ctx, []string{unit.Name}, persistent)
-		if err != nil {
-			s.Error = err
-		}
-	case servicesv1alpha1.ServiceStatusStarted:
-		reschan := make(chan string)
-		_, err := conn.StartUnitContext(ctx, unit.Name, u.ActivationMode.String(), reschan)
-		if err != nil {
-			s.Error = err
-		} else {
-			// wait for done
-			job := <-reschan
-			if job != "done" {
-				s.Error = fmt.Errorf("job != done with status: %s", job)
-			}
-		}
-	case servicesv1alpha1.ServiceStatusStopped:
-		reschan := make(chan string)
-		_, err := conn.StopUnitContext(ctx, unit.Name, u.ActivationMode.String(), reschan)
-		if err != nil {
-			s.Error = err
-		} else {
-			// wait for done
-			<-reschan
-		}
-	case servicesv1alpha1.ServiceStatusEnabledAndStarted:
-		_, _, err := conn.EnableUnitFilesContext(ctx, []string{unit.Name}, persistent, false)
-		if err != nil {
-			s.Error = err
-		} else {
-			reschan := make(chan string)
-			_, err := conn.StartUnitContext(ctx, unit.Name, u.ActivationMode.String(), reschan)
-			if err != nil {
-				s.Error = err
-			} else {
-				// wait for done
-				job := <-reschan
-				if job != "done" {
-					s.Error = fmt.Errorf("job != done with status: %s", job)
-				}
+			if r.UserConnections != nil {
+				// The user manager is usually still starting, e.g. right after
+				// lingering was enabled.
+				s.RetryAfter = r.UserConnections.RetryIn(u.User)
 			}
+			return s, nil
 		}
-	case servicesv1alpha1.ServiceStatusDisabledAndStopped:
-		_, err := conn.DisableUnitFilesContext(ctx, []string{unit.Name}, persistent)
-		if err != nil {
-			s.Error = err
-		} else {
-			reschan := make(chan string)
-			_, err := conn.StopUnitContext(ctx, unit.Name, u.ActivationMode.String(), reschan)
-			if err != nil {
-				s.Error = err
-			} else {
-				// wait for done
-				<-reschan
-			}
+		conn = userConn
+	}
+
+	props, err := conn.GetUnitPropertiesContext(ctx, u.Name)
+	if err := observeOperation("get_properties", err); err != nil {
+		return nil, err
+	}
+	activeState, _ := props["ActiveState"].(string)
+	unitFileState, _ := props["UnitFileState"].(string)
+	unitFilePreset, _ := props["UnitFilePreset"].(string)
+	activeSince, _ := props["ActiveEnterTimestamp"].(uint64)
+
+	a := plan(u.DesiredStatus, newUnitState(activeState, unitFileState, unitFilePreset))
+	key := newUnitKey(ctx, systemd, u.User, u.Name)
+	if a.start && crashLoopHeld(prev, unitHash(u)) {
+		// The agent stopped the unit after it crash looped.
+		a.start = false
+	}
+
+	// The unit converged on a previous reconcile, so anything we have to do now
+	// was caused by a change made outside of the agent.
+	if a.any() && prev != nil && prev.Error == "" && prev.DesiredStatus == u.DesiredStatus.String() {
+		logger.Info("unit drifted from desired state", "unit", u.Name, "activeState", activeState, "unitFileState", unitFileState)
+		r.eventf(systemd, corev1.EventTypeWarning, ReasonDriftCorrected, "Unit %s drifted to %s/%s, restoring %s", u.Name, activeState, unitFileState, u.DesiredStatus)
+	}
+
+	if a.unitFiles() {
+		runtime := u.EnableMode == servicesv1alpha1.EnableModeRuntimeOnly
+		s.Error = r.applyUnitFiles(ctx, systemd, dbusUnitFiles{conn: conn}, u.Name, runtime, a)
+		if s.Error == nil {
+			s.Error = r.daemonReload(ctx, conn, systemd)
 		}
 	}
 
+	a.start = r.handleRecovery(ctx, conn, systemd, u, prev, s, a.start, activeState, activeSince)
+	s.Applied = a.any()
+	if s.Error == nil && a.start {
+		s.Error = r.runJob(ctx, systemd, u.Name, "start", ReasonStarted, func(ch chan<- string) (int, error) {
+			return conn.StartUnitContext(ctx, u.Name, u.ActivationMode.String(), ch)
+		})
+	}
+	if s.Error == nil && a.stop {
+		s.Error = r.runJob(ctx, systemd, u.Name, "stop", ReasonStopped, func(ch chan<- string) (int, error) {
+			return conn.StopUnitContext(ctx, u.Name, u.ActivationMode.String(), ch)
+		})
+	}
+
+	// A pending signal waits until the unit reached its desired state.
+	s.Signal, err = r.handleSignal(ctx, conn, systemd, u, prev, s.Error == nil)
+	if err != nil && s.Error == nil {
+		s.Error = err
+	}
+
 	// check status
-	units, err := conn.ListJobsContext(ctx)
-	if err != nil {
+	props, err = conn.GetUnitPropertiesContext(ctx, u.Name)
+	if err := observeOperation("get_properties", err); err != nil {
 		return nil, err
 	}
+	s.Status, _ = props["ActiveState"].(string)
+	subState, _ := props["SubState"].(string)
+	activeEnter, _ := props["ActiveEnterTimestamp"].(uint64)
+
+	var restarts *uint32
+	var controlGroup string
+	var mainPID, controlPID uint32
+	if unitType := unitType(u.Name); unitType != "" {
+		typeProps, err := conn.GetUnitTypePropertiesContext(ctx, u.Name, unitType)
+		if err := observeOperation("get_properties", err); err != nil {
+			return nil, err
+		}
+		if n, ok := typeProps["NRestarts"].(uint32); ok {
+			restarts = &n
+		}
+		controlGroup, _ = typeProps["ControlGroup"].(string)
+		mainPID, _ = typeProps["MainPID"].(uint32)
+		controlPID, _ = typeProps["ControlPID"].(uint32)
+		if unitType == "Service" {
+			s.MainExit = mainExit(typeProps)
+		}
 
-	for _, u := range units {
-		if u.Unit == unit.Name {
-			s.Status = u.Status
-			break
+		if s.Error == nil && u.Accounting {
+			runtime := u.EnableMode == servicesv1alpha1.EnableModeRuntimeOnly
+			s.Error = r.enableAccounting(ctx, conn, systemd, u.Name, runtime, typeProps)
 		}
+		s.Resources = unitResources(typeProps)
+		setResourceMetrics(key, s.Resources)
+	}
+	setUnitMetrics(key, s.Status, subState, restarts)
+	s.Processes = r.listUnitProcesses(logger, controlGroup, mainPID, controlPID)
+	if restarts != nil {
+		r.handleCrashLoop(ctx, conn, systemd, u, prev, s, key, *restarts)
 	}
+	r.handleProbes(ctx, conn, systemd, u, prev, s, key)
+	r.handleReadiness(systemd, u, prev, s, subState, activeEnter)
 
 	return s, nil
 }
+
+// plan returns which operations are needed to move a unit from its current
+// state to the desired one.
+func plan(desired servicesv1alpha1.ServiceStatus, state unitState) actions {
+	var a actions
+	switch desired {
+	case servicesv1alpha1.ServiceStatusEnabled:
+		a.unmask, a.enable = state.masked, !state.enabled
+	case servicesv1alpha1.ServiceStatusDisabled:
+		a.disable = state.enabled
+	case servicesv1alpha1.ServiceStatusStarted:
+		a.start = !state.active
+	case servicesv1alpha1.ServiceStatusStopped:
+		a.stop = state.active
+	case servicesv1alpha1.ServiceStatusEnabledAndStarted:
+		a.unmask, a.enable, a.start = state.masked, !state.enabled, !state.active
+	case servicesv1alpha1.ServiceStatusDisabledAndStopped:
+		a.disable, a.stop = state.enabled, state.active
+	case servicesv1alpha1.ServiceStatusMasked:
+		a.mask, a.stop = !state.masked, state.active
+	case servicesv1alpha1.ServiceStatusPreset:
+		// Like `systemctl preset`, masked units are left alone.
+		if !state.masked {
+			a.enable = state.preset == "enabled" && !state.enabled
+			a.disable = state.preset == "disabled" && state.enabled
+		}
+	}
+	return a
+}
+
+// isEnabled reports whether the UnitFileState means the unit is enabled,
+// either persistently or for the current boot only.
+func isEnabled(unitFileState string) bool {
+	return strings.HasPrefix(unitFileState, "enabled")
+}
+
+// isActive reports whether the ActiveState means the unit is running or about to.
+func isActive(activeState string) bool {
+	switch activeState {
+	case "active", "activating", "reloading":
+		return true
+	}
+	return false
+}
+
+// runJob queues a systemd job through submit, waits for it to finish and
+// records the outcome as an event.
+func (r *Reconciler) runJob(ctx context.Context, systemd *servicesv1alpha1.Systemd, name, operation, reason string, submit func(chan<- string) (int, error)) error {
+	reschan := make(chan string, 1)
+	start := time.Now()
+	if _, err := submit(reschan); err != nil {
+		observeOperation(operation, err)
+		r.eventf(systemd, corev1.EventTypeWarning, ReasonFailed, "Failed to %s unit %s: %v", operation, name, err)
+		return err
+	}
+
+	var result string
+	select {
+	case result = <-reschan:
+	case <-ctx.Done():
+		return observeOperation(operation, ctx.Err())
+	case <-r.aborted():
+		err := fmt.Errorf("agent stopped before %s job for %s finished", operation, name)
+		r.eventf(systemd, corev1.EventTypeWarning, ReasonFailed, "%v", err)
+		return observeOperation(operation, err)
+	}
+	observeJob(operation, start)
+	if result != "done" {
+		err := observeOperation(operation, fmt.Errorf("%s job for %s finished with result: %s", operation, name, result))
+		r.eventf(systemd, corev1.EventTypeWarning, ReasonFailed, "Failed to %s unit %s: job result %s", operation, name, result)
+		return err
+	}
+	observeOperation(operation, nil)
+	r.eventf(systemd, corev1.EventTypeNormal, reason, "%s unit %s", reason, name)
+	return nil
+}
+
+// daemonReload makes systemd pick up unit file changes made by enable or disable.
+func (r *Reconciler) daemonReload(ctx context.Context, conn *dbus.Conn, systemd *servicesv1alpha1.Systemd) error {
+	if err := observeOperation("daemon_reload", conn.ReloadContext(ctx)); err != nil {
+		r.eventf(systemd, corev1.EventTypeWarning, ReasonFailed, "Failed to reload systemd daemon: %v", err)
+		return err
+	}
+	r.eventf(systemd, corev1.EventTypeNormal, ReasonDaemonReloaded, "Reloaded systemd daemon")
+	return nil
+}
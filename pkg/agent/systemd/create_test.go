@@ -0,0 +1,77 @@
+package systemd
+
+import (
+	"testing"
+
+	servicesv1alpha1 "github.com/faroshq/plugin-services/pkg/apis/services/v1alpha1"
+)
+
+func TestNewUnitState(t *testing.T) {
+	for _, tc := range []struct {
+		activeState, unitFileState, unitFilePreset string
+		want                                       unitState
+	}{
+		{"active", "enabled", "enabled", unitState{enabled: true, active: true, preset: "enabled"}},
+		{"activating", "enabled-runtime", "disabled", unitState{enabled: true, active: true, preset: "disabled"}},
+		{"reloading", "disabled", "enabled", unitState{active: true, preset: "enabled"}},
+		{"inactive", "disabled", "disabled", unitState{preset: "disabled"}},
+		{"failed", "masked", "enabled", unitState{masked: true}},
+		{"deactivating", "masked-runtime", "enabled", unitState{masked: true}},
+		{"active", "static", "enabled", unitState{active: true}},
+		{"inactive", "generated", "disabled", unitState{}},
+		{"inactive", "alias", "enabled", unitState{}},
+	} {
+		t.Run(tc.activeState+"/"+tc.unitFileState, func(t *testing.T) {
+			if got := newUnitState(tc.activeState, tc.unitFileState, tc.unitFilePreset); got != tc.want {
+				t.Errorf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPlan(t *testing.T) {
+	var (
+		running  = unitState{enabled: true, active: true, preset: "enabled"}
+		stopped  = unitState{preset: "disabled"}
+		masked   = unitState{masked: true, active: true}
+		disabled = unitState{active: true, preset: "enabled"}
+	)
+
+	for _, tc := range []struct {
+		name    string
+		desired servicesv1alpha1.ServiceStatus
+		state   unitState
+		want    actions
+	}{
+		{"enabled/already", servicesv1alpha1.ServiceStatusEnabled, running, actions{}},
+		{"enabled/stopped", servicesv1alpha1.ServiceStatusEnabled, stopped, actions{enable: true}},
+		{"enabled/masked", servicesv1alpha1.ServiceStatusEnabled, masked, actions{unmask: true, enable: true}},
+		{"disabled/running", servicesv1alpha1.ServiceStatusDisabled, running, actions{disable: true}},
+		{"disabled/stopped", servicesv1alpha1.ServiceStatusDisabled, stopped, actions{}},
+		{"started/running", servicesv1alpha1.ServiceStatusStarted, running, actions{}},
+		{"started/stopped", servicesv1alpha1.ServiceStatusStarted, stopped, actions{start: true}},
+		{"stopped/running", servicesv1alpha1.ServiceStatusStopped, running, actions{stop: true}},
+		{"stopped/stopped", servicesv1alpha1.ServiceStatusStopped, stopped, actions{}},
+		{"enabled-and-started/stopped", servicesv1alpha1.ServiceStatusEnabledAndStarted, stopped, actions{enable: true, start: true}},
+		{"enabled-and-started/masked", servicesv1alpha1.ServiceStatusEnabledAndStarted, masked, actions{unmask: true, enable: true}},
+		{"disabled-and-stopped/running", servicesv1alpha1.ServiceStatusDisabledAndStopped, running, actions{disable: true, stop: true}},
+		{"disabled-and-stopped/stopped", servicesv1alpha1.ServiceStatusDisabledAndStopped, stopped, actions{}},
+		{"masked/running", servicesv1alpha1.ServiceStatusMasked, running, actions{mask: true, stop: true}},
+		{"masked/masked", servicesv1alpha1.ServiceStatusMasked, masked, actions{stop: true}},
+		{"preset/enable", servicesv1alpha1.ServiceStatusPreset, disabled, actions{enable: true}},
+		{"preset/disable", servicesv1alpha1.ServiceStatusPreset, unitState{enabled: true, preset: "disabled"}, actions{disable: true}},
+		{"preset/already", servicesv1alpha1.ServiceStatusPreset, running, actions{}},
+		{"preset/masked", servicesv1alpha1.ServiceStatusPreset, unitState{masked: true, preset: "enabled"}, actions{}},
+		{"preset/static", servicesv1alpha1.ServiceStatusPreset, unitState{active: true}, actions{}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := plan(tc.desired, tc.state)
+			if got != tc.want {
+				t.Errorf("got %+v, want %+v", got, tc.want)
+			}
+			if got.any() != (tc.want != actions{}) {
+				t.Errorf("any() = %v for %+v", got.any(), got)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package systemd
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+
+	servicesv1alpha1 "github.com/faroshq/plugin-services/pkg/apis/services/v1alpha1"
+)
+
+// Event reasons emitted on Systemd objects for every action taken on the device.
+// Reasons for completed jobs double as the verb in the event message.
+const (
+	ReasonStarted        = "Started"
+	ReasonStopped        = "Stopped"
+	ReasonRestarted      = "Restarted"
+	ReasonEnabled        = "Enabled"
+	ReasonDisabled       = "Disabled"
+	ReasonMasked         = "Masked"
+	ReasonUnmasked       = "Unmasked"
+	ReasonDaemonReloaded = "DaemonReloaded"
+	ReasonDriftCorrected = "DriftCorrected"
+	ReasonLingerEnabled  = "LingerEnabled"
+	// ReasonAccountingEnabled is recorded when accounting properties of a unit
+	// were turned on.
+	ReasonAccountingEnabled = "AccountingEnabled"
+	ReasonSignalSent        = "SignalSent"
+	// ReasonUnhealthy is recorded when a probe of a unit reached its failure
+	// threshold.
+	ReasonUnhealthy = "Unhealthy"
+	// ReasonCrashedAfterStart is recorded when a unit with a readiness policy
+	// stopped running before it was stable.
+	ReasonCrashedAfterStart = servicesv1alpha1.CrashedAfterStartReason
+	// ReasonCrashLooping is recorded when systemd restarted a service too
+	// often within the crash loop window.
+	ReasonCrashLooping = "CrashLooping"
+	// ReasonRecovering is recorded when the agent reset a failed unit to start
+	// it again.
+	ReasonRecovering = "Recovering"
+	ReasonFailed     = "Failed"
+)
+
+// eventf records an event on obj if the reconciler has a recorder configured.
+func (r *Reconciler) eventf(obj runtime.Object, eventType, reason, messageFmt string, args ...interface{}) {
+	if r.Recorder == nil {
+		return
+	}
+	r.Recorder.Eventf(obj, eventType, reason, messageFmt, args...)
+}
@@ -0,0 +1,31 @@
+package systemd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+)
+
+const healthCheckTimeout = 5 * time.Second
+
+// SystemdChecker returns a readiness check which fails when the agent can not
+// talk to systemd over dbus.
+func (r *Reconciler) SystemdChecker() healthz.Checker {
+	return func(req *http.Request) error {
+		ctx, cancel := context.WithTimeout(req.Context(), healthCheckTimeout)
+		defer cancel()
+
+		conn, err := r.Connection.Get()
+		if err != nil {
+			return fmt.Errorf("failed to connect to systemd: %w", err)
+		}
+
+		if _, err := conn.SystemStateContext(ctx); err != nil {
+			return fmt.Errorf("failed to query systemd state: %w", err)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,34 @@
+package systemd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"k8s.io/apimachinery/pkg/util/yaml"
+
+	servicesv1alpha1 "github.com/faroshq/plugin-services/pkg/apis/services/v1alpha1"
+)
+
+// ReadManifests reads the Systemd objects of a YAML or JSON stream holding any
+// number of documents. Empty documents are skipped, any other kind is an error.
+func ReadManifests(r io.Reader) ([]servicesv1alpha1.Systemd, error) {
+	var objects []servicesv1alpha1.Systemd
+	decoder := yaml.NewYAMLOrJSONDecoder(r, 4096)
+	for {
+		var systemd servicesv1alpha1.Systemd
+		if err := decoder.Decode(&systemd); err != nil {
+			if errors.Is(err, io.EOF) {
+				return objects, nil
+			}
+			return nil, err
+		}
+		if systemd.APIVersion == "" && systemd.Kind == "" {
+			continue
+		}
+		if gvk := systemd.GroupVersionKind(); gvk != servicesv1alpha1.SchemeGroupVersion.WithKind("Systemd") {
+			return nil, fmt.Errorf("unexpected object %s %q, expected Systemd objects only", gvk, systemd.Name)
+		}
+		objects = append(objects, systemd)
+	}
+}
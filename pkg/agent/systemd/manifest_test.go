@@ -0,0 +1,114 @@
+package systemd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadManifests(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		data    string
+		want    []string
+		wantErr bool
+	}{{
+		name: "empty",
+	}, {
+		name: "single document",
+		data: `apiVersion: services.plugins.faros.sh/v1alpha1
+kind: Systemd
+metadata:
+  name: web
+spec:
+  services:
+  - name: nginx.service
+    desiredState: started
+`,
+		want: []string{"web:nginx.service"},
+	}, {
+		name: "multiple documents with empty ones",
+		data: `---
+apiVersion: services.plugins.faros.sh/v1alpha1
+kind: Systemd
+metadata:
+  name: web
+spec:
+  services:
+  - name: nginx.service
+  - name: php-fpm.service
+---
+# only a comment
+---
+apiVersion: services.plugins.faros.sh/v1alpha1
+kind: Systemd
+metadata:
+  name: db
+spec:
+  services:
+  - name: postgresql.service
+`,
+		want: []string{"web:nginx.service,php-fpm.service", "db:postgresql.service"},
+	}, {
+		name: "json",
+		data: `{"apiVersion": "services.plugins.faros.sh/v1alpha1", "kind": "Systemd", "metadata": {"name": "web"}, "spec": {"services": [{"name": "nginx.service"}]}}`,
+		want: []string{"web:nginx.service"},
+	}, {
+		name: "other kind",
+		data: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: web
+`,
+		wantErr: true,
+	}, {
+		name: "other version",
+		data: `apiVersion: services.plugins.faros.sh/v1beta1
+kind: Systemd
+metadata:
+  name: web
+`,
+		wantErr: true,
+	}, {
+		name: "other kind after a valid document",
+		data: `apiVersion: services.plugins.faros.sh/v1alpha1
+kind: Systemd
+metadata:
+  name: web
+---
+apiVersion: services.plugins.faros.sh/v1alpha1
+kind: RunawayGuard
+metadata:
+  name: guard
+`,
+		wantErr: true,
+	}, {
+		name:    "malformed",
+		data:    "apiVersion: [\n",
+		wantErr: true,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			objects, err := ReadManifests(strings.NewReader(tc.data))
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				if objects != nil {
+					t.Errorf("got objects %v with an error", objects)
+				}
+				return
+			}
+			var got []string
+			for _, o := range objects {
+				var names []string
+				for _, u := range o.Spec.Units {
+					names = append(names, u.Name)
+				}
+				got = append(got, o.Name+":"+strings.Join(names, ","))
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
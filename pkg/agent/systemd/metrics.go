@@ -0,0 +1,216 @@
+package systemd
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	servicesv1alpha1 "github.com/faroshq/plugin-services/pkg/apis/services/v1alpha1"
+)
+
+const metricsNamespace = "faros_systemd"
+
+// activeStates are the values systemd reports in ActiveState. Every state gets
+// a series so that alerts can match on a state the unit is not in.
+var activeStates = []string{"active", "reloading", "inactive", "failed", "activating", "deactivating"}
+
+// unitLabels identify a unit of a Systemd object. Units are labelled with
+// their object, as several objects or users may manage units of the same name.
+var unitLabels = []string{"cluster", "namespace", "systemd", "user", "unit"}
+
+var (
+	unitActiveState = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "unit_active_state",
+		Help:      "ActiveState of a managed unit, 1 for the state the unit is currently in.",
+	}, append(unitLabels, "state"))
+
+	unitSubState = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "unit_sub_state",
+		Help:      "SubState of a managed unit, only the current state is exported.",
+	}, append(unitLabels, "state"))
+
+	unitRestarts = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "unit_restarts",
+		Help:      "Number of automatic restarts systemd performed for a managed service (NRestarts).",
+	}, unitLabels)
+
+	unitCPUUsage = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "unit_cpu_usage_seconds",
+		Help:      "CPU time consumed by a managed unit (CPUUsageNSec).",
+	}, unitLabels)
+
+	unitMemoryCurrent = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "unit_memory_current_bytes",
+		Help:      "Memory used by a managed unit (MemoryCurrent).",
+	}, unitLabels)
+
+	unitMemoryPeak = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "unit_memory_peak_bytes",
+		Help:      "Most memory used by a managed unit since it started (MemoryPeak).",
+	}, unitLabels)
+
+	unitTasks = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "unit_tasks",
+		Help:      "Number of tasks of a managed unit (TasksCurrent).",
+	}, unitLabels)
+
+	unitIPIngress = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "unit_ip_ingress_bytes",
+		Help:      "IP traffic received by a managed unit (IPIngressBytes).",
+	}, unitLabels)
+
+	unitIPEgress = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "unit_ip_egress_bytes",
+		Help:      "IP traffic sent by a managed unit (IPEgressBytes).",
+	}, unitLabels)
+
+	unitIORead = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "unit_io_read_bytes",
+		Help:      "Data read from block devices by a managed unit (IOReadBytes).",
+	}, unitLabels)
+
+	dbusOperations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "dbus_operations_total",
+		Help:      "Number of operations issued to systemd over dbus by operation and result.",
+	}, []string{"operation", "result"})
+
+	reconcileDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "reconcile_duration_seconds",
+		Help:      "Time spent reconciling a Systemd object.",
+		Buckets:   prometheus.ExponentialBuckets(0.01, 2, 14),
+	})
+
+	jobDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "job_duration_seconds",
+		Help:      "Time from queueing a systemd job until it finished, by operation.",
+		Buckets:   prometheus.ExponentialBuckets(0.01, 2, 14),
+	}, []string{"operation"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		unitActiveState,
+		unitSubState,
+		unitRestarts,
+		unitCPUUsage,
+		unitMemoryCurrent,
+		unitMemoryPeak,
+		unitTasks,
+		unitIPIngress,
+		unitIPEgress,
+		unitIORead,
+		dbusOperations,
+		reconcileDuration,
+		jobDuration,
+	)
+}
+
+// observeOperation counts a dbus operation and passes err through.
+func observeOperation(operation string, err error) error {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	dbusOperations.WithLabelValues(operation, result).Inc()
+	return err
+}
+
+func observeJob(operation string, start time.Time) {
+	jobDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
+
+// labelValues returns the values of unitLabels for the unit.
+func (k unitKey) labelValues(extra ...string) []string {
+	return append([]string{k.cluster, k.namespace, k.name, k.user, k.unit}, extra...)
+}
+
+func (k unitKey) labels() prometheus.Labels {
+	return prometheus.Labels{"cluster": k.cluster, "namespace": k.namespace, "systemd": k.name, "user": k.user, "unit": k.unit}
+}
+
+// setUnitMetrics exports the current state of a managed unit.
+func setUnitMetrics(key unitKey, activeState, subState string, restarts *uint32) {
+	for _, state := range activeStates {
+		value := 0.0
+		if state == activeState {
+			value = 1
+		}
+		unitActiveState.WithLabelValues(key.labelValues(state)...).Set(value)
+	}
+
+	unitSubState.DeletePartialMatch(key.labels())
+	if subState != "" {
+		unitSubState.WithLabelValues(key.labelValues(subState)...).Set(1)
+	}
+
+	if restarts != nil {
+		unitRestarts.WithLabelValues(key.labelValues()...).Set(float64(*restarts))
+	}
+}
+
+// setResourceMetrics exports the resource usage of a managed unit. Values
+// which are not accounted have no series.
+func setResourceMetrics(key unitKey, resources *servicesv1alpha1.UnitResources) {
+	if resources == nil {
+		return
+	}
+	setOrDelete(unitCPUUsage, key, resources.CPUUsageNSec, 1e-9)
+	setOrDelete(unitMemoryCurrent, key, resources.MemoryCurrentBytes, 1)
+	setOrDelete(unitMemoryPeak, key, resources.MemoryPeakBytes, 1)
+	setOrDelete(unitTasks, key, resources.TasksCurrent, 1)
+	setOrDelete(unitIPIngress, key, resources.IPIngressBytes, 1)
+	setOrDelete(unitIPEgress, key, resources.IPEgressBytes, 1)
+	setOrDelete(unitIORead, key, resources.IOReadBytes, 1)
+}
+
+func setOrDelete(gauge *prometheus.GaugeVec, key unitKey, value *int64, scale float64) {
+	if value == nil {
+		gauge.DeleteLabelValues(key.labelValues()...)
+		return
+	}
+	gauge.WithLabelValues(key.labelValues()...).Set(float64(*value) * scale)
+}
+
+// unitMetrics are the vectors holding series of managed units.
+var unitMetrics = []*prometheus.MetricVec{
+	unitActiveState.MetricVec,
+	unitSubState.MetricVec,
+	unitRestarts.MetricVec,
+	unitCPUUsage.MetricVec,
+	unitMemoryCurrent.MetricVec,
+	unitMemoryPeak.MetricVec,
+	unitTasks.MetricVec,
+	unitIPIngress.MetricVec,
+	unitIPEgress.MetricVec,
+	unitIORead.MetricVec,
+}
+
+// deleteUnitMetrics drops all series of a unit which is no longer managed.
+func deleteUnitMetrics(key unitKey) {
+	for _, vec := range unitMetrics {
+		vec.DeletePartialMatch(key.labels())
+	}
+}
+
+// deleteObjectMetrics drops the series of all units of a Systemd object which
+// was deleted.
+func deleteObjectMetrics(cluster, namespace, name string) {
+	labels := prometheus.Labels{"cluster": cluster, "namespace": namespace, "systemd": name}
+	for _, vec := range unitMetrics {
+		vec.DeletePartialMatch(labels)
+	}
+}
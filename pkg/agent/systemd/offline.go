@@ -0,0 +1,95 @@
+package systemd
+
+import (
+	"context"
+	"errors"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	servicesv1alpha1 "github.com/faroshq/plugin-services/pkg/apis/services/v1alpha1"
+)
+
+var errUserUnitsOffline = errors.New("user units can not be managed offline")
+
+// ApplyOffline applies the unit file part of spec through files, e.g. a Rootfs
+// while building a device image. Starting and stopping units needs a running
+// systemd, so that part of the desired state is skipped. Units are always
+// enabled persistently, runtime enablement lives in /run which does not
+// survive until the first boot.
+//
+// The returned status reports the UnitFileState of every unit. It fails only
+// if the spec is invalid, failed units are reported in their status.
+func ApplyOffline(ctx context.Context, files UnitFileManager, spec servicesv1alpha1.SystemdSpec) ([]servicesv1alpha1.UnitStatus, error) {
+	spec = *spec.DeepCopy()
+	setDefaults(&spec)
+	if errs := validateSpec(spec, sets.NewString()); len(errs) > 0 {
+		return nil, errs.ToAggregate()
+	}
+
+	// Events are not recorded offline.
+	r := &Reconciler{}
+
+	statuses := make([]servicesv1alpha1.UnitStatus, 0, len(spec.Units))
+	for _, unit := range spec.Units {
+		now := metav1.Now()
+		status := servicesv1alpha1.UnitStatus{
+			Name:               unit.Name,
+			User:               unit.User,
+			DesiredStatus:      unit.DesiredStatus.String(),
+			LastTransitionTime: &now,
+		}
+
+		state, applied, err := r.applyOffline(ctx, files, unit)
+		status.Status = state
+		if applied {
+			status.LastAppliedTime = &now
+		}
+		if err != nil {
+			status.Error = err.Error()
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// applyOffline returns the UnitFileState of the unit, and whether unit files
+// had to be changed.
+func (r *Reconciler) applyOffline(ctx context.Context, files UnitFileManager, unit servicesv1alpha1.Unit) (string, bool, error) {
+	if unit.User != "" {
+		return "", false, errUserUnitsOffline
+	}
+
+	unitFileState, unitFilePreset, err := files.UnitFileState(ctx, unit.Name)
+	if err != nil {
+		return "", false, err
+	}
+	// Nothing runs offline, start and stop actions are ignored.
+	a := plan(unit.DesiredStatus, newUnitState("", unitFileState, unitFilePreset))
+	if !a.unitFiles() {
+		return unitFileState, false, nil
+	}
+	if err := r.applyUnitFiles(ctx, nil, files, unit.Name, false, a); err != nil {
+		return unitFileState, true, err
+	}
+
+	unitFileState, _, err = files.UnitFileState(ctx, unit.Name)
+	return unitFileState, true, err
+}
+
+// setDefaults applies the defaults of the API types, for specs which were not
+// read through the API server.
+func setDefaults(spec *servicesv1alpha1.SystemdSpec) {
+	for i := range spec.Units {
+		unit := &spec.Units[i]
+		if unit.DesiredStatus == "" {
+			unit.DesiredStatus = servicesv1alpha1.ServiceStatusStarted
+		}
+		if unit.ActivationMode == "" {
+			unit.ActivationMode = defaultActivationMode
+		}
+		if unit.EnableMode == "" {
+			unit.EnableMode = defaultEnableMode
+		}
+	}
+}
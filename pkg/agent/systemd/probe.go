@@ -0,0 +1,453 @@
+package systemd
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"os/exec"
+	"os/user"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/coreos/go-systemd/v22/dbus"
+	conditionsv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/apis/conditions/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/util/conditions"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	servicesv1alpha1 "github.com/faroshq/plugin-services/pkg/apis/services/v1alpha1"
+	utilstrings "github.com/faroshq/plugin-services/pkg/util/strings"
+)
+
+const (
+	defaultProbePeriod      = 30 * time.Second
+	minProbePeriod          = 5 * time.Second
+	defaultProbeTimeout     = 5 * time.Second
+	defaultFailureThreshold = 3
+	defaultProbeHost        = "127.0.0.1"
+	// probeSlack is how long after a probe is due its result is picked up,
+	// on top of its timeout.
+	probeSlack = time.Second
+	// maxProbeMessageLength bounds the output of failed exec probes kept in
+	// the status.
+	maxProbeMessageLength = 256
+)
+
+// probeClient does not verify certificates, services on the device usually
+// serve self-signed ones.
+var probeClient = &http.Client{
+	Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+		DisableKeepAlives: true,
+	},
+}
+
+// prober runs the probes of an active unit in the background, so slow probes
+// do not hold up reconciles. Reconciles pick up the latest results.
+type prober struct {
+	probes []servicesv1alpha1.UnitProbe
+	user   string
+	stop   context.CancelFunc
+
+	lock    sync.Mutex
+	results map[string]servicesv1alpha1.ProbeStatus
+}
+
+// handleProbes records the latest results of the probes of an active unit and
+// the Healthy condition in s, starting the prober of the unit if needed. The
+// unit is restarted when a probe failed RestartThreshold times in a row.
+func (r *Reconciler) handleProbes(ctx context.Context, conn *dbus.Conn, systemd *servicesv1alpha1.Systemd, unit *servicesv1alpha1.Unit, prev *servicesv1alpha1.UnitStatus, s *status, key unitKey) {
+	if len(unit.Probes) == 0 || s.Status != "active" {
+		r.stopProber(key)
+	}
+	if len(unit.Probes) == 0 {
+		return
+	}
+	if s.Status != "active" {
+		// Results of a previous run say nothing about the next one.
+		setUnitCondition(&s.Conditions, prev, conditions.UnknownCondition(servicesv1alpha1.HealthyCondition, "NotActive", "Unit is %s", s.Status))
+		return
+	}
+
+	p := r.startProber(key, unit, prev)
+	results := p.snapshot()
+	now := time.Now()
+	var failing []string
+	restart := false
+	for _, probe := range unit.Probes {
+		result, ok := results[probe.Name]
+		if !ok {
+			result = servicesv1alpha1.ProbeStatus{Name: probe.Name}
+		}
+		// Until the first result the probe runs right away.
+		var due time.Duration
+		if result.LastProbeTime != nil {
+			due = probePeriod(probe) - now.Sub(result.LastProbeTime.Time)
+		}
+		if due < 0 {
+			due = 0
+		}
+		s.checkIn(due + probeTimeout(probe) + probeSlack)
+
+		unhealthy, restartNeeded := probeOutcome(probe, result)
+		if unhealthy {
+			failing = append(failing, fmt.Sprintf("%s (%s)", probe.Name, result.Message))
+			var last *servicesv1alpha1.ProbeStatus
+			if prev != nil {
+				last = findProbeStatus(prev.Probes, probe.Name)
+			}
+			if last == nil || last.ConsecutiveFailures < failureThreshold(probe) {
+				r.eventf(systemd, corev1.EventTypeWarning, ReasonUnhealthy, "Probe %s of unit %s failed %d times: %s", probe.Name, unit.Name, result.ConsecutiveFailures, result.Message)
+			}
+		}
+		restart = restart || restartNeeded
+		s.Probes = append(s.Probes, result)
+	}
+
+	if restart && s.Error == nil {
+		s.Error = r.runJob(ctx, systemd, unit.Name, "restart", ReasonRestarted, func(ch chan<- string) (int, error) {
+			return conn.RestartUnitContext(ctx, unit.Name, unit.ActivationMode.String(), ch)
+		})
+		if s.Error == nil {
+			// Give the restarted service the full thresholds again.
+			p.resetFailures()
+			for i := range s.Probes {
+				s.Probes[i].ConsecutiveFailures = 0
+			}
+		}
+	}
+
+	if len(failing) > 0 {
+		setUnitCondition(&s.Conditions, prev, conditions.FalseCondition(servicesv1alpha1.HealthyCondition, "ProbeFailed", conditionsv1alpha1.ConditionSeverityWarning,
+			"Failing probes: %s", strings.Join(failing, ", ")))
+		return
+	}
+	setUnitCondition(&s.Conditions, prev, conditions.TrueCondition(servicesv1alpha1.HealthyCondition))
+}
+
+// probeOutcome reports whether the failures of a probe reached its failure
+// threshold, making the unit unhealthy, and its restart threshold.
+func probeOutcome(probe servicesv1alpha1.UnitProbe, result servicesv1alpha1.ProbeStatus) (unhealthy, restart bool) {
+	unhealthy = result.ConsecutiveFailures >= failureThreshold(probe)
+	restart = probe.RestartThreshold != nil && result.ConsecutiveFailures >= *probe.RestartThreshold
+	return unhealthy, restart
+}
+
+// startProber returns the prober of a unit, starting it when the unit has
+// none yet or its probes changed. A new prober continues from the results
+// reported in prev.
+func (r *Reconciler) startProber(key unitKey, unit *servicesv1alpha1.Unit, prev *servicesv1alpha1.UnitStatus) *prober {
+	r.proberLock.Lock()
+	defer r.proberLock.Unlock()
+
+	if p, ok := r.probers[key]; ok {
+		if p.user == unit.User && reflect.DeepEqual(p.probes, unit.Probes) {
+			return p
+		}
+		p.stop()
+	}
+	if r.probers == nil {
+		r.probers = map[unitKey]*prober{}
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
+	p := &prober{
+		probes:  make([]servicesv1alpha1.UnitProbe, len(unit.Probes)),
+		user:    unit.User,
+		stop:    stop,
+		results: map[string]servicesv1alpha1.ProbeStatus{},
+	}
+	for i := range unit.Probes {
+		unit.Probes[i].DeepCopyInto(&p.probes[i])
+	}
+	if prev != nil {
+		for _, result := range prev.Probes {
+			p.results[result.Name] = *result.DeepCopy()
+		}
+	}
+	r.probers[key] = p
+	go p.run(ctx)
+	return p
+}
+
+// stopProber stops the prober of a unit, if it has one.
+func (r *Reconciler) stopProber(key unitKey) {
+	r.proberLock.Lock()
+	defer r.proberLock.Unlock()
+	if p, ok := r.probers[key]; ok {
+		p.stop()
+		delete(r.probers, key)
+	}
+}
+
+// stopObjectProbers stops the probers of all units of a deleted object.
+func (r *Reconciler) stopObjectProbers(cluster, namespace, name string) {
+	r.proberLock.Lock()
+	defer r.proberLock.Unlock()
+	for key, p := range r.probers {
+		if key.cluster == cluster && key.namespace == namespace && key.name == name {
+			p.stop()
+			delete(r.probers, key)
+		}
+	}
+}
+
+// run runs every probe when it is due until ctx is done.
+func (p *prober) run(ctx context.Context) {
+	for {
+		now := time.Now()
+		next := now.Add(defaultProbePeriod)
+		for _, probe := range p.probes {
+			due := now
+			if result, ok := p.result(probe.Name); ok && result.LastProbeTime != nil {
+				due = result.LastProbeTime.Add(probePeriod(probe))
+			}
+			if !due.After(now) {
+				p.record(probe, runProbe(ctx, probe, p.user))
+				due = time.Now().Add(probePeriod(probe))
+			}
+			if due.Before(next) {
+				next = due
+			}
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+func (p *prober) result(name string) (servicesv1alpha1.ProbeStatus, bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	result, ok := p.results[name]
+	return result, ok
+}
+
+// record stores the outcome of a probe run.
+func (p *prober) record(probe servicesv1alpha1.UnitProbe, err error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	result := p.results[probe.Name]
+	result.Name = probe.Name
+	if err != nil {
+		result.Success = false
+		result.ConsecutiveFailures++
+		result.Message = utilstrings.Truncate(err.Error(), maxProbeMessageLength)
+	} else {
+		result.Success = true
+		result.ConsecutiveFailures = 0
+		result.Message = ""
+	}
+	now := metav1.Now()
+	result.LastProbeTime = &now
+	p.results[probe.Name] = result
+}
+
+// snapshot returns a copy of the latest results.
+func (p *prober) snapshot() map[string]servicesv1alpha1.ProbeStatus {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	results := make(map[string]servicesv1alpha1.ProbeStatus, len(p.results))
+	for name, result := range p.results {
+		results[name] = *result.DeepCopy()
+	}
+	return results
+}
+
+func (p *prober) resetFailures() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	for name, result := range p.results {
+		result.ConsecutiveFailures = 0
+		p.results[name] = result
+	}
+}
+
+// runProbe runs a single probe and returns why it failed. Exec probes of
+// user units run as the user.
+func runProbe(ctx context.Context, p servicesv1alpha1.UnitProbe, user string) error {
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout(p))
+	defer cancel()
+
+	switch {
+	case p.HTTPGet != nil:
+		return probeHTTP(ctx, *p.HTTPGet)
+	case p.TCPSocket != nil:
+		return probeTCP(ctx, *p.TCPSocket)
+	case p.Exec != nil:
+		return probeExec(ctx, *p.Exec, user)
+	}
+	return fmt.Errorf("probe has no handler")
+}
+
+func probeHTTP(ctx context.Context, p servicesv1alpha1.HTTPGetProbe) error {
+	scheme := "http"
+	if p.Scheme == servicesv1alpha1.URISchemeHTTPS {
+		scheme = "https"
+	}
+	path := p.Path
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	url := scheme + "://" + net.JoinHostPort(probeHost(p.Host), strconv.Itoa(int(p.Port))) + path
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := probeClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("GET %s returned %s", url, resp.Status)
+	}
+	return nil
+}
+
+func probeTCP(ctx context.Context, p servicesv1alpha1.TCPSocketProbe) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(probeHost(p.Host), strconv.Itoa(int(p.Port))))
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// probeExec runs the command in its own process group, which is killed when
+// the probe times out so children holding its output open do not outlive it.
+func probeExec(ctx context.Context, p servicesv1alpha1.ExecProbe, username string) error {
+	cmd := exec.Command(p.Command[0], p.Command[1:]...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if username != "" {
+		credential, err := userCredential(username)
+		if err != nil {
+			return err
+		}
+		cmd.SysProcAttr.Credential = credential
+	}
+	out := &limitedBuffer{max: maxProbeMessageLength}
+	cmd.Stdout = out
+	cmd.Stderr = out
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		_ = syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		<-done
+		return fmt.Errorf("command timed out: %w", ctx.Err())
+	}
+	if err != nil {
+		if output := strings.TrimSpace(out.String()); output != "" {
+			return fmt.Errorf("%v: %s", err, output)
+		}
+		return err
+	}
+	return nil
+}
+
+// limitedBuffer keeps the first max bytes written to it and discards the
+// rest, so a chatty probe command can not grow the agent's memory. Writes
+// never fail, the command would otherwise see a broken pipe.
+type limitedBuffer struct {
+	buf []byte
+	max int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.max - len(b.buf); room > 0 {
+		if len(p) < room {
+			room = len(p)
+		}
+		b.buf = append(b.buf, p[:room]...)
+	}
+	return len(p), nil
+}
+
+func (b *limitedBuffer) String() string {
+	return string(b.buf)
+}
+
+// userCredential returns the credential to run commands as username.
+func userCredential(username string) (*syscall.Credential, error) {
+	usr, err := user.Lookup(username)
+	if err != nil {
+		return nil, err
+	}
+	uid, err := strconv.ParseUint(usr.Uid, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid uid %q of user %s: %w", usr.Uid, username, err)
+	}
+	gid, err := strconv.ParseUint(usr.Gid, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid gid %q of user %s: %w", usr.Gid, username, err)
+	}
+	credential := &syscall.Credential{Uid: uint32(uid), Gid: uint32(gid)}
+	if groups, err := usr.GroupIds(); err == nil {
+		for _, group := range groups {
+			if id, err := strconv.ParseUint(group, 10, 32); err == nil {
+				credential.Groups = append(credential.Groups, uint32(id))
+			}
+		}
+	}
+	return credential, nil
+}
+
+func probeHost(host string) string {
+	if host == "" {
+		return defaultProbeHost
+	}
+	return host
+}
+
+func probePeriod(p servicesv1alpha1.UnitProbe) time.Duration {
+	if p.Period == nil {
+		return defaultProbePeriod
+	}
+	return p.Period.Duration
+}
+
+func probeTimeout(p servicesv1alpha1.UnitProbe) time.Duration {
+	if p.Timeout == nil {
+		return defaultProbeTimeout
+	}
+	return p.Timeout.Duration
+}
+
+func failureThreshold(p servicesv1alpha1.UnitProbe) int32 {
+	if p.FailureThreshold <= 0 {
+		return defaultFailureThreshold
+	}
+	return p.FailureThreshold
+}
+
+// findProbeStatus returns the result reported for the named probe, if any.
+func findProbeStatus(probes []servicesv1alpha1.ProbeStatus, name string) *servicesv1alpha1.ProbeStatus {
+	for i := range probes {
+		if probes[i].Name == name {
+			return &probes[i]
+		}
+	}
+	return nil
+}
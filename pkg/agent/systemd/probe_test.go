@@ -0,0 +1,268 @@
+package systemd
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	servicesv1alpha1 "github.com/faroshq/plugin-services/pkg/apis/services/v1alpha1"
+)
+
+func TestProberRecord(t *testing.T) {
+	probe := servicesv1alpha1.UnitProbe{Name: "http"}
+	failed := errors.New("connection refused")
+	long := errors.New(strings.Repeat("x", maxProbeMessageLength+10))
+
+	for _, tc := range []struct {
+		name string
+		runs []error
+		want servicesv1alpha1.ProbeStatus
+	}{{
+		name: "success",
+		runs: []error{nil},
+		want: servicesv1alpha1.ProbeStatus{Name: "http", Success: true},
+	}, {
+		name: "failures are counted",
+		runs: []error{failed, failed, failed},
+		want: servicesv1alpha1.ProbeStatus{Name: "http", ConsecutiveFailures: 3, Message: "connection refused"},
+	}, {
+		name: "success resets the failures",
+		runs: []error{failed, failed, nil},
+		want: servicesv1alpha1.ProbeStatus{Name: "http", Success: true},
+	}, {
+		name: "failures after a success",
+		runs: []error{failed, nil, failed},
+		want: servicesv1alpha1.ProbeStatus{Name: "http", ConsecutiveFailures: 1, Message: "connection refused"},
+	}, {
+		name: "long message is truncated",
+		runs: []error{long},
+		want: servicesv1alpha1.ProbeStatus{Name: "http", ConsecutiveFailures: 1, Message: strings.Repeat("x", maxProbeMessageLength)},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			p := &prober{results: map[string]servicesv1alpha1.ProbeStatus{}}
+			before := time.Now().Add(-time.Second)
+			for _, err := range tc.runs {
+				p.record(probe, err)
+			}
+			got, ok := p.result(probe.Name)
+			if !ok {
+				t.Fatal("no result recorded")
+			}
+			if got.LastProbeTime == nil || got.LastProbeTime.Time.Before(before) {
+				t.Errorf("got probe time %v", got.LastProbeTime)
+			}
+			got.LastProbeTime = nil
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+
+	t.Run("continues from previous results", func(t *testing.T) {
+		p := &prober{results: map[string]servicesv1alpha1.ProbeStatus{"http": {Name: "http", ConsecutiveFailures: 2}}}
+		p.record(probe, failed)
+		if got, _ := p.result("http"); got.ConsecutiveFailures != 3 {
+			t.Errorf("got %d failures, want 3", got.ConsecutiveFailures)
+		}
+		p.resetFailures()
+		if got, _ := p.result("http"); got.ConsecutiveFailures != 0 || got.Message != "connection refused" {
+			t.Errorf("got %+v after reset", got)
+		}
+	})
+}
+
+func TestProbeOutcome(t *testing.T) {
+	int32Ptr := func(i int32) *int32 { return &i }
+
+	for _, tc := range []struct {
+		name          string
+		probe         servicesv1alpha1.UnitProbe
+		failures      int32
+		wantUnhealthy bool
+		wantRestart   bool
+	}{
+		{"passing", servicesv1alpha1.UnitProbe{}, 0, false, false},
+		{"below the default threshold", servicesv1alpha1.UnitProbe{}, defaultFailureThreshold - 1, false, false},
+		{"at the default threshold", servicesv1alpha1.UnitProbe{}, defaultFailureThreshold, true, false},
+		{"below the threshold", servicesv1alpha1.UnitProbe{FailureThreshold: 5}, 4, false, false},
+		{"at the threshold", servicesv1alpha1.UnitProbe{FailureThreshold: 5}, 5, true, false},
+		{"above the threshold", servicesv1alpha1.UnitProbe{FailureThreshold: 1}, 7, true, false},
+		{"below the restart threshold", servicesv1alpha1.UnitProbe{FailureThreshold: 1, RestartThreshold: int32Ptr(3)}, 2, true, false},
+		{"at the restart threshold", servicesv1alpha1.UnitProbe{FailureThreshold: 1, RestartThreshold: int32Ptr(3)}, 3, true, true},
+		{"restart before unhealthy", servicesv1alpha1.UnitProbe{FailureThreshold: 5, RestartThreshold: int32Ptr(2)}, 2, false, true},
+		{"restart threshold not reached when passing", servicesv1alpha1.UnitProbe{RestartThreshold: int32Ptr(1)}, 0, false, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			unhealthy, restart := probeOutcome(tc.probe, servicesv1alpha1.ProbeStatus{ConsecutiveFailures: tc.failures})
+			if unhealthy != tc.wantUnhealthy || restart != tc.wantRestart {
+				t.Errorf("got unhealthy %v restart %v, want %v %v", unhealthy, restart, tc.wantUnhealthy, tc.wantRestart)
+			}
+		})
+	}
+}
+
+func TestHandleProbes(t *testing.T) {
+	int32Ptr := func(i int32) *int32 { return &i }
+	lastProbe := metav1.NewTime(time.Now())
+	result := func(failures int32) servicesv1alpha1.ProbeStatus {
+		r := servicesv1alpha1.ProbeStatus{Name: "http", Success: failures == 0, ConsecutiveFailures: failures, LastProbeTime: &lastProbe}
+		if failures > 0 {
+			r.Message = "connection refused"
+		}
+		return r
+	}
+
+	for _, tc := range []struct {
+		name string
+		// unitStatus is the ActiveState of the unit, err an error handling
+		// the unit earlier in the reconcile.
+		unitStatus string
+		err        error
+		probe      servicesv1alpha1.UnitProbe
+		result     servicesv1alpha1.ProbeStatus
+		prev       *servicesv1alpha1.ProbeStatus
+		want       corev1.ConditionStatus
+		wantEvents int
+		// wantProber is whether the prober keeps running.
+		wantProber bool
+	}{{
+		name:       "passing",
+		unitStatus: "active",
+		result:     result(0),
+		want:       corev1.ConditionTrue,
+		wantProber: true,
+	}, {
+		name:       "failing below the threshold",
+		unitStatus: "active",
+		result:     result(defaultFailureThreshold - 1),
+		want:       corev1.ConditionTrue,
+		wantProber: true,
+	}, {
+		name:       "reaching the threshold",
+		unitStatus: "active",
+		result:     result(defaultFailureThreshold),
+		prev:       &servicesv1alpha1.ProbeStatus{Name: "http", ConsecutiveFailures: defaultFailureThreshold - 1},
+		want:       corev1.ConditionFalse,
+		wantEvents: 1,
+		wantProber: true,
+	}, {
+		name:       "still failing",
+		unitStatus: "active",
+		result:     result(defaultFailureThreshold + 1),
+		prev:       &servicesv1alpha1.ProbeStatus{Name: "http", ConsecutiveFailures: defaultFailureThreshold},
+		want:       corev1.ConditionFalse,
+		wantProber: true,
+	}, {
+		name:       "no restart after an earlier error",
+		unitStatus: "active",
+		err:        errors.New("start failed"),
+		probe:      servicesv1alpha1.UnitProbe{RestartThreshold: int32Ptr(1)},
+		result:     result(1),
+		want:       corev1.ConditionTrue,
+		wantProber: true,
+	}, {
+		name:       "not active",
+		unitStatus: "inactive",
+		result:     result(defaultFailureThreshold),
+		want:       corev1.ConditionUnknown,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(10)
+			r := &Reconciler{Recorder: recorder}
+			systemd := cachedSystemd("default", "web", 1)
+			unit := systemd.Spec.Units[0].DeepCopy()
+			probe := tc.probe
+			probe.Name = "http"
+			probe.TCPSocket = &servicesv1alpha1.TCPSocketProbe{Port: 80}
+			unit.Probes = []servicesv1alpha1.UnitProbe{probe}
+			key := newUnitKey(context.Background(), systemd, unit.User, unit.Name)
+
+			// A running prober with the results, which never probes itself.
+			r.probers = map[unitKey]*prober{key: {
+				probes:  []servicesv1alpha1.UnitProbe{*probe.DeepCopy()},
+				stop:    func() {},
+				results: map[string]servicesv1alpha1.ProbeStatus{"http": tc.result},
+			}}
+			var prev *servicesv1alpha1.UnitStatus
+			if tc.prev != nil {
+				prev = &servicesv1alpha1.UnitStatus{Name: unit.Name, Probes: []servicesv1alpha1.ProbeStatus{*tc.prev}}
+			}
+			s := &status{Name: unit.Name, Status: tc.unitStatus, Error: tc.err}
+
+			r.handleProbes(context.Background(), nil, systemd, unit, prev, s, key)
+
+			var got corev1.ConditionStatus
+			for _, c := range s.Conditions {
+				if c.Type == servicesv1alpha1.HealthyCondition {
+					got = c.Status
+				}
+			}
+			if got != tc.want {
+				t.Errorf("got Healthy %q, want %q", got, tc.want)
+			}
+			if len(recorder.Events) != tc.wantEvents {
+				t.Errorf("got %d events, want %d", len(recorder.Events), tc.wantEvents)
+			}
+			if _, ok := r.probers[key]; ok != tc.wantProber {
+				t.Errorf("prober running %v, want %v", ok, tc.wantProber)
+			}
+			if tc.wantProber && (len(s.Probes) != 1 || s.Probes[0].ConsecutiveFailures != tc.result.ConsecutiveFailures) {
+				t.Errorf("got probe results %+v", s.Probes)
+			}
+		})
+	}
+}
+
+func TestProbeExec(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		command []string
+		timeout time.Duration
+		// wantErr is a prefix of the error, no error is expected when empty.
+		wantErr string
+		// maxLen bounds the length of the error.
+		maxLen int
+	}{{
+		name:    "success",
+		command: []string{"sh", "-c", "echo ok"},
+	}, {
+		name:    "failure with output",
+		command: []string{"sh", "-c", "echo not ready >&2; exit 1"},
+		wantErr: "exit status 1: not ready",
+	}, {
+		name:    "output is capped",
+		command: []string{"sh", "-c", "head -c 10000000 /dev/zero | tr '\\0' x; exit 2"},
+		wantErr: "exit status 2: xxx",
+		maxLen:  len("exit status 2: ") + maxProbeMessageLength,
+	}, {
+		name:    "timeout",
+		command: []string{"sh", "-c", "sleep 10 & wait"},
+		timeout: 100 * time.Millisecond,
+		wantErr: "command timed out",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tc.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tc.timeout)
+				defer cancel()
+			}
+			err := probeExec(ctx, servicesv1alpha1.ExecProbe{Command: tc.command}, "")
+			switch {
+			case tc.wantErr == "" && err != nil:
+				t.Errorf("got error %v", err)
+			case tc.wantErr != "" && (err == nil || !strings.HasPrefix(err.Error(), tc.wantErr)):
+				t.Errorf("got error %v, want %q", err, tc.wantErr)
+			case tc.maxLen > 0 && len(err.Error()) > tc.maxLen:
+				t.Errorf("got %d bytes of error, want at most %d", len(err.Error()), tc.maxLen)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package systemd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	conditionsv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/apis/conditions/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/util/conditions"
+	corev1 "k8s.io/api/core/v1"
+
+	servicesv1alpha1 "github.com/faroshq/plugin-services/pkg/apis/services/v1alpha1"
+)
+
+// maxUnitProcesses bounds the processes listed in the status of a unit, so a
+// forking server can not blow up the size of the object.
+const maxUnitProcesses = 50
+
+// maxUnitSockets bounds the listening sockets listed in the status of a unit.
+const maxUnitSockets = 32
+
+// cgroupUnitTypes maps unit type suffixes to the dbus interface holding the
+// ControlGroup property, for the types which run processes.
+var cgroupUnitTypes = map[string]string{
+	".service": "Service",
+	".socket":  "Socket",
+	".mount":   "Mount",
+	".swap":    "Swap",
+	".slice":   "Slice",
+	".scope":   "Scope",
+}
+
+// unitType returns the dbus interface of the type specific properties of the
+// named unit, or "" for types without processes.
+func unitType(name string) string {
+	for suffix, unitType := range cgroupUnitTypes {
+		if strings.HasSuffix(name, suffix) {
+			return unitType
+		}
+	}
+	return ""
+}
+
+// unitProcesses describes the processes in the control group of a unit.
+type unitProcesses struct {
+	count int32
+	// list holds the first maxUnitProcesses processes.
+	list []servicesv1alpha1.UnitProcess
+	// zombies exited without being reaped by their parent, orphans were
+	// reparented after their parent in the unit exited.
+	zombies, orphans int32
+	// listening holds the first maxUnitSockets sockets the processes listen on.
+	listening []servicesv1alpha1.ListeningSocket
+}
+
+// listUnitProcesses lists the processes in the control group of a unit.
+// managed are the pids systemd itself spawned in it, the main and control
+// process, which are children of the manager rather than orphans. Failures
+// are logged only, the process list is informational.
+func (r *Reconciler) listUnitProcesses(logger logr.Logger, controlGroup string, managed ...uint32) unitProcesses {
+	var result unitProcesses
+	if r.Processes == nil || controlGroup == "" {
+		return result
+	}
+	processes, err := r.Processes.CgroupProcesses(controlGroup)
+	if err != nil {
+		logger.V(1).Info("unable to list processes of unit", "controlGroup", controlGroup, "error", err.Error())
+		return result
+	}
+
+	pids := make(map[int]bool, len(processes))
+	pidList := make([]int, 0, len(processes))
+	for _, p := range processes {
+		pids[p.PID] = true
+		pidList = append(pidList, p.PID)
+	}
+	spawned := map[int]bool{}
+	for _, pid := range managed {
+		if pid != 0 {
+			spawned[int(pid)] = true
+		}
+	}
+
+	result.count = int32(len(processes))
+	for i, p := range processes {
+		if p.State == "Z" {
+			result.zombies++
+		}
+		if !pids[p.PPID] && !spawned[p.PID] {
+			result.orphans++
+		}
+		if i < maxUnitProcesses {
+			result.list = append(result.list, servicesv1alpha1.UnitProcess{
+				PID:      int32(p.PID),
+				Command:  p.Comm,
+				State:    p.State,
+				RSSBytes: int64(p.RSS),
+			})
+		}
+	}
+
+	sockets, err := r.Processes.ListeningSockets(pidList)
+	if err != nil {
+		logger.V(1).Info("unable to list sockets of unit", "controlGroup", controlGroup, "error", err.Error())
+		return result
+	}
+	for i, s := range sockets {
+		if i == maxUnitSockets {
+			break
+		}
+		result.listening = append(result.listening, servicesv1alpha1.ListeningSocket{
+			Protocol: s.Protocol,
+			Address:  s.Address,
+			Port:     int32(s.Port),
+			PID:      int32(s.PID),
+		})
+	}
+	return result
+}
+
+// setZombiesCondition reports in the ZombiesDetected condition which units
+// have zombie processes.
+func setZombiesCondition(systemd *servicesv1alpha1.Systemd) {
+	var units []string
+	for _, unit := range systemd.Status.Units {
+		if unit.Zombies > 0 {
+			units = append(units, fmt.Sprintf("%s (%d)", describeUnit(unit.User, unit.Name), unit.Zombies))
+		}
+	}
+	if len(units) == 0 {
+		conditions.Set(systemd, &conditionsv1alpha1.Condition{
+			Type:   servicesv1alpha1.ZombiesDetectedCondition,
+			Status: corev1.ConditionFalse,
+			Reason: "NoZombies",
+		})
+		return
+	}
+	conditions.Set(systemd, &conditionsv1alpha1.Condition{
+		Type:     servicesv1alpha1.ZombiesDetectedCondition,
+		Status:   corev1.ConditionTrue,
+		Severity: conditionsv1alpha1.ConditionSeverityWarning,
+		Reason:   "ZombiesFound",
+		Message:  "Units with zombie processes: " + strings.Join(units, ", "),
+	})
+}
@@ -0,0 +1,138 @@
+package systemd
+
+import (
+	"fmt"
+	"syscall"
+	"time"
+
+	conditionsv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/apis/conditions/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/util/conditions"
+	corev1 "k8s.io/api/core/v1"
+
+	servicesv1alpha1 "github.com/faroshq/plugin-services/pkg/apis/services/v1alpha1"
+	"github.com/faroshq/plugin-services/pkg/util/proc"
+)
+
+const (
+	defaultStablePeriod = 10 * time.Second
+	maxStablePeriod     = 10 * time.Minute
+	// startingRecheck is how often a unit which is still activating is
+	// checked again.
+	startingRecheck = 5 * time.Second
+)
+
+// Codes of ExecMainCode, see waitid(2).
+const (
+	cldExited = 1
+	cldKilled = 2
+	cldDumped = 3
+)
+
+// handleReadiness records in the Ready condition of a unit with a readiness
+// policy whether it stayed active for the stable period. Units still starting
+// or stabilizing are marked pending and checked again, units which stopped
+// running fail with CrashedAfterStart.
+func (r *Reconciler) handleReadiness(systemd *servicesv1alpha1.Systemd, unit *servicesv1alpha1.Unit, prev *servicesv1alpha1.UnitStatus, s *status, subState string, activeEnter uint64) {
+	if unit.Readiness == nil || s.Error != nil {
+		return
+	}
+	notReady := func(reason, messageFormat string, args ...interface{}) {
+		s.Pending = true
+		setUnitCondition(&s.Conditions, prev, conditions.FalseCondition(conditionsv1alpha1.ReadyCondition, reason, conditionsv1alpha1.ConditionSeverityInfo, messageFormat, args...))
+	}
+
+	switch {
+	case s.Status == "active":
+		period := stablePeriod(*unit.Readiness)
+		now := time.Now()
+		if r.now != nil {
+			now = r.now()
+		}
+		active := now.Sub(time.UnixMicro(int64(activeEnter)))
+		if remaining := period - active; remaining > 0 {
+			notReady("Stabilizing", "Unit is active for %s of %s", active.Truncate(time.Second), period)
+			s.checkIn(remaining)
+			return
+		}
+		if unit.Readiness.WaitForProbes && len(unit.Probes) > 0 && !isTrue(s.Conditions, servicesv1alpha1.HealthyCondition) {
+			// Probes requeue the unit themselves.
+			notReady("WaitingForProbes", "Unit is active, waiting for its probes to pass")
+			return
+		}
+		setUnitCondition(&s.Conditions, prev, conditions.TrueCondition(conditionsv1alpha1.ReadyCondition))
+
+	case s.Status == "activating" && subState != "auto-restart", s.Status == "reloading":
+		notReady("Starting", "Unit is %s", s.Status)
+		s.checkIn(startingRecheck)
+
+	case s.Status == "inactive" && (s.MainExit == nil || s.MainExit.Result == "success"):
+		// A oneshot service which ran to completion.
+		setUnitCondition(&s.Conditions, prev, conditions.FalseCondition(conditionsv1alpha1.ReadyCondition, "Exited", conditionsv1alpha1.ConditionSeverityInfo, "Unit exited successfully"))
+
+	default:
+		s.Error = fmt.Errorf("unit %s stopped running after start: %s", unit.Name, describeExit(s.MainExit))
+		setUnitCondition(&s.Conditions, prev, conditions.FalseCondition(conditionsv1alpha1.ReadyCondition, servicesv1alpha1.CrashedAfterStartReason, conditionsv1alpha1.ConditionSeverityError, "%v", s.Error))
+		if prev == nil || conditionReason(prev.Conditions, conditionsv1alpha1.ReadyCondition) != servicesv1alpha1.CrashedAfterStartReason {
+			r.eventf(systemd, corev1.EventTypeWarning, ReasonCrashedAfterStart, "Unit %s stopped running after start: %s", unit.Name, describeExit(s.MainExit))
+		}
+	}
+}
+
+// mainExit returns how the main process of a service last exited, nil when
+// it did not exit yet.
+func mainExit(typeProps map[string]interface{}) *servicesv1alpha1.ProcessExit {
+	code, _ := typeProps["ExecMainCode"].(int32)
+	status, _ := typeProps["ExecMainStatus"].(int32)
+	result, _ := typeProps["Result"].(string)
+
+	exit := &servicesv1alpha1.ProcessExit{Result: result}
+	switch code {
+	case cldExited:
+		exit.ExitCode = &status
+	case cldKilled, cldDumped:
+		exit.Signal = proc.SignalName(syscall.Signal(status))
+	default:
+		return nil
+	}
+	return exit
+}
+
+// describeExit returns a message describing how a process exited.
+func describeExit(exit *servicesv1alpha1.ProcessExit) string {
+	switch {
+	case exit == nil:
+		return "main process did not run"
+	case exit.ExitCode != nil:
+		return fmt.Sprintf("main process exited with code %d, result %s", *exit.ExitCode, exit.Result)
+	case exit.Signal != "":
+		return fmt.Sprintf("main process killed by %s, result %s", exit.Signal, exit.Result)
+	}
+	return "result " + exit.Result
+}
+
+func stablePeriod(readiness servicesv1alpha1.UnitReadiness) time.Duration {
+	if readiness.StablePeriod == nil {
+		return defaultStablePeriod
+	}
+	return readiness.StablePeriod.Duration
+}
+
+// isTrue reports whether the condition of type t in c is true.
+func isTrue(c conditionsv1alpha1.Conditions, t conditionsv1alpha1.ConditionType) bool {
+	for _, condition := range c {
+		if condition.Type == t {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// conditionReason returns the reason of the condition of type t in c.
+func conditionReason(c conditionsv1alpha1.Conditions, t conditionsv1alpha1.ConditionType) string {
+	for _, condition := range c {
+		if condition.Type == t {
+			return condition.Reason
+		}
+	}
+	return ""
+}
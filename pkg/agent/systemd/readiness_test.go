@@ -0,0 +1,217 @@
+package systemd
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	conditionsv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/apis/conditions/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	servicesv1alpha1 "github.com/faroshq/plugin-services/pkg/apis/services/v1alpha1"
+)
+
+func TestHandleReadiness(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	int32Ptr := func(i int32) *int32 { return &i }
+	crashed := conditionsv1alpha1.Conditions{{
+		Type:   conditionsv1alpha1.ReadyCondition,
+		Status: corev1.ConditionFalse,
+		Reason: servicesv1alpha1.CrashedAfterStartReason,
+	}}
+	healthy := conditionsv1alpha1.Conditions{{Type: servicesv1alpha1.HealthyCondition, Status: corev1.ConditionTrue}}
+
+	for _, tc := range []struct {
+		name      string
+		readiness *servicesv1alpha1.UnitReadiness
+		probes    bool
+		// unitStatus and subState are the ActiveState and SubState of the
+		// unit, active how long it has been active.
+		unitStatus, subState string
+		active               time.Duration
+		exit                 *servicesv1alpha1.ProcessExit
+		conditions           conditionsv1alpha1.Conditions
+		err                  error
+		prev                 conditionsv1alpha1.Conditions
+
+		// wantStatus and wantReason describe the Ready condition, which is
+		// not set when wantStatus is empty.
+		wantStatus  corev1.ConditionStatus
+		wantReason  string
+		wantPending bool
+		wantCheckIn time.Duration
+		wantErr     bool
+		wantEvents  int
+	}{{
+		name:       "no readiness policy",
+		unitStatus: "active",
+	}, {
+		name:       "earlier error",
+		readiness:  &servicesv1alpha1.UnitReadiness{},
+		unitStatus: "failed",
+		err:        errors.New("start failed"),
+		wantErr:    true,
+	}, {
+		name:        "stabilizing",
+		readiness:   &servicesv1alpha1.UnitReadiness{},
+		unitStatus:  "active",
+		active:      defaultStablePeriod - time.Second,
+		wantStatus:  corev1.ConditionFalse,
+		wantReason:  "Stabilizing",
+		wantPending: true,
+		wantCheckIn: time.Second,
+	}, {
+		name:       "stable period elapsed",
+		readiness:  &servicesv1alpha1.UnitReadiness{},
+		unitStatus: "active",
+		active:     defaultStablePeriod,
+		wantStatus: corev1.ConditionTrue,
+	}, {
+		name:        "custom stable period",
+		readiness:   &servicesv1alpha1.UnitReadiness{StablePeriod: &metav1.Duration{Duration: time.Minute}},
+		unitStatus:  "active",
+		active:      defaultStablePeriod,
+		wantStatus:  corev1.ConditionFalse,
+		wantReason:  "Stabilizing",
+		wantPending: true,
+		wantCheckIn: time.Minute - defaultStablePeriod,
+	}, {
+		name:        "waiting for probes",
+		readiness:   &servicesv1alpha1.UnitReadiness{WaitForProbes: true},
+		probes:      true,
+		unitStatus:  "active",
+		active:      time.Hour,
+		wantStatus:  corev1.ConditionFalse,
+		wantReason:  "WaitingForProbes",
+		wantPending: true,
+	}, {
+		name:       "probes passed",
+		readiness:  &servicesv1alpha1.UnitReadiness{WaitForProbes: true},
+		probes:     true,
+		unitStatus: "active",
+		active:     time.Hour,
+		conditions: healthy,
+		wantStatus: corev1.ConditionTrue,
+	}, {
+		name:       "no probes to wait for",
+		readiness:  &servicesv1alpha1.UnitReadiness{WaitForProbes: true},
+		unitStatus: "active",
+		active:     time.Hour,
+		wantStatus: corev1.ConditionTrue,
+	}, {
+		name:        "starting",
+		readiness:   &servicesv1alpha1.UnitReadiness{},
+		unitStatus:  "activating",
+		subState:    "start",
+		wantStatus:  corev1.ConditionFalse,
+		wantReason:  "Starting",
+		wantPending: true,
+		wantCheckIn: startingRecheck,
+	}, {
+		name:        "reloading",
+		readiness:   &servicesv1alpha1.UnitReadiness{},
+		unitStatus:  "reloading",
+		wantStatus:  corev1.ConditionFalse,
+		wantReason:  "Starting",
+		wantPending: true,
+		wantCheckIn: startingRecheck,
+	}, {
+		name:       "waiting to restart after a crash",
+		readiness:  &servicesv1alpha1.UnitReadiness{},
+		unitStatus: "activating",
+		subState:   "auto-restart",
+		exit:       &servicesv1alpha1.ProcessExit{ExitCode: int32Ptr(1), Result: "exit-code"},
+		wantStatus: corev1.ConditionFalse,
+		wantReason: servicesv1alpha1.CrashedAfterStartReason,
+		wantErr:    true,
+		wantEvents: 1,
+	}, {
+		name:       "oneshot exited",
+		readiness:  &servicesv1alpha1.UnitReadiness{},
+		unitStatus: "inactive",
+		exit:       &servicesv1alpha1.ProcessExit{ExitCode: int32Ptr(0), Result: "success"},
+		wantStatus: corev1.ConditionFalse,
+		wantReason: "Exited",
+	}, {
+		name:       "inactive without a main process",
+		readiness:  &servicesv1alpha1.UnitReadiness{},
+		unitStatus: "inactive",
+		wantStatus: corev1.ConditionFalse,
+		wantReason: "Exited",
+	}, {
+		name:       "inactive after a failure",
+		readiness:  &servicesv1alpha1.UnitReadiness{},
+		unitStatus: "inactive",
+		exit:       &servicesv1alpha1.ProcessExit{Signal: "SIGKILL", Result: "signal"},
+		wantStatus: corev1.ConditionFalse,
+		wantReason: servicesv1alpha1.CrashedAfterStartReason,
+		wantErr:    true,
+		wantEvents: 1,
+	}, {
+		name:       "crashed",
+		readiness:  &servicesv1alpha1.UnitReadiness{},
+		unitStatus: "failed",
+		exit:       &servicesv1alpha1.ProcessExit{ExitCode: int32Ptr(2), Result: "exit-code"},
+		wantStatus: corev1.ConditionFalse,
+		wantReason: servicesv1alpha1.CrashedAfterStartReason,
+		wantErr:    true,
+		wantEvents: 1,
+	}, {
+		name:       "still crashed",
+		readiness:  &servicesv1alpha1.UnitReadiness{},
+		unitStatus: "failed",
+		exit:       &servicesv1alpha1.ProcessExit{ExitCode: int32Ptr(2), Result: "exit-code"},
+		prev:       crashed,
+		wantStatus: corev1.ConditionFalse,
+		wantReason: servicesv1alpha1.CrashedAfterStartReason,
+		wantErr:    true,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(10)
+			r := &Reconciler{Recorder: recorder, now: func() time.Time { return now }}
+			systemd := cachedSystemd("default", "web", 1)
+			unit := systemd.Spec.Units[0].DeepCopy()
+			unit.Readiness = tc.readiness
+			if tc.probes {
+				unit.Probes = []servicesv1alpha1.UnitProbe{{Name: "http", TCPSocket: &servicesv1alpha1.TCPSocketProbe{Port: 80}}}
+			}
+			var prev *servicesv1alpha1.UnitStatus
+			if tc.prev != nil {
+				prev = &servicesv1alpha1.UnitStatus{Name: unit.Name, Conditions: tc.prev}
+			}
+			s := &status{Name: unit.Name, Status: tc.unitStatus, Error: tc.err, MainExit: tc.exit, Conditions: tc.conditions.DeepCopy()}
+			activeEnter := uint64(now.Add(-tc.active).UnixMicro())
+
+			r.handleReadiness(systemd, unit, prev, s, tc.subState, activeEnter)
+
+			var ready *conditionsv1alpha1.Condition
+			for i := range s.Conditions {
+				if s.Conditions[i].Type == conditionsv1alpha1.ReadyCondition {
+					ready = &s.Conditions[i]
+				}
+			}
+			switch {
+			case tc.wantStatus == "" && ready != nil:
+				t.Errorf("got Ready %s %s, want none", ready.Status, ready.Reason)
+			case tc.wantStatus != "" && ready == nil:
+				t.Errorf("got no Ready condition, want %s %s", tc.wantStatus, tc.wantReason)
+			case ready != nil && (ready.Status != tc.wantStatus || ready.Reason != tc.wantReason):
+				t.Errorf("got Ready %s %s, want %s %s", ready.Status, ready.Reason, tc.wantStatus, tc.wantReason)
+			}
+			if s.Pending != tc.wantPending {
+				t.Errorf("got pending %v, want %v", s.Pending, tc.wantPending)
+			}
+			if s.CheckIn != tc.wantCheckIn {
+				t.Errorf("got check in %v, want %v", s.CheckIn, tc.wantCheckIn)
+			}
+			if (s.Error != nil) != tc.wantErr {
+				t.Errorf("got error %v, want error %v", s.Error, tc.wantErr)
+			}
+			if len(recorder.Events) != tc.wantEvents {
+				t.Errorf("got %d events, want %d", len(recorder.Events), tc.wantEvents)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package systemd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/coreos/go-systemd/v22/dbus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	servicesv1alpha1 "github.com/faroshq/plugin-services/pkg/apis/services/v1alpha1"
+)
+
+const (
+	defaultRecoveryAttempts = 5
+	maxRecoveryAttempts     = 100
+	defaultInitialBackoff   = 10 * time.Second
+	minInitialBackoff       = time.Second
+	defaultMaxBackoff       = 5 * time.Minute
+	// recoveryResetAfter is how long a recovered unit has to stay active
+	// before its attempts are forgotten.
+	recoveryResetAfter = 10 * time.Minute
+)
+
+// handleRecovery decides whether a planned start of a failed unit with a
+// recovery policy happens on this reconcile. Before an attempt the failed
+// state of the unit is reset, so systemd does not refuse the start because of
+// its start limit. Until the backoff expired, or once all attempts were used,
+// the start is skipped. The attempts start over when the unit is changed. It
+// returns whether to start the unit and records the attempts in s.
+func (r *Reconciler) handleRecovery(ctx context.Context, conn *dbus.Conn, systemd *servicesv1alpha1.Systemd, unit *servicesv1alpha1.Unit, prev *servicesv1alpha1.UnitStatus, s *status, start bool, activeState string, activeSince uint64) bool {
+	if unit.Recovery == nil {
+		return start
+	}
+	hash := unitHash(unit)
+	if prev != nil {
+		s.Recovery = prev.Recovery.DeepCopy()
+	}
+	if activeState == "active" && time.Since(time.UnixMicro(int64(activeSince))) >= recoveryResetAfter {
+		s.Recovery = nil
+	}
+	if s.Recovery != nil && s.Recovery.UnitHash != hash {
+		// The unit was changed, which may have fixed it.
+		s.Recovery = nil
+	}
+	if !start || activeState != "failed" || s.Error != nil {
+		return start
+	}
+
+	policy := *unit.Recovery
+	attempts := policy.MaxAttempts
+	if attempts <= 0 {
+		attempts = defaultRecoveryAttempts
+	}
+	if s.Recovery == nil {
+		s.Recovery = &servicesv1alpha1.RecoveryStatus{UnitHash: hash}
+	}
+	recovery := s.Recovery
+	now := metav1.Now()
+
+	if recovery.Attempts >= attempts {
+		if !recovery.GaveUp {
+			r.eventf(systemd, corev1.EventTypeWarning, ReasonFailed, "Gave up recovering unit %s after %d attempts", unit.Name, recovery.Attempts)
+		}
+		recovery.GaveUp = true
+		recovery.NextAttemptTime = nil
+		s.Error = fmt.Errorf("unit %s failed, gave up recovering it after %d attempts", unit.Name, recovery.Attempts)
+		return false
+	}
+	if recovery.NextAttemptTime != nil && now.Before(recovery.NextAttemptTime) {
+		s.checkIn(recovery.NextAttemptTime.Sub(now.Time))
+		s.Error = fmt.Errorf("unit %s failed, starting it again at %s (attempt %d of %d)", unit.Name, recovery.NextAttemptTime.UTC().Format(time.RFC3339), recovery.Attempts+1, attempts)
+		return false
+	}
+
+	if err := observeOperation("reset_failed", conn.ResetFailedUnitContext(ctx, unit.Name)); err != nil {
+		r.eventf(systemd, corev1.EventTypeWarning, ReasonFailed, "Failed to reset failed unit %s: %v", unit.Name, err)
+		s.Error = err
+		return false
+	}
+	recovery.Attempts++
+	recovery.LastAttemptTime = &now
+	delay := recoveryBackoff(policy, recovery.Attempts)
+	next := metav1.NewTime(now.Add(delay))
+	recovery.NextAttemptTime = &next
+	// Check whether the unit failed again once the next attempt is due.
+	s.checkIn(delay)
+	r.eventf(systemd, corev1.EventTypeNormal, ReasonRecovering, "Reset failed unit %s, starting it again (attempt %d of %d)", unit.Name, recovery.Attempts, attempts)
+	return true
+}
+
+// recoveryBackoff returns the delay after the given attempt, InitialBackoff
+// doubled for every attempt before, capped at MaxBackoff.
+func recoveryBackoff(policy servicesv1alpha1.RecoveryPolicy, attempt int32) time.Duration {
+	delay, limit := recoveryBackoffs(policy)
+	for i := int32(1); i < attempt && delay < limit; i++ {
+		// Stop before doubling overflows, limit may be close to the maximum.
+		if delay > limit/2 {
+			return limit
+		}
+		delay *= 2
+	}
+	if delay > limit {
+		return limit
+	}
+	return delay
+}
+
+func recoveryBackoffs(policy servicesv1alpha1.RecoveryPolicy) (initial, limit time.Duration) {
+	initial, limit = defaultInitialBackoff, defaultMaxBackoff
+	if policy.InitialBackoff != nil {
+		initial = policy.InitialBackoff.Duration
+	}
+	if policy.MaxBackoff != nil {
+		limit = policy.MaxBackoff.Duration
+	}
+	return initial, limit
+}
@@ -0,0 +1,43 @@
+package systemd
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	servicesv1alpha1 "github.com/faroshq/plugin-services/pkg/apis/services/v1alpha1"
+)
+
+func TestRecoveryBackoff(t *testing.T) {
+	duration := func(d time.Duration) *metav1.Duration {
+		return &metav1.Duration{Duration: d}
+	}
+
+	for _, tc := range []struct {
+		name    string
+		policy  servicesv1alpha1.RecoveryPolicy
+		attempt int32
+		want    time.Duration
+	}{
+		{"defaults first attempt", servicesv1alpha1.RecoveryPolicy{}, 1, defaultInitialBackoff},
+		{"defaults second attempt", servicesv1alpha1.RecoveryPolicy{}, 2, 2 * defaultInitialBackoff},
+		{"defaults fifth attempt", servicesv1alpha1.RecoveryPolicy{}, 5, 16 * defaultInitialBackoff},
+		{"defaults capped", servicesv1alpha1.RecoveryPolicy{}, 10, defaultMaxBackoff},
+		{"no attempt yet", servicesv1alpha1.RecoveryPolicy{}, 0, defaultInitialBackoff},
+		{"custom initial", servicesv1alpha1.RecoveryPolicy{InitialBackoff: duration(time.Second)}, 3, 4 * time.Second},
+		{"custom initial capped by default max", servicesv1alpha1.RecoveryPolicy{InitialBackoff: duration(time.Minute)}, 4, defaultMaxBackoff},
+		{"custom max", servicesv1alpha1.RecoveryPolicy{MaxBackoff: duration(25 * time.Second)}, 3, 25 * time.Second},
+		{"max below default initial", servicesv1alpha1.RecoveryPolicy{MaxBackoff: duration(5 * time.Second)}, 1, 5 * time.Second},
+		{"initial equals max", servicesv1alpha1.RecoveryPolicy{InitialBackoff: duration(time.Minute), MaxBackoff: duration(time.Minute)}, 7, time.Minute},
+		{"last attempt", servicesv1alpha1.RecoveryPolicy{}, maxRecoveryAttempts, defaultMaxBackoff},
+		{"very large max", servicesv1alpha1.RecoveryPolicy{InitialBackoff: duration(time.Second), MaxBackoff: duration(math.MaxInt64)}, maxRecoveryAttempts, math.MaxInt64},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := recoveryBackoff(tc.policy, tc.attempt); got != tc.want {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
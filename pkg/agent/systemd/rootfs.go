@@ -0,0 +1,526 @@
+package systemd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	persistentConfigDir = "/etc/systemd/system"
+	runtimeConfigDir    = "/run/systemd/system"
+	// maxSymlinks bounds the symlinks followed resolving a path, as
+	// MAXSYMLINKS does in the kernel.
+	maxSymlinks = 40
+)
+
+var (
+	// unitPaths is the search path of system units, highest priority first.
+	unitPaths = []string{
+		persistentConfigDir,
+		runtimeConfigDir,
+		"/usr/local/lib/systemd/system",
+		"/usr/lib/systemd/system",
+		"/lib/systemd/system",
+	}
+	// presetPaths is the search path of system preset files, highest priority first.
+	presetPaths = []string{
+		"/etc/systemd/system-preset",
+		"/run/systemd/system-preset",
+		"/usr/local/lib/systemd/system-preset",
+		"/usr/lib/systemd/system-preset",
+		"/lib/systemd/system-preset",
+	}
+)
+
+// Rootfs is a UnitFileManager for the system units of an unbooted root
+// filesystem, which works like `systemctl --root`: enablement is done by
+// creating the symlinks listed in the [Install] section of unit files. Links
+// point to paths on the booted system, not into Root. Symlinks below Root are
+// followed as on the booted system, so no path leads outside of Root.
+type Rootfs struct {
+	// Root is the directory the root filesystem is mounted at.
+	Root string
+}
+
+var _ UnitFileManager = Rootfs{}
+
+// installInfo is the [Install] section of a unit file.
+type installInfo struct {
+	// path of the unit file on the booted system.
+	path            string
+	wantedBy        []string
+	requiredBy      []string
+	alias           []string
+	also            []string
+	defaultInstance string
+}
+
+func (i *installInfo) empty() bool {
+	return len(i.wantedBy) == 0 && len(i.requiredBy) == 0 && len(i.alias) == 0 && len(i.also) == 0
+}
+
+func (f Rootfs) UnitFileState(ctx context.Context, name string) (string, string, error) {
+	for _, c := range []struct {
+		dir, state string
+	}{
+		{persistentConfigDir, "masked"},
+		{runtimeConfigDir, "masked-runtime"},
+	} {
+		if f.isMask(path.Join(c.dir, name)) {
+			return c.state, "", nil
+		}
+	}
+
+	info, err := f.readInstall(name)
+	if err != nil {
+		return "", "", err
+	}
+	preset, err := f.preset(name)
+	if err != nil {
+		return "", "", err
+	}
+
+	for _, c := range []struct {
+		dir, state string
+	}{
+		{persistentConfigDir, "enabled"},
+		{runtimeConfigDir, "enabled-runtime"},
+	} {
+		links, err := f.links(c.dir, name, info.path)
+		if err != nil {
+			return "", "", err
+		}
+		if len(links) > 0 {
+			return c.state, preset, nil
+		}
+	}
+	if info.empty() {
+		return "static", "", nil
+	}
+	return "disabled", preset, nil
+}
+
+func (f Rootfs) Enable(ctx context.Context, name string, runtime bool) error {
+	return f.enable(name, configDir(runtime), map[string]bool{})
+}
+
+func (f Rootfs) enable(name, dir string, visited map[string]bool) error {
+	if visited[name] {
+		return nil
+	}
+	visited[name] = true
+
+	info, err := f.readInstall(name)
+	if err != nil {
+		return err
+	}
+
+	linkName := name
+	if prefix, instance, ok := splitInstance(name); ok && instance == "" && !info.empty() {
+		if info.defaultInstance == "" {
+			return fmt.Errorf("template %s has no DefaultInstance, enable an instance of it instead", name)
+		}
+		linkName = prefix + "@" + info.defaultInstance + path.Ext(name)
+	}
+
+	for _, names := range [][]string{info.wantedBy, info.requiredBy, info.alias, info.also} {
+		for _, n := range names {
+			if strings.Contains(n, "/") {
+				return fmt.Errorf("invalid unit name %q in the [Install] section of %s", n, info.path)
+			}
+		}
+	}
+
+	for _, target := range info.wantedBy {
+		if err := f.symlink(info.path, path.Join(dir, target+".wants", linkName)); err != nil {
+			return err
+		}
+	}
+	for _, target := range info.requiredBy {
+		if err := f.symlink(info.path, path.Join(dir, target+".requires", linkName)); err != nil {
+			return err
+		}
+	}
+	for _, alias := range info.alias {
+		if err := f.symlink(info.path, path.Join(dir, alias)); err != nil {
+			return err
+		}
+	}
+	for _, also := range info.also {
+		if err := f.enable(also, dir, visited); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f Rootfs) Disable(ctx context.Context, name string, runtime bool) error {
+	return f.disable(name, configDir(runtime), map[string]bool{})
+}
+
+func (f Rootfs) disable(name, dir string, visited map[string]bool) error {
+	if visited[name] {
+		return nil
+	}
+	visited[name] = true
+
+	info, err := f.readInstall(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		// Still clean up links left behind by a removed unit file.
+		info, err = &installInfo{}, nil
+	}
+	if err != nil {
+		return err
+	}
+
+	links, err := f.links(dir, name, info.path)
+	if err != nil {
+		return err
+	}
+	for _, link := range links {
+		p, err := f.path(link, false)
+		if err != nil {
+			return err
+		}
+		if err := os.Remove(p); err != nil {
+			return err
+		}
+	}
+	for _, also := range info.also {
+		if err := f.disable(also, dir, visited); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f Rootfs) Mask(ctx context.Context, name string, runtime bool) error {
+	link := path.Join(configDir(runtime), name)
+	if f.isMask(link) {
+		return nil
+	}
+	p, err := f.path(link, false)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Lstat(p); err == nil {
+		return fmt.Errorf("unable to mask %s, %s already exists", name, link)
+	}
+	return f.symlink("/dev/null", link)
+}
+
+func (f Rootfs) Unmask(ctx context.Context, name string, runtime bool) error {
+	link := path.Join(configDir(runtime), name)
+	if !f.isMask(link) {
+		return nil
+	}
+	p, err := f.path(link, false)
+	if err != nil {
+		return err
+	}
+	return os.Remove(p)
+}
+
+// path returns where p of the booted system is found below Root. Symlinks in
+// its directories are followed as on the booted system, absolute ones relative
+// to Root and ".." never above it, so the result is always below Root. The
+// last element is only followed when follow is set, like stat(2) and lstat(2).
+func (f Rootfs) path(p string, follow bool) (string, error) {
+	resolved, err := f.resolve(p, follow)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(f.Root, filepath.FromSlash(resolved)), nil
+}
+
+// resolve returns p of the booted system with its symlinks resolved.
+// Elements which do not exist are kept as they are.
+func (f Rootfs) resolve(p string, follow bool) (string, error) {
+	resolved := "/"
+	rest := strings.Split(p, "/")
+	links := 0
+	for len(rest) > 0 {
+		name := rest[0]
+		rest = rest[1:]
+		switch name {
+		case "", ".":
+			continue
+		case "..":
+			resolved = path.Dir(resolved)
+			continue
+		}
+
+		next := path.Join(resolved, name)
+		if len(rest) == 0 && !follow {
+			return next, nil
+		}
+		target, err := os.Readlink(filepath.Join(f.Root, filepath.FromSlash(next)))
+		if err != nil {
+			// Not a symlink, or it does not exist yet.
+			resolved = next
+			continue
+		}
+		links++
+		if links > maxSymlinks {
+			return "", fmt.Errorf("too many levels of symbolic links resolving %s", p)
+		}
+		if path.IsAbs(target) {
+			resolved = "/"
+		}
+		rest = append(strings.Split(target, "/"), rest...)
+	}
+	return resolved, nil
+}
+
+// isMask reports whether the link at p masks a unit.
+func (f Rootfs) isMask(p string) bool {
+	link, err := f.path(p, false)
+	if err != nil {
+		return false
+	}
+	target, err := os.Readlink(link)
+	return err == nil && target == "/dev/null"
+}
+
+// symlink creates link pointing to target, unless it already does.
+func (f Rootfs) symlink(target, link string) error {
+	p, err := f.path(link, false)
+	if err != nil {
+		return err
+	}
+	if current, err := os.Readlink(p); err == nil {
+		if current == target {
+			return nil
+		}
+		return fmt.Errorf("unable to link %s to %s, it already points to %s", link, target, current)
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	return os.Symlink(target, p)
+}
+
+// links returns the symlinks in dir which enable or alias the unit, either by
+// name or because they point to its unit file. Links of an instance are only
+// matched by name, as they point to the unit file of the template.
+func (f Rootfs) links(dir, name, unitPath string) ([]string, error) {
+	_, instance, _ := splitInstance(name)
+	var links []string
+	match := func(p string) {
+		link, err := f.path(p, false)
+		if err != nil {
+			return
+		}
+		target, err := os.Readlink(link)
+		if err != nil || target == "/dev/null" {
+			return
+		}
+		if path.Base(p) == name || (instance == "" && unitPath != "" && target == unitPath) {
+			links = append(links, p)
+		}
+	}
+
+	entries, err := f.readDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		p := path.Join(dir, entry.Name())
+		if !entry.IsDir() {
+			// Aliases. A link named after the unit is a linked unit file.
+			if path.Base(p) != name {
+				match(p)
+			}
+			continue
+		}
+		if !strings.HasSuffix(entry.Name(), ".wants") && !strings.HasSuffix(entry.Name(), ".requires") {
+			continue
+		}
+		deps, err := f.readDir(p)
+		if err != nil {
+			return nil, err
+		}
+		for _, dep := range deps {
+			match(path.Join(p, dep.Name()))
+		}
+	}
+	return links, nil
+}
+
+// readInstall finds the unit file of name and parses its [Install] section.
+// Instances without a unit file of their own use the one of their template.
+func (f Rootfs) readInstall(name string) (*installInfo, error) {
+	candidates := []string{name}
+	if prefix, instance, ok := splitInstance(name); ok && instance != "" {
+		candidates = append(candidates, prefix+"@"+path.Ext(name))
+	}
+
+	for _, dir := range unitPaths {
+		for _, candidate := range candidates {
+			p := path.Join(dir, candidate)
+			if f.isMask(p) {
+				continue
+			}
+			data, err := f.readFile(p)
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					continue
+				}
+				return nil, err
+			}
+			info := parseInstall(string(data))
+			info.path = p
+			return info, nil
+		}
+	}
+	return nil, fmt.Errorf("unit file %s does not exist: %w", name, fs.ErrNotExist)
+}
+
+// preset returns whether the preset policy of the root filesystem enables or
+// disables name. Like systemd, units not matched by any rule are enabled.
+func (f Rootfs) preset(name string) (string, error) {
+	files := map[string]string{}
+	for _, dir := range presetPaths {
+		entries, err := f.readDir(dir)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".preset") {
+				continue
+			}
+			// Files in earlier directories override files with the same name.
+			if _, ok := files[entry.Name()]; !ok {
+				files[entry.Name()] = path.Join(dir, entry.Name())
+			}
+		}
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, file := range names {
+		data, err := f.readFile(files[file])
+		if err != nil {
+			return "", err
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) < 2 || strings.HasPrefix(fields[0], "#") || strings.HasPrefix(fields[0], ";") {
+				continue
+			}
+			if ok, _ := path.Match(fields[1], name); !ok {
+				continue
+			}
+			switch fields[0] {
+			case "enable":
+				return "enabled", nil
+			case "disable":
+				return "disabled", nil
+			}
+		}
+	}
+	return "enabled", nil
+}
+
+func (f Rootfs) readFile(p string) ([]byte, error) {
+	resolved, err := f.path(p, true)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(resolved)
+}
+
+func (f Rootfs) readDir(p string) ([]fs.DirEntry, error) {
+	resolved, err := f.path(p, true)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadDir(resolved)
+}
+
+// parseInstall parses the [Install] section of a unit file.
+func parseInstall(data string) *installInfo {
+	info := &installInfo{}
+	section := ""
+	continued := ""
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if continued != "" {
+			line = continued + " " + line
+			continued = ""
+		}
+		if strings.HasSuffix(line, "\\") {
+			continued = strings.TrimSuffix(line, "\\")
+			continue
+		}
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if line[0] == '[' {
+			section = strings.Trim(line, "[]")
+			continue
+		}
+		if section != "Install" {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		values := strings.Fields(value)
+		// An empty assignment resets the list, as in systemd.
+		switch strings.TrimSpace(key) {
+		case "WantedBy":
+			info.wantedBy = appendOrReset(info.wantedBy, values)
+		case "RequiredBy":
+			info.requiredBy = appendOrReset(info.requiredBy, values)
+		case "Alias":
+			info.alias = appendOrReset(info.alias, values)
+		case "Also":
+			info.also = appendOrReset(info.also, values)
+		case "DefaultInstance":
+			info.defaultInstance = value
+		}
+	}
+	return info
+}
+
+func appendOrReset(list, values []string) []string {
+	if len(values) == 0 {
+		return nil
+	}
+	return append(list, values...)
+}
+
+// splitInstance splits a template or instance name like getty@tty1.service
+// into its prefix and instance.
+func splitInstance(name string) (prefix, instance string, ok bool) {
+	base := strings.TrimSuffix(name, path.Ext(name))
+	return strings.Cut(base, "@")
+}
+
+func configDir(runtime bool) string {
+	if runtime {
+		return runtimeConfigDir
+	}
+	return persistentConfigDir
+}
@@ -0,0 +1,389 @@
+package systemd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRootfs(t *testing.T) {
+	const (
+		appFile    = "/usr/lib/systemd/system/app.service"
+		appInstall = "[Unit]\nDescription=app\n\n[Install]\nWantedBy=multi-user.target\n"
+	)
+	enable := func(name string, runtime bool) func(context.Context, Rootfs) error {
+		return func(ctx context.Context, f Rootfs) error { return f.Enable(ctx, name, runtime) }
+	}
+	disable := func(name string, runtime bool) func(context.Context, Rootfs) error {
+		return func(ctx context.Context, f Rootfs) error { return f.Disable(ctx, name, runtime) }
+	}
+	mask := func(name string, runtime bool) func(context.Context, Rootfs) error {
+		return func(ctx context.Context, f Rootfs) error { return f.Mask(ctx, name, runtime) }
+	}
+	unmask := func(name string, runtime bool) func(context.Context, Rootfs) error {
+		return func(ctx context.Context, f Rootfs) error { return f.Unmask(ctx, name, runtime) }
+	}
+
+	for _, tc := range []struct {
+		name  string
+		files map[string]string
+		links map[string]string
+		op    func(context.Context, Rootfs) error
+		// unit whose state is checked after op, app.service when empty.
+		unit      string
+		wantErr   bool
+		state     string
+		preset    string
+		wantLinks map[string]string
+		noLinks   []string
+	}{{
+		name:   "disabled",
+		files:  map[string]string{appFile: appInstall},
+		state:  "disabled",
+		preset: "enabled",
+	}, {
+		name:   "static",
+		files:  map[string]string{appFile: "[Service]\nExecStart=/bin/true\n"},
+		state:  "static",
+		preset: "",
+	}, {
+		name:      "enable",
+		files:     map[string]string{appFile: appInstall},
+		op:        enable("app.service", false),
+		state:     "enabled",
+		preset:    "enabled",
+		wantLinks: map[string]string{"/etc/systemd/system/multi-user.target.wants/app.service": appFile},
+	}, {
+		name:      "enable runtime",
+		files:     map[string]string{appFile: appInstall},
+		op:        enable("app.service", true),
+		state:     "enabled-runtime",
+		preset:    "enabled",
+		wantLinks: map[string]string{"/run/systemd/system/multi-user.target.wants/app.service": appFile},
+		noLinks:   []string{"/etc/systemd/system/multi-user.target.wants/app.service"},
+	}, {
+		name: "enable with alias, requires and also",
+		files: map[string]string{
+			appFile:                              "[Install]\nRequiredBy=network.target\nAlias=web.service\nAlso=app.socket\n",
+			"/usr/lib/systemd/system/app.socket": "[Install]\nWantedBy=sockets.target\nAlso=app.service\n",
+			"/usr/lib/systemd/system/unrelated.socket": "[Install]\nWantedBy=sockets.target\n",
+		},
+		op:     enable("app.service", false),
+		state:  "enabled",
+		preset: "enabled",
+		wantLinks: map[string]string{
+			"/etc/systemd/system/network.target.requires/app.service": appFile,
+			"/etc/systemd/system/web.service":                         appFile,
+			"/etc/systemd/system/sockets.target.wants/app.socket":     "/usr/lib/systemd/system/app.socket",
+		},
+		noLinks: []string{"/etc/systemd/system/sockets.target.wants/unrelated.socket"},
+	}, {
+		name:      "enable is idempotent",
+		files:     map[string]string{appFile: appInstall},
+		links:     map[string]string{"/etc/systemd/system/multi-user.target.wants/app.service": appFile},
+		op:        enable("app.service", false),
+		state:     "enabled",
+		preset:    "enabled",
+		wantLinks: map[string]string{"/etc/systemd/system/multi-user.target.wants/app.service": appFile},
+	}, {
+		name:    "enable refuses to replace a foreign link",
+		files:   map[string]string{appFile: appInstall},
+		links:   map[string]string{"/etc/systemd/system/multi-user.target.wants/app.service": "/opt/app.service"},
+		op:      enable("app.service", false),
+		wantErr: true,
+	}, {
+		name:    "enable missing unit",
+		op:      enable("app.service", false),
+		wantErr: true,
+	}, {
+		name:    "enable rejects paths in the install section",
+		files:   map[string]string{appFile: "[Install]\nAlias=../../../../outside/app.service\n"},
+		op:      enable("app.service", false),
+		wantErr: true,
+	}, {
+		name: "enable template with default instance",
+		files: map[string]string{
+			"/usr/lib/systemd/system/getty@.service": "[Install]\nWantedBy=getty.target\nDefaultInstance=tty1\n",
+		},
+		op:        enable("getty@.service", false),
+		unit:      "getty@tty1.service",
+		state:     "enabled",
+		preset:    "enabled",
+		wantLinks: map[string]string{"/etc/systemd/system/getty.target.wants/getty@tty1.service": "/usr/lib/systemd/system/getty@.service"},
+	}, {
+		name: "enable template without default instance",
+		files: map[string]string{
+			"/usr/lib/systemd/system/getty@.service": "[Install]\nWantedBy=getty.target\n",
+		},
+		op:      enable("getty@.service", false),
+		wantErr: true,
+	}, {
+		name: "enable instance",
+		files: map[string]string{
+			"/usr/lib/systemd/system/getty@.service": "[Install]\nWantedBy=getty.target\n",
+		},
+		op:        enable("getty@tty2.service", false),
+		unit:      "getty@tty2.service",
+		state:     "enabled",
+		preset:    "enabled",
+		wantLinks: map[string]string{"/etc/systemd/system/getty.target.wants/getty@tty2.service": "/usr/lib/systemd/system/getty@.service"},
+		noLinks:   []string{"/etc/systemd/system/getty.target.wants/getty@tty1.service"},
+	}, {
+		name:  "disable",
+		files: map[string]string{appFile: "[Install]\nWantedBy=multi-user.target\nAlias=web.service\n"},
+		links: map[string]string{
+			"/etc/systemd/system/multi-user.target.wants/app.service": appFile,
+			"/etc/systemd/system/web.service":                         appFile,
+			"/etc/systemd/system/default.target.wants/app.service":    appFile,
+			"/etc/systemd/system/multi-user.target.wants/db.service":  "/usr/lib/systemd/system/db.service",
+		},
+		op:        disable("app.service", false),
+		state:     "disabled",
+		preset:    "enabled",
+		wantLinks: map[string]string{"/etc/systemd/system/multi-user.target.wants/db.service": "/usr/lib/systemd/system/db.service"},
+		noLinks: []string{
+			"/etc/systemd/system/multi-user.target.wants/app.service",
+			"/etc/systemd/system/web.service",
+			"/etc/systemd/system/default.target.wants/app.service",
+		},
+	}, {
+		name:      "disable runtime keeps persistent links",
+		files:     map[string]string{appFile: appInstall},
+		links:     map[string]string{"/etc/systemd/system/multi-user.target.wants/app.service": appFile, "/run/systemd/system/multi-user.target.wants/app.service": appFile},
+		op:        disable("app.service", true),
+		state:     "enabled",
+		preset:    "enabled",
+		wantLinks: map[string]string{"/etc/systemd/system/multi-user.target.wants/app.service": appFile},
+		noLinks:   []string{"/run/systemd/system/multi-user.target.wants/app.service"},
+	}, {
+		name:    "disable removed unit",
+		links:   map[string]string{"/etc/systemd/system/multi-user.target.wants/app.service": appFile},
+		op:      disable("app.service", false),
+		noLinks: []string{"/etc/systemd/system/multi-user.target.wants/app.service"},
+	}, {
+		name:      "mask",
+		files:     map[string]string{appFile: appInstall},
+		op:        mask("app.service", false),
+		state:     "masked",
+		wantLinks: map[string]string{"/etc/systemd/system/app.service": "/dev/null"},
+	}, {
+		name:      "mask runtime",
+		files:     map[string]string{appFile: appInstall},
+		op:        mask("app.service", true),
+		state:     "masked-runtime",
+		wantLinks: map[string]string{"/run/systemd/system/app.service": "/dev/null"},
+	}, {
+		name:    "mask refuses to replace a unit file",
+		files:   map[string]string{"/etc/systemd/system/app.service": appInstall},
+		op:      mask("app.service", false),
+		wantErr: true,
+	}, {
+		name:    "unmask",
+		files:   map[string]string{appFile: appInstall},
+		links:   map[string]string{"/etc/systemd/system/app.service": "/dev/null"},
+		op:      unmask("app.service", false),
+		state:   "disabled",
+		preset:  "enabled",
+		noLinks: []string{"/etc/systemd/system/app.service"},
+	}, {
+		name:      "unmask runtime keeps persistent mask",
+		files:     map[string]string{appFile: appInstall},
+		links:     map[string]string{"/etc/systemd/system/app.service": "/dev/null", "/run/systemd/system/app.service": "/dev/null"},
+		op:        unmask("app.service", true),
+		state:     "masked",
+		wantLinks: map[string]string{"/etc/systemd/system/app.service": "/dev/null"},
+		noLinks:   []string{"/run/systemd/system/app.service"},
+	}, {
+		name: "preset disable",
+		files: map[string]string{
+			appFile: appInstall,
+			"/usr/lib/systemd/system-preset/90-default.preset": "# comment\nenable db.service\ndisable *\n",
+		},
+		state:  "disabled",
+		preset: "disabled",
+	}, {
+		name: "preset override in /etc",
+		files: map[string]string{
+			appFile: appInstall,
+			"/usr/lib/systemd/system-preset/90-default.preset": "disable *\n",
+			"/etc/systemd/system-preset/90-default.preset":     "enable app.service\n",
+		},
+		state:  "disabled",
+		preset: "enabled",
+	}, {
+		name: "preset files in lexical order",
+		files: map[string]string{
+			appFile: appInstall,
+			"/usr/lib/systemd/system-preset/10-app.preset": "disable app*\n",
+			"/etc/systemd/system-preset/50-all.preset":     "enable *\n",
+		},
+		state:  "disabled",
+		preset: "disabled",
+	}, {
+		name:      "relative symlink out of root",
+		files:     map[string]string{appFile: appInstall},
+		links:     map[string]string{"/etc/systemd/system": "../../../outside"},
+		op:        enable("app.service", false),
+		state:     "enabled",
+		preset:    "enabled",
+		wantLinks: map[string]string{"/outside/multi-user.target.wants/app.service": appFile},
+	}, {
+		name:      "absolute symlink out of root",
+		files:     map[string]string{appFile: appInstall},
+		links:     map[string]string{"/etc/systemd": "/outside"},
+		op:        enable("app.service", false),
+		state:     "enabled",
+		preset:    "enabled",
+		wantLinks: map[string]string{"/outside/system/multi-user.target.wants/app.service": appFile},
+	}, {
+		name: "symlinked unit file out of root",
+		links: map[string]string{
+			appFile:                              "/outside/app.service",
+			"/usr/lib/systemd/system/db.service": "../../../../../outside/app.service",
+		},
+		files:  map[string]string{"/outside/app.service": appInstall},
+		op:     enable("db.service", false),
+		state:  "disabled",
+		preset: "enabled",
+		wantLinks: map[string]string{
+			"/etc/systemd/system/multi-user.target.wants/db.service": "/usr/lib/systemd/system/db.service",
+		},
+	}, {
+		name:    "symlink loop",
+		files:   map[string]string{appFile: appInstall},
+		links:   map[string]string{"/etc/systemd/system": "/etc/systemd/loop", "/etc/systemd/loop": "/etc/systemd/system"},
+		op:      enable("app.service", false),
+		wantErr: true,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			dir := t.TempDir()
+			root := filepath.Join(dir, "root")
+			// outside must stay empty, whatever the links in root point to.
+			outside := filepath.Join(dir, "outside")
+			for _, d := range []string{root, outside} {
+				if err := os.Mkdir(d, 0o755); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for p, data := range tc.files {
+				writeRootfsFile(t, root, p, data)
+			}
+			for link, target := range tc.links {
+				p := filepath.Join(root, link)
+				if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Symlink(target, p); err != nil {
+					t.Fatal(err)
+				}
+			}
+			f := Rootfs{Root: root}
+
+			if tc.op != nil {
+				err := tc.op(ctx, f)
+				if (err != nil) != tc.wantErr {
+					t.Fatalf("got error %v, want error %v", err, tc.wantErr)
+				}
+			}
+			if entries, err := os.ReadDir(outside); err != nil || len(entries) > 0 {
+				t.Errorf("wrote outside of root: %v %v", entries, err)
+			}
+			if tc.wantErr {
+				return
+			}
+
+			for link, target := range tc.wantLinks {
+				if got, err := os.Readlink(filepath.Join(root, link)); err != nil || got != target {
+					t.Errorf("link %s points to %q (%v), want %q", link, got, err, target)
+				}
+			}
+			for _, link := range tc.noLinks {
+				if _, err := os.Lstat(filepath.Join(root, link)); !os.IsNotExist(err) {
+					t.Errorf("link %s still exists", link)
+				}
+			}
+
+			if tc.state == "" {
+				return
+			}
+			unit := tc.unit
+			if unit == "" {
+				unit = "app.service"
+			}
+			state, preset, err := f.UnitFileState(ctx, unit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if state != tc.state || preset != tc.preset {
+				t.Errorf("got state %q preset %q, want %q %q", state, preset, tc.state, tc.preset)
+			}
+		})
+	}
+}
+
+func writeRootfsFile(t *testing.T, root, p, data string) {
+	t.Helper()
+	p = filepath.Join(root, p)
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseInstall(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		data string
+		want installInfo
+	}{{
+		name: "empty",
+	}, {
+		name: "no install section",
+		data: "[Unit]\nDescription=app\nWantedBy=ignored.target\n[Service]\nExecStart=/bin/app\n",
+	}, {
+		name: "all keys",
+		data: "[Unit]\nDescription=app\n\n[Install]\nWantedBy=multi-user.target graphical.target\nRequiredBy = network.target\nAlias=web.service\nAlso=app.socket\nDefaultInstance=tty1\n",
+		want: installInfo{
+			wantedBy:        []string{"multi-user.target", "graphical.target"},
+			requiredBy:      []string{"network.target"},
+			alias:           []string{"web.service"},
+			also:            []string{"app.socket"},
+			defaultInstance: "tty1",
+		},
+	}, {
+		name: "repeated keys accumulate",
+		data: "[Install]\nWantedBy=a.target\nWantedBy=b.target\n",
+		want: installInfo{wantedBy: []string{"a.target", "b.target"}},
+	}, {
+		name: "empty assignment resets",
+		data: "[Install]\nWantedBy=a.target\nWantedBy=\nWantedBy=b.target\nAlias=x.service\nAlias=\n",
+		want: installInfo{wantedBy: []string{"b.target"}},
+	}, {
+		name: "comments and continuation lines",
+		data: "[Install]\n# WantedBy=commented.target\n; Alias=commented.service\nWantedBy=a.target \\\n  b.target\n",
+		want: installInfo{wantedBy: []string{"a.target", "b.target"}},
+	}, {
+		name: "later sections end install",
+		data: "[Install]\nWantedBy=a.target\n[X-Custom]\nWantedBy=b.target\n",
+		want: installInfo{wantedBy: []string{"a.target"}},
+	}, {
+		name: "lines without assignment",
+		data: "[Install]\nWantedBy\nAlias=x.service\n",
+		want: installInfo{alias: []string{"x.service"}},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := parseInstall(tc.data)
+			if !reflect.DeepEqual(*got, tc.want) {
+				t.Errorf("got %+v, want %+v", *got, tc.want)
+			}
+			if got.empty() != (len(tc.want.wantedBy)+len(tc.want.requiredBy)+len(tc.want.alias)+len(tc.want.also) == 0) {
+				t.Errorf("empty() = %v", got.empty())
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package systemd
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+const (
+	defaultResyncPeriod            = 5 * time.Minute
+	defaultMaxConcurrentReconciles = 1
+
+	// MaxConcurrentReconciles is the number of workers the controller is set up
+	// with. The configured limit is enforced by the reconciler itself, so it can
+	// change while the plugin runs.
+	MaxConcurrentReconciles = 16
+)
+
+// Settings are the tunables of the reconciler which can change while the
+// plugin runs.
+type Settings struct {
+	// ResyncPeriod is how often converged objects are reconciled again to
+	// detect and correct changes made on the device outside of the agent.
+	ResyncPeriod time.Duration
+	// ProtectedUnits are system units the agent refuses to manage.
+	ProtectedUnits sets.String
+	// MaxConcurrentReconciles is the number of objects reconciled in parallel.
+	MaxConcurrentReconciles int
+}
+
+func (s Settings) withDefaults() Settings {
+	if s.ResyncPeriod <= 0 {
+		s.ResyncPeriod = defaultResyncPeriod
+	}
+	if s.ProtectedUnits == nil {
+		s.ProtectedUnits = sets.NewString()
+	}
+	if s.MaxConcurrentReconciles <= 0 {
+		s.MaxConcurrentReconciles = defaultMaxConcurrentReconciles
+	}
+	return s
+}
+
+// SetSettings replaces the settings of the reconciler. Reconciles already in
+// flight keep the settings they started with.
+func (r *Reconciler) SetSettings(settings Settings) {
+	settings = settings.withDefaults()
+
+	r.settingsLock.Lock()
+	r.currentSettings = settings
+	r.settingsLock.Unlock()
+
+	r.limit.setLimit(settings.MaxConcurrentReconciles)
+}
+
+// ProtectedUnits returns the system units the current settings protect.
+func (r *Reconciler) ProtectedUnits() sets.String {
+	return r.settings().ProtectedUnits
+}
+
+func (r *Reconciler) settings() Settings {
+	r.settingsLock.RLock()
+	defer r.settingsLock.RUnlock()
+	return r.currentSettings.withDefaults()
+}
+
+// limiter bounds the number of concurrent reconciles below the number of
+// controller workers. Its zero value admits a single reconcile at a time.
+type limiter struct {
+	lock   sync.Mutex
+	cond   *sync.Cond
+	limit  int
+	active int
+}
+
+func (l *limiter) acquire() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.cond == nil {
+		l.cond = sync.NewCond(&l.lock)
+	}
+	for l.active >= l.max() {
+		l.cond.Wait()
+	}
+	l.active++
+}
+
+func (l *limiter) release() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.active--
+	if l.cond != nil {
+		l.cond.Broadcast()
+	}
+}
+
+func (l *limiter) setLimit(limit int) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.limit = limit
+	if l.cond != nil {
+		l.cond.Broadcast()
+	}
+}
+
+func (l *limiter) max() int {
+	if l.limit <= 0 {
+		return defaultMaxConcurrentReconciles
+	}
+	return l.limit
+}
+
+// objectLocks serializes the reconciles of each object, across the controller
+// workers and EnforceCache.
+type objectLocks struct {
+	lock  sync.Mutex
+	locks map[objectKey]*objectLock
+}
+
+type objectKey struct {
+	cluster, namespace, name string
+}
+
+type objectLock struct {
+	sync.Mutex
+	// waiters is the number of holders and waiters, the lock is dropped once
+	// there are none.
+	waiters int
+}
+
+// acquire locks the object and returns the func unlocking it.
+func (o *objectLocks) acquire(cluster, namespace, name string) func() {
+	key := objectKey{cluster: cluster, namespace: namespace, name: name}
+
+	o.lock.Lock()
+	if o.locks == nil {
+		o.locks = map[objectKey]*objectLock{}
+	}
+	l, ok := o.locks[key]
+	if !ok {
+		l = &objectLock{}
+		o.locks[key] = l
+	}
+	l.waiters++
+	o.lock.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+		o.lock.Lock()
+		defer o.lock.Unlock()
+		l.waiters--
+		if l.waiters == 0 {
+			delete(o.locks, key)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package systemd
+
+import (
+	"context"
+	"time"
+)
+
+// detachedContext carries the values of its parent without its cancellation.
+// Reconciles run on it so that stopping the manager does not interrupt a
+// systemd job or the status patch recording its outcome half-way.
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+
+// begin registers an in-flight reconcile and returns the context it runs on.
+// The returned func must be called once the reconcile is done.
+func (r *Reconciler) begin(ctx context.Context) (context.Context, func()) {
+	r.inflight.Add(1)
+	return detachedContext{ctx}, r.inflight.Done
+}
+
+// Wait blocks until all in-flight reconciles have finished or ctx is done.
+func (r *Reconciler) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		r.inflight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Abort stops waiting for pending systemd jobs. In-flight reconciles record
+// the unfinished jobs as errors and still patch their status.
+func (r *Reconciler) Abort() {
+	ch := r.aborted()
+	r.abortClose.Do(func() {
+		close(ch)
+	})
+}
+
+func (r *Reconciler) aborted() chan struct{} {
+	r.abortInit.Do(func() {
+		r.abort = make(chan struct{})
+	})
+	return r.abort
+}
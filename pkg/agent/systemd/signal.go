@@ -0,0 +1,146 @@
+package systemd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/coreos/go-systemd/v22/dbus"
+	"github.com/kcp-dev/logicalcluster/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	servicesv1alpha1 "github.com/faroshq/plugin-services/pkg/apis/services/v1alpha1"
+	"github.com/faroshq/plugin-services/pkg/util/proc"
+)
+
+// handleSignal sends the signal requested for a unit, unless the request was
+// handled before according to the previous status or the agent's own record,
+// which covers status updates that failed. A new request is only sent when
+// send is set, and once it was persisted as handled, so it is not sent again
+// when the agent restarts before its status is reported. It returns the status
+// of the last handled request, and fails when the request could not be
+// persisted.
+func (r *Reconciler) handleSignal(ctx context.Context, conn *dbus.Conn, systemd *servicesv1alpha1.Systemd, unit *servicesv1alpha1.Unit, prev *servicesv1alpha1.UnitStatus, send bool) (*servicesv1alpha1.SignalStatus, error) {
+	key := newUnitKey(ctx, systemd, unit.User, unit.Name)
+
+	var reported *servicesv1alpha1.SignalStatus
+	if prev != nil {
+		reported = prev.Signal
+	}
+	recorded := latestSignal(r.sentSignal(key), r.cachedSignal(ctx, systemd, unit))
+
+	request := unit.Signal
+	if request == nil || !send {
+		return latestSignal(reported, recorded), nil
+	}
+	for _, handled := range []*servicesv1alpha1.SignalStatus{reported, recorded} {
+		if handled != nil && handled.RequestID == request.RequestID {
+			return handled, nil
+		}
+	}
+
+	target := request.Target
+	if target == "" {
+		target = servicesv1alpha1.SignalTargetMain
+	}
+	now := metav1.Now()
+	result := &servicesv1alpha1.SignalStatus{
+		RequestID: request.RequestID,
+		Signal:    request.Signal,
+		Target:    target,
+		Result:    servicesv1alpha1.SignalResultSent,
+		Time:      &now,
+	}
+	if err := r.persistSignal(ctx, systemd, unit, result); err != nil {
+		return latestSignal(reported, recorded), fmt.Errorf("failed to record signal request %s before sending it: %w", request.RequestID, err)
+	}
+	r.recordSignal(key, result)
+
+	signal, _ := proc.ParseSignal(request.Signal.String())
+	err := conn.KillUnitWithTarget(ctx, unit.Name, dbus.Who(target), int32(signal))
+	if err := observeOperation("kill", err); err != nil {
+		result.Result = servicesv1alpha1.SignalResultFailed
+		result.Message = err.Error()
+		r.eventf(systemd, corev1.EventTypeWarning, ReasonFailed, "Failed to send %s to %s processes of unit %s: %v", request.Signal, target, unit.Name, err)
+		r.recordSignal(key, result)
+		// The request is handled either way, the status reports the failure.
+		if err := r.persistSignal(ctx, systemd, unit, result); err != nil {
+			log.FromContext(ctx).Error(err, "failed to record failed signal request", "unit", unit.Name)
+		}
+	} else {
+		r.eventf(systemd, corev1.EventTypeNormal, ReasonSignalSent, "Sent %s to %s processes of unit %s", request.Signal, target, unit.Name)
+	}
+	return result, nil
+}
+
+// persistSignal records the outcome of a signal request in the state cache,
+// or without one in the status of the object. Only the agent's own record
+// is kept when neither is available, e.g. in standalone mode.
+func (r *Reconciler) persistSignal(ctx context.Context, systemd *servicesv1alpha1.Systemd, unit *servicesv1alpha1.Unit, result *servicesv1alpha1.SignalStatus) error {
+	switch {
+	case r.Cache != nil:
+		cluster, _ := logicalcluster.ClusterFromContext(ctx)
+		return r.Cache.RecordSignal(cluster.String(), systemd, unit.User, unit.Name, result)
+	case r.Client != nil:
+		var current servicesv1alpha1.Systemd
+		if err := r.Get(ctx, client.ObjectKeyFromObject(systemd), &current); err != nil {
+			return err
+		}
+		patch := client.MergeFrom(current.DeepCopy())
+		status := findUnitStatus(current.Status.Units, unit.User, unit.Name)
+		if status == nil {
+			current.Status.Units = append(current.Status.Units, servicesv1alpha1.UnitStatus{Name: unit.Name, User: unit.User})
+			status = &current.Status.Units[len(current.Status.Units)-1]
+		}
+		status.Signal = result.DeepCopy()
+		return r.Status().Patch(ctx, &current, patch)
+	}
+	return nil
+}
+
+// cachedSignal returns the outcome of the last signal request of a unit
+// recorded in the state cache.
+func (r *Reconciler) cachedSignal(ctx context.Context, systemd *servicesv1alpha1.Systemd, unit *servicesv1alpha1.Unit) *servicesv1alpha1.SignalStatus {
+	if r.Cache == nil {
+		return nil
+	}
+	cluster, _ := logicalcluster.ClusterFromContext(ctx)
+	cached, ok := r.Cache.Get(cluster.String(), systemd.Namespace, systemd.Name)
+	if !ok {
+		return nil
+	}
+	if status := findUnitStatus(cached.Object.Status.Units, unit.User, unit.Name); status != nil {
+		return status.Signal
+	}
+	return nil
+}
+
+func (r *Reconciler) sentSignal(key unitKey) *servicesv1alpha1.SignalStatus {
+	r.signalsLock.Lock()
+	defer r.signalsLock.Unlock()
+	return r.signalsSent[key].DeepCopy()
+}
+
+func (r *Reconciler) recordSignal(key unitKey, result *servicesv1alpha1.SignalStatus) {
+	r.signalsLock.Lock()
+	defer r.signalsLock.Unlock()
+	if r.signalsSent == nil {
+		r.signalsSent = map[unitKey]*servicesv1alpha1.SignalStatus{}
+	}
+	r.signalsSent[key] = result.DeepCopy()
+}
+
+// latestSignal returns the more recently handled of two signal requests.
+func latestSignal(a, b *servicesv1alpha1.SignalStatus) *servicesv1alpha1.SignalStatus {
+	switch {
+	case a == nil:
+		return b
+	case b == nil || b.Time == nil:
+		return a
+	case a.Time == nil || a.Time.Before(b.Time):
+		return b
+	}
+	return a
+}
@@ -0,0 +1,198 @@
+package systemd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	servicesv1alpha1 "github.com/faroshq/plugin-services/pkg/apis/services/v1alpha1"
+)
+
+func TestHandleSignal(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	// handled returns the outcome of request id handled minutes after start.
+	handled := func(id string, minutes int) *servicesv1alpha1.SignalStatus {
+		handledTime := metav1.NewTime(start.Add(time.Duration(minutes) * time.Minute))
+		return &servicesv1alpha1.SignalStatus{
+			RequestID: id,
+			Signal:    "SIGHUP",
+			Target:    servicesv1alpha1.SignalTargetMain,
+			Result:    servicesv1alpha1.SignalResultSent,
+			Time:      &handledTime,
+		}
+	}
+	request := func(id string) *servicesv1alpha1.UnitSignal {
+		return &servicesv1alpha1.UnitSignal{RequestID: id, Signal: "SIGHUP"}
+	}
+
+	for _, tc := range []struct {
+		name    string
+		request *servicesv1alpha1.UnitSignal
+		send    bool
+		// reported is in the previous status, sent in the agent's own record
+		// and cached in the state cache.
+		reported, sent, cached *servicesv1alpha1.SignalStatus
+		// failPersist makes recording a new request in the state cache fail.
+		failPersist bool
+		// want is the request id of the returned status, none when empty.
+		want    string
+		wantErr bool
+	}{{
+		name: "nothing requested or handled",
+		send: true,
+	}, {
+		name:     "no request reports the latest handled",
+		send:     true,
+		reported: handled("a", 1),
+		cached:   handled("b", 2),
+		want:     "b",
+	}, {
+		name:     "latest handled without time",
+		send:     true,
+		reported: handled("a", 1),
+		sent:     &servicesv1alpha1.SignalStatus{RequestID: "b", Result: servicesv1alpha1.SignalResultSent},
+		want:     "a",
+	}, {
+		name:     "handled according to the status",
+		request:  request("a"),
+		send:     true,
+		reported: handled("a", 1),
+		want:     "a",
+	}, {
+		name:    "handled but the status update failed",
+		request: request("a"),
+		send:    true,
+		sent:    handled("a", 1),
+		want:    "a",
+	}, {
+		name:     "handled before the agent restarted",
+		request:  request("b"),
+		send:     true,
+		reported: handled("a", 1),
+		cached:   handled("b", 2),
+		want:     "b",
+	}, {
+		name:     "handled long ago",
+		request:  request("a"),
+		send:     true,
+		reported: handled("a", 1),
+		cached:   handled("b", 2),
+		want:     "a",
+	}, {
+		name:     "new request waits",
+		request:  request("c"),
+		send:     false,
+		reported: handled("a", 1),
+		sent:     handled("b", 2),
+		want:     "b",
+	}, {
+		name:        "new request not persisted",
+		request:     request("c"),
+		send:        true,
+		reported:    handled("a", 1),
+		cached:      handled("b", 2),
+		failPersist: true,
+		want:        "b",
+		wantErr:     true,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := logicalcluster.WithCluster(context.Background(), logicalcluster.New("root"))
+			systemd := cachedSystemd("default", "web", 1)
+			unit := systemd.Spec.Units[0].DeepCopy()
+			unit.Signal = tc.request
+			key := newUnitKey(ctx, systemd, unit.User, unit.Name)
+
+			dir := t.TempDir()
+			cache, err := NewCache(filepath.Join(dir, "state", "state.json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.cached != nil {
+				if err := cache.RecordSignal("root", systemd, unit.User, unit.Name, tc.cached); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tc.failPersist {
+				// The directory of the state file can not be created.
+				if err := os.RemoveAll(filepath.Join(dir, "state")); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(filepath.Join(dir, "state"), nil, 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			r := &Reconciler{Cache: cache}
+			if tc.sent != nil {
+				r.recordSignal(key, tc.sent)
+			}
+			var prev *servicesv1alpha1.UnitStatus
+			if tc.reported != nil {
+				prev = &servicesv1alpha1.UnitStatus{Name: unit.Name, Signal: tc.reported}
+			}
+
+			// Without a systemd connection sending a signal panics, no case
+			// expects one to be sent.
+			defer func() {
+				if p := recover(); p != nil {
+					t.Fatalf("signal was sent: %v", p)
+				}
+			}()
+			got, err := r.handleSignal(ctx, nil, systemd, unit, prev, tc.send)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("got error %v, want error %v", err, tc.wantErr)
+			}
+			var gotID string
+			if got != nil {
+				gotID = got.RequestID
+			}
+			if gotID != tc.want {
+				t.Errorf("got request %q, want %q", gotID, tc.want)
+			}
+			// A request which was not persisted is not recorded as handled
+			// either, it is tried again on the next reconcile.
+			if tc.request != nil {
+				if sent := r.sentSignal(key); sent != nil && sent.RequestID == tc.request.RequestID && tc.want != tc.request.RequestID {
+					t.Errorf("request %s recorded as handled", tc.request.RequestID)
+				}
+			}
+		})
+	}
+}
+
+func TestLatestSignal(t *testing.T) {
+	at := func(id string, minutes int) *servicesv1alpha1.SignalStatus {
+		handledTime := metav1.NewTime(time.Date(2026, 1, 1, 0, minutes, 0, 0, time.UTC))
+		return &servicesv1alpha1.SignalStatus{RequestID: id, Time: &handledTime}
+	}
+	untimed := &servicesv1alpha1.SignalStatus{RequestID: "untimed"}
+
+	for _, tc := range []struct {
+		name string
+		a, b *servicesv1alpha1.SignalStatus
+		want string
+	}{
+		{"none", nil, nil, ""},
+		{"only a", at("a", 1), nil, "a"},
+		{"only b", nil, at("b", 1), "b"},
+		{"a later", at("a", 2), at("b", 1), "a"},
+		{"b later", at("a", 1), at("b", 2), "b"},
+		{"same time", at("a", 1), at("b", 1), "a"},
+		{"a without time", untimed, at("b", 1), "b"},
+		{"b without time", at("a", 1), untimed, "a"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var got string
+			if latest := latestSignal(tc.a, tc.b); latest != nil {
+				got = latest.RequestID
+			}
+			if got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
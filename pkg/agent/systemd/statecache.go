@@ -0,0 +1,192 @@
+package systemd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	conditionsv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/apis/conditions/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/util/conditions"
+	"github.com/kcp-dev/logicalcluster/v2"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	servicesv1alpha1 "github.com/faroshq/plugin-services/pkg/apis/services/v1alpha1"
+)
+
+// replayPeriod is how often status the hub has not seen yet is reported again.
+const replayPeriod = 30 * time.Second
+
+// EnforceCache applies the cached desired state of every object once, so
+// units are managed right after the agent starts, before the hub can be
+// reached. The status computed on the way is cached until it can be reported.
+// Objects are applied within the limit of concurrent reconciles and never
+// concurrently with a reconcile of the same object. It does nothing without a
+// Cache.
+func (r *Reconciler) EnforceCache(ctx context.Context) {
+	if r.Cache == nil {
+		return
+	}
+	logger := log.FromContext(ctx).WithName("state-cache")
+
+	for _, cached := range r.Cache.Objects() {
+		if !cached.Object.DeletionTimestamp.IsZero() {
+			continue
+		}
+		r.enforceCached(ctx, logger, cached)
+	}
+}
+
+func (r *Reconciler) enforceCached(ctx context.Context, logger logr.Logger, cached CachedObject) {
+	r.limit.acquire()
+	defer r.limit.release()
+	defer r.objects.acquire(cached.Cluster, cached.Object.Namespace, cached.Object.Name)()
+
+	// A reconcile which ran meanwhile saw the object on the hub, or its
+	// deletion, and already applied it.
+	if current, ok := r.Cache.Get(cached.Cluster, cached.Object.Namespace, cached.Object.Name); !ok || current.Revision != cached.Revision {
+		return
+	}
+	systemd := cached.Object
+	logger = logger.WithValues("clusterName", cached.Cluster, "namespace", systemd.Namespace, "name", systemd.Name)
+	ctx = logicalcluster.WithCluster(log.IntoContext(ctx, logger), logicalcluster.New(cached.Cluster))
+
+	if _, err := r.Apply(ctx, systemd); err != nil {
+		logger.Error(err, "failed to apply cached desired state")
+	}
+	markDisconnected(systemd, "AppliedFromCache", "Applied from the local state cache while the hub was unreachable")
+	// A reconcile may have seen the object on the hub in the meantime,
+	// its status is more recent.
+	if _, err := r.Cache.StoreIfUnchanged(cached.Cluster, systemd, true, cached.Revision); err != nil {
+		logger.Error(err, "failed to update state cache")
+	}
+}
+
+// CacheReplayer returns a runnable which reports status the hub has not seen
+// yet, e.g. computed by EnforceCache or while status updates failed.
+func (r *Reconciler) CacheReplayer() manager.Runnable {
+	return &cacheReplayer{r: r}
+}
+
+type cacheReplayer struct {
+	r *Reconciler
+}
+
+// Start implements manager.Runnable.
+func (c *cacheReplayer) Start(ctx context.Context) error {
+	if c.r.Cache == nil {
+		return nil
+	}
+	logger := log.FromContext(ctx).WithName("state-cache")
+
+	ticker := time.NewTicker(replayPeriod)
+	defer ticker.Stop()
+	for {
+		if err := c.r.replayPending(ctx, logger); err != nil {
+			logger.V(1).Info("unable to report cached status, will retry", "error", err.Error())
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable. Every agent
+// reports the status of its own device.
+func (c *cacheReplayer) NeedLeaderElection() bool {
+	return false
+}
+
+// replayPending reports the cached status of objects the hub has not seen
+// yet. Status of a spec which changed meanwhile is dropped, a reconcile of the
+// new spec reports a fresh one.
+func (r *Reconciler) replayPending(ctx context.Context, logger logr.Logger) error {
+	reader := r.APIReader
+	if reader == nil {
+		reader = r.Client
+	}
+
+	for _, cached := range r.Cache.Objects() {
+		if !cached.Pending {
+			continue
+		}
+		logger := logger.WithValues("clusterName", cached.Cluster, "namespace", cached.Object.Namespace, "name", cached.Object.Name)
+		ctx := logicalcluster.WithCluster(ctx, logicalcluster.New(cached.Cluster))
+
+		var current servicesv1alpha1.Systemd
+		if err := reader.Get(ctx, client.ObjectKeyFromObject(cached.Object), &current); err != nil {
+			if apierrors.IsNotFound(err) {
+				logger.Info("dropping cached object deleted on the hub")
+				if err := r.Cache.Delete(cached.Cluster, cached.Object.Namespace, cached.Object.Name); err != nil {
+					return err
+				}
+				continue
+			}
+			return err
+		}
+
+		if current.Generation == cached.Object.Generation {
+			// The optimistic lock keeps a status reported by a reconcile
+			// meanwhile from being overwritten with the older cached one.
+			patch := client.MergeFromWithOptions(current.DeepCopy(), client.MergeFromWithOptimisticLock{})
+			current.Status = cached.Object.Status
+			switch err := r.Status().Patch(ctx, &current, patch); {
+			case apierrors.IsConflict(err):
+				logger.V(1).Info("dropping cached status, a newer one was reported")
+			case err != nil:
+				return err
+			default:
+				logger.Info("reported cached status")
+			}
+		}
+		if _, err := r.Cache.StoreIfUnchanged(cached.Cluster, cached.Object, false, cached.Revision); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cacheStatus records the status of systemd in the cache. pending marks a
+// status which could not be reported, it is flagged with the Disconnected
+// condition and replayed later.
+func (r *Reconciler) cacheStatus(ctx context.Context, logger logr.Logger, systemd *servicesv1alpha1.Systemd, pending bool, reportErr error) {
+	if r.Cache == nil {
+		return
+	}
+	cluster, _ := logicalcluster.ClusterFromContext(ctx)
+
+	systemd = systemd.DeepCopy()
+	if pending {
+		markDisconnected(systemd, "StatusNotReported", fmt.Sprintf("Status could not be reported to the hub: %v", reportErr))
+	}
+	if err := r.Cache.Store(cluster.String(), systemd, pending); err != nil {
+		logger.Error(err, "failed to update state cache")
+	}
+}
+
+// uncache removes a deleted object from the cache.
+func (r *Reconciler) uncache(ctx context.Context, logger logr.Logger, namespace, name string) {
+	if r.Cache == nil {
+		return
+	}
+	cluster, _ := logicalcluster.ClusterFromContext(ctx)
+	if err := r.Cache.Delete(cluster.String(), namespace, name); err != nil {
+		logger.Error(err, "failed to update state cache")
+	}
+}
+
+// markDisconnected flags status recorded while the hub was unreachable.
+func markDisconnected(systemd *servicesv1alpha1.Systemd, reason, message string) {
+	conditions.Set(systemd, &conditionsv1alpha1.Condition{
+		Type:    servicesv1alpha1.DisconnectedCondition,
+		Status:  corev1.ConditionTrue,
+		Reason:  reason,
+		Message: message,
+	})
+}
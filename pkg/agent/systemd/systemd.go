@@ -2,33 +2,93 @@ package systemd
 
 import (
 	"context"
+	"sync"
+	"time"
 
 	conditionsv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/apis/conditions/v1alpha1"
 	"github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/util/conditions"
 	"github.com/kcp-dev/logicalcluster/v2"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	servicesv1alpha1 "github.com/faroshq/plugin-services/pkg/apis/services/v1alpha1"
+	"github.com/faroshq/plugin-services/pkg/util/proc"
 )
 
 // Reconciler reconciles a SystemD object
 type Reconciler struct {
 	client.Client
-	Scheme *runtime.Scheme
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+	// Connection is the dbus connection to systemd shared by all reconciles.
+	Connection *Connection
+	// UserConnections are the connections to user managers, used for units
+	// with a user set. User units fail when it is nil.
+	UserConnections *UserConnections
+	// Cache persists objects and their status on the device, it is optional.
+	Cache *Cache
+	// APIReader reads objects from the hub bypassing the informer cache, when
+	// cached status is replayed. Defaults to Client.
+	APIReader client.Reader
+	// Processes reads the processes of units for their status, they are not
+	// listed when it is nil.
+	Processes *proc.Reader
+
+	settingsLock    sync.RWMutex
+	currentSettings Settings
+	limit           limiter
+	objects         objectLocks
+
+	// signalsSent records the signal requests handled per unit, in case
+	// their status could not be reported.
+	signalsLock sync.Mutex
+	signalsSent map[unitKey]*servicesv1alpha1.SignalStatus
+
+	// restartSamples are the restart counters of services seen within their
+	// crash loop window.
+	crashLoopLock  sync.Mutex
+	restartSamples map[unitKey][]restartSample
+
+	// probers run the probes of active units.
+	proberLock sync.Mutex
+	probers    map[unitKey]*prober
+
+	// now is the clock the readiness of units is measured with, time.Now
+	// when nil.
+	now func() time.Time
+
+	inflight   sync.WaitGroup
+	abort      chan struct{}
+	abortInit  sync.Once
+	abortClose sync.Once
 }
 
 // +kubebuilder:rbac:groups=services.plugins.faros.sh,resources=systemd,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=services.plugins.faros.sh,resources=systemd/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=services.plugins.faros.sh,resources=systemd/finalizers,verbs=update
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 // Reconcile reconciles a SystemD object
 func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	ctx, done := r.begin(ctx)
+	defer done()
+
+	r.limit.acquire()
+	defer r.limit.release()
+	defer r.objects.acquire(req.ClusterName, req.Namespace, req.Name)()
+
 	logger := log.FromContext(ctx)
 
+	start := time.Now()
+	defer func() {
+		reconcileDuration.Observe(time.Since(start).Seconds())
+	}()
+
 	// Include the clusterName from req.ObjectKey in the logger, similar to the namespace and name keys that are already
 	// there.
 	logger = logger.WithValues("clusterName", req.ClusterName).WithValues("namespace", req.Namespace).WithValues("name", req.Name)
@@ -39,6 +99,9 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 	var systemd servicesv1alpha1.Systemd
 	if err := r.Get(ctx, req.NamespacedName, &systemd); err != nil {
 		if apierrors.IsNotFound(err) {
+			r.uncache(ctx, logger, req.Namespace, req.Name)
+			deleteObjectMetrics(req.ClusterName, req.Namespace, req.Name)
+			r.stopObjectProbers(req.ClusterName, req.Namespace, req.Name)
 			return ctrl.Result{}, nil
 		}
 		return ctrl.Result{}, err
@@ -48,6 +111,10 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 	var err error
 	if systemd.DeletionTimestamp.IsZero() {
 		result, err = r.createOrUpdate(ctx, logger, systemd.DeepCopy())
+	} else {
+		r.uncache(ctx, logger, systemd.Namespace, systemd.Name)
+		deleteObjectMetrics(req.ClusterName, systemd.Namespace, systemd.Name)
+		r.stopObjectProbers(req.ClusterName, systemd.Namespace, systemd.Name)
 	}
 	if err != nil {
 		systemdCopy := systemd.DeepCopy()
@@ -71,5 +138,6 @@ func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		// TODO: scope to a specific agent
 		For(&servicesv1alpha1.Systemd{}).
+		WithOptions(controller.Options{MaxConcurrentReconciles: MaxConcurrentReconciles}).
 		Complete(r)
 }
@@ -0,0 +1,68 @@
+package systemd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/coreos/go-systemd/v22/dbus"
+	godbus "github.com/godbus/dbus/v5"
+
+	servicesv1alpha1 "github.com/faroshq/plugin-services/pkg/apis/services/v1alpha1"
+)
+
+// NewDialer returns a Dialer for transport. For TransportPrivate address
+// optionally overrides the socket, e.g. "unix:path=/host/run/systemd/private"
+// when the host socket is mounted into a container. TransportAddress requires
+// an address.
+func NewDialer(transport servicesv1alpha1.Transport, address string) (Dialer, error) {
+	switch transport {
+	case "", servicesv1alpha1.TransportAuto:
+		return dbus.NewWithContext, nil
+	case servicesv1alpha1.TransportSystem:
+		return dbus.NewSystemConnectionContext, nil
+	case servicesv1alpha1.TransportPrivate:
+		if address == "" {
+			return dbus.NewSystemdConnectionContext, nil
+		}
+		// systemd does not implement Hello on its private socket.
+		return addressDialer(address, false), nil
+	case servicesv1alpha1.TransportUser:
+		return dbus.NewUserConnectionContext, nil
+	case servicesv1alpha1.TransportAddress:
+		if address == "" {
+			return nil, fmt.Errorf("transport %q requires a bus address", transport)
+		}
+		return addressDialer(address, true), nil
+	}
+	return nil, fmt.Errorf("unknown systemd transport %q", transport)
+}
+
+func addressDialer(address string, hello bool) Dialer {
+	return func(ctx context.Context) (*dbus.Conn, error) {
+		return dbus.NewConnection(func() (*godbus.Conn, error) {
+			conn, err := godbus.Dial(address, godbus.WithContext(ctx))
+			if err != nil {
+				return nil, err
+			}
+			return authenticate(conn, hello)
+		})
+	}
+}
+
+// authenticate authenticates a freshly dialed connection, closing it on failure.
+func authenticate(conn *godbus.Conn, hello bool) (*godbus.Conn, error) {
+	// Same as go-systemd: EXTERNAL with the numeric uid avoids a username lookup.
+	if err := conn.Auth([]godbus.Auth{godbus.AuthExternal(strconv.Itoa(os.Getuid()))}); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if hello {
+		if err := conn.Hello(); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
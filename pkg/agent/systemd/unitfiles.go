@@ -0,0 +1,153 @@
+package systemd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/coreos/go-systemd/v22/dbus"
+	corev1 "k8s.io/api/core/v1"
+
+	servicesv1alpha1 "github.com/faroshq/plugin-services/pkg/apis/services/v1alpha1"
+)
+
+// UnitFileManager changes which units are enabled or masked. It is implemented
+// on top of the dbus API of a running systemd, and by Rootfs on an unbooted
+// root filesystem.
+type UnitFileManager interface {
+	// UnitFileState returns the UnitFileState of a unit as reported by
+	// `systemctl is-enabled`, e.g. "enabled" or "masked-runtime", and its
+	// UnitFilePreset, "enabled" or "disabled".
+	UnitFileState(ctx context.Context, name string) (state, preset string, err error)
+	Enable(ctx context.Context, name string, runtime bool) error
+	Disable(ctx context.Context, name string, runtime bool) error
+	Mask(ctx context.Context, name string, runtime bool) error
+	Unmask(ctx context.Context, name string, runtime bool) error
+}
+
+// dbusUnitFiles is the UnitFileManager of the systemd connected to with conn.
+type dbusUnitFiles struct {
+	conn *dbus.Conn
+}
+
+var _ UnitFileManager = dbusUnitFiles{}
+
+func (d dbusUnitFiles) UnitFileState(ctx context.Context, name string) (string, string, error) {
+	props, err := d.conn.GetUnitPropertiesContext(ctx, name)
+	if err := observeOperation("get_properties", err); err != nil {
+		return "", "", err
+	}
+	state, _ := props["UnitFileState"].(string)
+	preset, _ := props["UnitFilePreset"].(string)
+	return state, preset, nil
+}
+
+func (d dbusUnitFiles) Enable(ctx context.Context, name string, runtime bool) error {
+	_, _, err := d.conn.EnableUnitFilesContext(ctx, []string{name}, runtime, false)
+	return err
+}
+
+func (d dbusUnitFiles) Disable(ctx context.Context, name string, runtime bool) error {
+	_, err := d.conn.DisableUnitFilesContext(ctx, []string{name}, runtime)
+	return err
+}
+
+func (d dbusUnitFiles) Mask(ctx context.Context, name string, runtime bool) error {
+	_, err := d.conn.MaskUnitFilesContext(ctx, []string{name}, runtime, false)
+	return err
+}
+
+func (d dbusUnitFiles) Unmask(ctx context.Context, name string, runtime bool) error {
+	_, err := d.conn.UnmaskUnitFilesContext(ctx, []string{name}, runtime)
+	return err
+}
+
+// unitState is the current state of a unit as far as planning is concerned.
+type unitState struct {
+	enabled bool
+	masked  bool
+	active  bool
+	// preset is the UnitFilePreset, empty when the unit can not be enabled.
+	preset string
+}
+
+func newUnitState(activeState, unitFileState, unitFilePreset string) unitState {
+	switch unitFileState {
+	case "enabled", "enabled-runtime", "disabled":
+	default:
+		// Static, generated or aliased units can not be enabled, the preset
+		// does not apply to them.
+		unitFilePreset = ""
+	}
+	return unitState{
+		enabled: isEnabled(unitFileState),
+		masked:  isMasked(unitFileState),
+		active:  isActive(activeState),
+		preset:  unitFilePreset,
+	}
+}
+
+// actions are the operations needed to move a unit to its desired state.
+type actions struct {
+	enable, disable, mask, unmask, start, stop bool
+}
+
+// unitFiles reports whether any unit file operation is needed.
+func (a actions) unitFiles() bool {
+	return a.enable || a.disable || a.mask || a.unmask
+}
+
+// any reports whether any operation is needed.
+func (a actions) any() bool {
+	return a.unitFiles() || a.start || a.stop
+}
+
+// applyUnitFiles applies the unit file operations in a to the named unit,
+// recording an event for each of them. The unit file state is read back after
+// every operation, and an operation which did not take effect is reported as
+// an error instead of being retried on every resync. Links under /etc outlive
+// a runtime disable or unmask, those are never made persistent behind the
+// back of the object.
+func (r *Reconciler) applyUnitFiles(ctx context.Context, systemd *servicesv1alpha1.Systemd, files UnitFileManager, name string, runtime bool, a actions) error {
+	ops := []struct {
+		needed    bool
+		operation string
+		reason    string
+		apply     func(context.Context, string, bool) error
+		// done reports whether the UnitFileState reflects the operation.
+		done func(state string) bool
+	}{
+		{a.unmask, "unmask", ReasonUnmasked, files.Unmask, func(state string) bool { return !isMasked(state) }},
+		{a.disable, "disable", ReasonDisabled, files.Disable, func(state string) bool { return !isEnabled(state) }},
+		{a.mask, "mask", ReasonMasked, files.Mask, isMasked},
+		{a.enable, "enable", ReasonEnabled, files.Enable, isEnabled},
+	}
+	for _, op := range ops {
+		if !op.needed {
+			continue
+		}
+		if err := observeOperation(op.operation, op.apply(ctx, name, runtime)); err != nil {
+			r.eventf(systemd, corev1.EventTypeWarning, ReasonFailed, "Failed to %s unit %s: %v", op.operation, name, err)
+			return err
+		}
+		state, _, err := files.UnitFileState(ctx, name)
+		if err != nil {
+			return err
+		}
+		if !op.done(state) {
+			err := fmt.Errorf("unit %s is %s after %s", name, state, op.operation)
+			if runtime {
+				err = fmt.Errorf("unit %s is %s after runtime %s, links under /etc take precedence, use enableMode persistent", name, state, op.operation)
+			}
+			r.eventf(systemd, corev1.EventTypeWarning, ReasonFailed, "Failed to %s unit %s: %v", op.operation, name, err)
+			return err
+		}
+		r.eventf(systemd, corev1.EventTypeNormal, op.reason, "%s unit %s", op.reason, name)
+	}
+	return nil
+}
+
+// isMasked reports whether the UnitFileState means the unit is masked.
+func isMasked(unitFileState string) bool {
+	return strings.HasPrefix(unitFileState, "masked")
+}
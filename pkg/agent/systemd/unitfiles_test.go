@@ -0,0 +1,96 @@
+package systemd
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestApplyUnitFiles(t *testing.T) {
+	const (
+		appFile    = "/usr/lib/systemd/system/app.service"
+		appInstall = "[Unit]\nDescription=app\n\n[Install]\nWantedBy=multi-user.target\n"
+	)
+
+	for _, tc := range []struct {
+		name string
+		// setup prepares the unit files before the operations are applied.
+		setup   func(context.Context, Rootfs) error
+		runtime bool
+		actions actions
+		// wantErr is a substring of the error, no error is expected when empty.
+		wantErr string
+		state   string
+	}{{
+		name:    "enable",
+		actions: actions{enable: true},
+		state:   "enabled",
+	}, {
+		name:    "enable runtime",
+		runtime: true,
+		actions: actions{enable: true},
+		state:   "enabled-runtime",
+	}, {
+		name:    "disable runtime enabled",
+		setup:   func(ctx context.Context, f Rootfs) error { return f.Enable(ctx, "app.service", true) },
+		runtime: true,
+		actions: actions{disable: true},
+		state:   "disabled",
+	}, {
+		name:    "disable persistently enabled",
+		setup:   func(ctx context.Context, f Rootfs) error { return f.Enable(ctx, "app.service", false) },
+		actions: actions{disable: true},
+		state:   "disabled",
+	}, {
+		name:    "runtime disable of a persistently enabled unit",
+		setup:   func(ctx context.Context, f Rootfs) error { return f.Enable(ctx, "app.service", false) },
+		runtime: true,
+		actions: actions{disable: true},
+		wantErr: "unit app.service is enabled after runtime disable, links under /etc take precedence",
+		state:   "enabled",
+	}, {
+		name:    "runtime unmask of a persistently masked unit",
+		setup:   func(ctx context.Context, f Rootfs) error { return f.Mask(ctx, "app.service", false) },
+		runtime: true,
+		actions: actions{unmask: true, enable: true},
+		wantErr: "unit app.service is masked after runtime unmask, links under /etc take precedence",
+		state:   "masked",
+	}, {
+		name:    "unmask and enable",
+		setup:   func(ctx context.Context, f Rootfs) error { return f.Mask(ctx, "app.service", true) },
+		runtime: true,
+		actions: actions{unmask: true, enable: true},
+		state:   "enabled-runtime",
+	}, {
+		name:    "disable and mask",
+		setup:   func(ctx context.Context, f Rootfs) error { return f.Enable(ctx, "app.service", false) },
+		actions: actions{disable: true, mask: true},
+		state:   "masked",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			root := filepath.Join(t.TempDir(), "root")
+			writeRootfsFile(t, root, appFile, appInstall)
+			f := Rootfs{Root: root}
+			if tc.setup != nil {
+				if err := tc.setup(ctx, f); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			r := &Reconciler{}
+			err := r.applyUnitFiles(ctx, nil, f, "app.service", tc.runtime, tc.actions)
+			switch {
+			case tc.wantErr == "" && err != nil:
+				t.Errorf("got error %v", err)
+			case tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)):
+				t.Errorf("got error %v, want %q", err, tc.wantErr)
+			}
+			// A failed runtime operation leaves the persistent state alone.
+			if state, _, err := f.UnitFileState(ctx, "app.service"); err != nil || state != tc.state {
+				t.Errorf("unit is %q (%v), want %q", state, err, tc.state)
+			}
+		})
+	}
+}
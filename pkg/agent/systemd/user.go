@@ -0,0 +1,167 @@
+package systemd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-systemd/v22/dbus"
+	godbus "github.com/godbus/dbus/v5"
+	corev1 "k8s.io/api/core/v1"
+
+	servicesv1alpha1 "github.com/faroshq/plugin-services/pkg/apis/services/v1alpha1"
+)
+
+// lingerDir holds a file per user with lingering enabled, it is maintained by logind.
+const lingerDir = "/var/lib/systemd/linger"
+
+var errUserUnitsUnsupported = errors.New("user units are not supported by this agent")
+
+// UserConnections hands out connections to the user managers (systemd --user)
+// of individual users, one Connection per user. The agent connects to the
+// private socket of the user manager, which systemd only opens to root and the
+// user itself.
+type UserConnections struct {
+	lock   sync.Mutex
+	conns  map[string]*Connection
+	closed bool
+}
+
+// NewUserConnections returns an empty set of user manager connections.
+func NewUserConnections() *UserConnections {
+	return &UserConnections{
+		conns: map[string]*Connection{},
+	}
+}
+
+// Get returns the connection to the user manager of username, connecting if
+// needed. Callers must not close the returned connection.
+func (u *UserConnections) Get(username string) (*dbus.Conn, error) {
+	c, err := u.connection(username)
+	if err != nil {
+		return nil, err
+	}
+	return c.Get()
+}
+
+// RetryIn returns how long until the next connection attempt to the user
+// manager of username is allowed.
+func (u *UserConnections) RetryIn(username string) time.Duration {
+	u.lock.Lock()
+	c, ok := u.conns[username]
+	u.lock.Unlock()
+
+	if !ok {
+		return minReconnectDelay
+	}
+	return c.RetryIn()
+}
+
+// Close closes all user manager connections. Get fails afterwards.
+func (u *UserConnections) Close() {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	u.closed = true
+	for _, c := range u.conns {
+		c.Close()
+	}
+	u.conns = nil
+}
+
+func (u *UserConnections) connection(username string) (*Connection, error) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	if u.closed {
+		return nil, errConnectionClosed
+	}
+	if c, ok := u.conns[username]; ok {
+		return c, nil
+	}
+
+	usr, err := user.Lookup(username)
+	if err != nil {
+		return nil, err
+	}
+	// systemd does not implement Hello on its private socket.
+	c := NewConnection(addressDialer(userManagerAddress(usr.Uid), false))
+	if u.conns == nil {
+		u.conns = map[string]*Connection{}
+	}
+	u.conns[username] = c
+	return c, nil
+}
+
+// userManagerAddress returns the address of the private socket of the user
+// manager running as uid.
+func userManagerAddress(uid string) string {
+	return "unix:path=" + filepath.Join("/run/user", uid, "systemd", "private")
+}
+
+// userConnection returns the connection to the user manager owning unit,
+// enabling lingering first if the unit asks for it.
+func (r *Reconciler) userConnection(ctx context.Context, systemd *servicesv1alpha1.Systemd, unit *servicesv1alpha1.Unit) (*dbus.Conn, error) {
+	if r.UserConnections == nil {
+		return nil, errUserUnitsUnsupported
+	}
+	if unit.Linger {
+		if err := r.ensureLinger(ctx, systemd, unit.User); err != nil {
+			return nil, err
+		}
+	}
+	conn, err := r.UserConnections.Get(unit.User)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the user manager of %s: %w", unit.User, err)
+	}
+	return conn, nil
+}
+
+// ensureLinger enables lingering for username through logind, so its user
+// manager is started at boot and survives logout. Lingering is never disabled
+// by the agent, as other software on the device may rely on it.
+func (r *Reconciler) ensureLinger(ctx context.Context, systemd *servicesv1alpha1.Systemd, username string) error {
+	if _, err := os.Stat(filepath.Join(lingerDir, username)); err == nil {
+		return nil
+	}
+
+	usr, err := user.Lookup(username)
+	if err != nil {
+		return err
+	}
+	uid, err := strconv.ParseUint(usr.Uid, 10, 32)
+	if err != nil {
+		return fmt.Errorf("invalid uid %q of user %s: %w", usr.Uid, username, err)
+	}
+
+	if err := observeOperation("set_linger", setUserLinger(ctx, uint32(uid))); err != nil {
+		r.eventf(systemd, corev1.EventTypeWarning, ReasonFailed, "Failed to enable lingering for user %s: %v", username, err)
+		return fmt.Errorf("failed to enable lingering for %s: %w", username, err)
+	}
+	r.eventf(systemd, corev1.EventTypeNormal, ReasonLingerEnabled, "Enabled lingering for user %s", username)
+	return nil
+}
+
+// setUserLinger calls SetUserLinger on logind. go-systemd does not wrap it, so
+// the call is made on a short-lived system bus connection.
+func setUserLinger(ctx context.Context, uid uint32) error {
+	conn, err := godbus.SystemBusPrivate(godbus.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	conn, err = authenticate(conn, true)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	logind := conn.Object("org.freedesktop.login1", "/org/freedesktop/login1")
+	// uid, enable, interactive
+	return logind.CallWithContext(ctx, "org.freedesktop.login1.Manager.SetUserLinger", 0, uid, true, false).Err
+}
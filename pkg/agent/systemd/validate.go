@@ -0,0 +1,291 @@
+package systemd
+
+import (
+	"fmt"
+	"regexp"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	servicesv1alpha1 "github.com/faroshq/plugin-services/pkg/apis/services/v1alpha1"
+	"github.com/faroshq/plugin-services/pkg/util/proc"
+)
+
+// unitNameRegexp mirrors the validation pattern on Unit.Name in the API types.
+var unitNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9:_.\\-]+(@[a-zA-Z0-9:_.\\-]*)?\.(service|socket|device|mount|automount|swap|target|path|timer|slice|scope)$`)
+
+// userNameRegexp mirrors the validation pattern on Unit.User in the API types.
+var userNameRegexp = regexp.MustCompile(`^[a-z_][a-z0-9_.-]*\$?$`)
+
+const (
+	maxUnitNameLength = 255
+	maxUserNameLength = 32
+)
+
+var (
+	validServiceStatuses = sets.NewString(
+		servicesv1alpha1.ServiceStatusEnabled.String(),
+		servicesv1alpha1.ServiceStatusDisabled.String(),
+		servicesv1alpha1.ServiceStatusStopped.String(),
+		servicesv1alpha1.ServiceStatusStarted.String(),
+		servicesv1alpha1.ServiceStatusEnabledAndStarted.String(),
+		servicesv1alpha1.ServiceStatusDisabledAndStopped.String(),
+		servicesv1alpha1.ServiceStatusMasked.String(),
+		servicesv1alpha1.ServiceStatusPreset.String(),
+	)
+	validActivationModes = sets.NewString(
+		servicesv1alpha1.ActivationModeReplace.String(),
+		servicesv1alpha1.ActivationModeFail.String(),
+		servicesv1alpha1.ActivationModeIsolate.String(),
+		servicesv1alpha1.ActivationModeIgnoreDependencies.String(),
+		servicesv1alpha1.ActivationModeIgnoreRequirements.String(),
+	)
+	validEnableModes = sets.NewString(
+		servicesv1alpha1.EnableModeRuntimeOnly.String(),
+		servicesv1alpha1.EnableModePersistent.String(),
+	)
+	validSignalTargets = sets.NewString(
+		servicesv1alpha1.SignalTargetMain.String(),
+		servicesv1alpha1.SignalTargetAll.String(),
+	)
+	validURISchemes = sets.NewString(
+		servicesv1alpha1.URISchemeHTTP.String(),
+		servicesv1alpha1.URISchemeHTTPS.String(),
+	)
+)
+
+// probeNameRegexp mirrors the validation pattern on UnitProbe.Name in the API types.
+var probeNameRegexp = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+const (
+	maxProbes          = 8
+	maxProbeNameLength = 63
+)
+
+const maxRequestIDLength = 63
+
+// validateSpec checks the spec before anything is sent to systemd. Admission
+// should already reject most of these, but objects created before the schema
+// was tightened are still served as they were stored.
+func validateSpec(spec servicesv1alpha1.SystemdSpec, protectedUnits sets.String) field.ErrorList {
+	var errs field.ErrorList
+	path := field.NewPath("spec", "services")
+	seen := sets.NewString()
+	for i, unit := range spec.Units {
+		idxPath := path.Index(i)
+		if key := unit.User + "/" + unit.Name; seen.Has(key) {
+			errs = append(errs, field.Duplicate(idxPath.Child("name"), unit.Name))
+		} else {
+			seen.Insert(key)
+		}
+		if unit.User == "" && protectedUnits.Has(unit.Name) {
+			errs = append(errs, field.Forbidden(idxPath.Child("name"), "unit is protected by the plugin configuration"))
+		}
+		errs = append(errs, validateUnit(idxPath, unit)...)
+	}
+	return errs
+}
+
+func validateUnit(path *field.Path, unit servicesv1alpha1.Unit) field.ErrorList {
+	var errs field.ErrorList
+
+	switch {
+	case unit.Name == "":
+		errs = append(errs, field.Required(path.Child("name"), ""))
+	case len(unit.Name) > maxUnitNameLength:
+		errs = append(errs, field.TooLong(path.Child("name"), unit.Name, maxUnitNameLength))
+	case !unitNameRegexp.MatchString(unit.Name):
+		errs = append(errs, field.Invalid(path.Child("name"), unit.Name, "must be a valid systemd unit name including the type suffix, e.g. nginx.service"))
+	}
+
+	if unit.DesiredStatus == "" {
+		errs = append(errs, field.Required(path.Child("desiredState"), ""))
+	} else if !validServiceStatuses.Has(unit.DesiredStatus.String()) {
+		errs = append(errs, field.NotSupported(path.Child("desiredState"), unit.DesiredStatus, validServiceStatuses.List()))
+	}
+
+	if unit.ActivationMode != "" && !validActivationModes.Has(unit.ActivationMode.String()) {
+		errs = append(errs, field.NotSupported(path.Child("activationMode"), unit.ActivationMode, validActivationModes.List()))
+	}
+
+	if unit.EnableMode != "" && !validEnableModes.Has(unit.EnableMode.String()) {
+		errs = append(errs, field.NotSupported(path.Child("enableMode"), unit.EnableMode, validEnableModes.List()))
+	}
+
+	switch {
+	case unit.User == "":
+		if unit.Linger {
+			errs = append(errs, field.Invalid(path.Child("linger"), unit.Linger, "requires user to be set"))
+		}
+	case len(unit.User) > maxUserNameLength:
+		errs = append(errs, field.TooLong(path.Child("user"), unit.User, maxUserNameLength))
+	case !userNameRegexp.MatchString(unit.User):
+		errs = append(errs, field.Invalid(path.Child("user"), unit.User, "must be a valid user name"))
+	}
+
+	if unit.Signal != nil {
+		errs = append(errs, validateSignal(path.Child("signal"), *unit.Signal)...)
+	}
+
+	if len(unit.Probes) > maxProbes {
+		errs = append(errs, field.TooMany(path.Child("probes"), len(unit.Probes), maxProbes))
+	}
+	probeNames := sets.NewString()
+	for i, probe := range unit.Probes {
+		idxPath := path.Child("probes").Index(i)
+		if probeNames.Has(probe.Name) {
+			errs = append(errs, field.Duplicate(idxPath.Child("name"), probe.Name))
+		}
+		probeNames.Insert(probe.Name)
+		errs = append(errs, validateProbe(idxPath, probe)...)
+	}
+
+	if unit.Readiness != nil {
+		errs = append(errs, validateReadiness(path.Child("readiness"), unit)...)
+	}
+
+	if unit.CrashLoop != nil {
+		errs = append(errs, validateCrashLoop(path.Child("crashLoop"), *unit.CrashLoop)...)
+	}
+
+	if unit.Recovery != nil {
+		errs = append(errs, validateRecovery(path.Child("recovery"), unit)...)
+	}
+
+	return errs
+}
+
+func validateReadiness(path *field.Path, unit servicesv1alpha1.Unit) field.ErrorList {
+	var errs field.ErrorList
+
+	switch unit.DesiredStatus {
+	case servicesv1alpha1.ServiceStatusStarted, servicesv1alpha1.ServiceStatusEnabledAndStarted:
+	default:
+		errs = append(errs, field.Invalid(path, unit.DesiredStatus, "requires desiredState started or enabled-and-started"))
+	}
+
+	if period := unit.Readiness.StablePeriod; period != nil && (period.Duration <= 0 || period.Duration > maxStablePeriod) {
+		errs = append(errs, field.Invalid(path.Child("stablePeriod"), period.Duration.String(), "must be positive and at most "+maxStablePeriod.String()))
+	}
+
+	return errs
+}
+
+func validateRecovery(path *field.Path, unit servicesv1alpha1.Unit) field.ErrorList {
+	var errs field.ErrorList
+	policy := *unit.Recovery
+
+	switch unit.DesiredStatus {
+	case servicesv1alpha1.ServiceStatusStarted, servicesv1alpha1.ServiceStatusEnabledAndStarted:
+	default:
+		errs = append(errs, field.Invalid(path, unit.DesiredStatus, "requires desiredState started or enabled-and-started"))
+	}
+
+	if policy.MaxAttempts < 0 || policy.MaxAttempts > maxRecoveryAttempts {
+		errs = append(errs, field.Invalid(path.Child("maxAttempts"), policy.MaxAttempts, fmt.Sprintf("must be between 1 and %d", maxRecoveryAttempts)))
+	}
+	initial, limit := recoveryBackoffs(policy)
+	if initial < minInitialBackoff {
+		errs = append(errs, field.Invalid(path.Child("initialBackoff"), initial.String(), "must be at least "+minInitialBackoff.String()))
+	}
+	if limit < initial {
+		errs = append(errs, field.Invalid(path.Child("maxBackoff"), limit.String(), "must be at least initialBackoff"))
+	}
+
+	return errs
+}
+
+func validateCrashLoop(path *field.Path, policy servicesv1alpha1.CrashLoopPolicy) field.ErrorList {
+	var errs field.ErrorList
+
+	if window := policy.Window; window != nil && (window.Duration < minCrashLoopWindow || window.Duration > maxCrashLoopWindow) {
+		errs = append(errs, field.Invalid(path.Child("window"), window.Duration.String(), "must be between "+minCrashLoopWindow.String()+" and "+maxCrashLoopWindow.String()))
+	}
+	if policy.Restarts < 0 {
+		errs = append(errs, field.Invalid(path.Child("restarts"), policy.Restarts, "must be at least 1"))
+	}
+	if policy.StopAfter != nil && *policy.StopAfter < 1 {
+		errs = append(errs, field.Invalid(path.Child("stopAfter"), *policy.StopAfter, "must be at least 1"))
+	}
+
+	return errs
+}
+
+func validateProbe(path *field.Path, probe servicesv1alpha1.UnitProbe) field.ErrorList {
+	var errs field.ErrorList
+
+	switch {
+	case probe.Name == "":
+		errs = append(errs, field.Required(path.Child("name"), ""))
+	case len(probe.Name) > maxProbeNameLength:
+		errs = append(errs, field.TooLong(path.Child("name"), probe.Name, maxProbeNameLength))
+	case !probeNameRegexp.MatchString(probe.Name):
+		errs = append(errs, field.Invalid(path.Child("name"), probe.Name, "must consist of lower case alphanumeric characters or '-'"))
+	}
+
+	handlers := 0
+	if probe.HTTPGet != nil {
+		handlers++
+		errs = append(errs, validatePort(path.Child("httpGet", "port"), probe.HTTPGet.Port)...)
+		if probe.HTTPGet.Scheme != "" && !validURISchemes.Has(probe.HTTPGet.Scheme.String()) {
+			errs = append(errs, field.NotSupported(path.Child("httpGet", "scheme"), probe.HTTPGet.Scheme, validURISchemes.List()))
+		}
+	}
+	if probe.TCPSocket != nil {
+		handlers++
+		errs = append(errs, validatePort(path.Child("tcpSocket", "port"), probe.TCPSocket.Port)...)
+	}
+	if probe.Exec != nil {
+		handlers++
+		if len(probe.Exec.Command) == 0 || probe.Exec.Command[0] == "" {
+			errs = append(errs, field.Required(path.Child("exec", "command"), ""))
+		}
+	}
+	if handlers != 1 {
+		errs = append(errs, field.Invalid(path, handlers, "exactly one of httpGet, tcpSocket and exec must be set"))
+	}
+
+	period := probePeriod(probe)
+	if period < minProbePeriod {
+		errs = append(errs, field.Invalid(path.Child("period"), probe.Period.Duration.String(), "must be at least "+minProbePeriod.String()))
+	}
+	if probe.Timeout != nil && (probe.Timeout.Duration <= 0 || probe.Timeout.Duration > period) {
+		errs = append(errs, field.Invalid(path.Child("timeout"), probe.Timeout.Duration.String(), "must be positive and at most the period"))
+	}
+	if probe.FailureThreshold < 0 {
+		errs = append(errs, field.Invalid(path.Child("failureThreshold"), probe.FailureThreshold, "must be at least 1"))
+	}
+	if probe.RestartThreshold != nil && *probe.RestartThreshold < 1 {
+		errs = append(errs, field.Invalid(path.Child("restartThreshold"), *probe.RestartThreshold, "must be at least 1"))
+	}
+
+	return errs
+}
+
+func validatePort(path *field.Path, port int32) field.ErrorList {
+	if port < 1 || port > 65535 {
+		return field.ErrorList{field.Invalid(path, port, "must be between 1 and 65535")}
+	}
+	return nil
+}
+
+func validateSignal(path *field.Path, signal servicesv1alpha1.UnitSignal) field.ErrorList {
+	var errs field.ErrorList
+
+	switch {
+	case signal.RequestID == "":
+		errs = append(errs, field.Required(path.Child("requestID"), ""))
+	case len(signal.RequestID) > maxRequestIDLength:
+		errs = append(errs, field.TooLong(path.Child("requestID"), signal.RequestID, maxRequestIDLength))
+	}
+
+	if _, ok := proc.ParseSignal(signal.Signal.String()); !ok {
+		errs = append(errs, field.NotSupported(path.Child("signal"), signal.Signal, proc.SignalNames()))
+	}
+
+	if signal.Target != "" && !validSignalTargets.Has(signal.Target.String()) {
+		errs = append(errs, field.NotSupported(path.Child("target"), signal.Target, validSignalTargets.List()))
+	}
+
+	return errs
+}
@@ -0,0 +1,87 @@
+package systemd
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	servicesv1alpha1 "github.com/faroshq/plugin-services/pkg/apis/services/v1alpha1"
+)
+
+func TestValidateSpec(t *testing.T) {
+	unit := func(name, user string, state servicesv1alpha1.ServiceStatus) servicesv1alpha1.Unit {
+		return servicesv1alpha1.Unit{Name: name, User: user, DesiredStatus: state}
+	}
+	started := servicesv1alpha1.ServiceStatusStarted
+
+	for _, tc := range []struct {
+		name  string
+		units []servicesv1alpha1.Unit
+		want  []string
+	}{{
+		name:  "valid",
+		units: []servicesv1alpha1.Unit{unit("nginx.service", "", started), unit("app@web.service", "alice", started)},
+	}, {
+		name:  "duplicate system unit",
+		units: []servicesv1alpha1.Unit{unit("nginx.service", "", started), unit("nginx.service", "", started)},
+		want:  []string{"FieldValueDuplicate spec.services[1].name"},
+	}, {
+		name:  "same name for different users",
+		units: []servicesv1alpha1.Unit{unit("nginx.service", "", started), unit("nginx.service", "alice", started), unit("nginx.service", "bob", started)},
+	}, {
+		name:  "duplicate user unit",
+		units: []servicesv1alpha1.Unit{unit("nginx.service", "alice", started), unit("nginx.service", "alice", started)},
+		want:  []string{"FieldValueDuplicate spec.services[1].name"},
+	}, {
+		name:  "protected system unit",
+		units: []servicesv1alpha1.Unit{unit("sshd.service", "", started)},
+		want:  []string{"FieldValueForbidden spec.services[0].name"},
+	}, {
+		name:  "protected name as user unit",
+		units: []servicesv1alpha1.Unit{unit("sshd.service", "alice", started)},
+	}, {
+		name:  "missing name",
+		units: []servicesv1alpha1.Unit{unit("", "", started)},
+		want:  []string{"FieldValueRequired spec.services[0].name"},
+	}, {
+		name:  "name without type suffix",
+		units: []servicesv1alpha1.Unit{unit("nginx", "", started)},
+		want:  []string{"FieldValueInvalid spec.services[0].name"},
+	}, {
+		name:  "missing desired state",
+		units: []servicesv1alpha1.Unit{unit("nginx.service", "", "")},
+		want:  []string{"FieldValueRequired spec.services[0].desiredState"},
+	}, {
+		name:  "unsupported desired state",
+		units: []servicesv1alpha1.Unit{unit("nginx.service", "", "running")},
+		want:  []string{"FieldValueNotSupported spec.services[0].desiredState"},
+	}, {
+		name:  "linger without user",
+		units: []servicesv1alpha1.Unit{{Name: "nginx.service", DesiredStatus: started, Linger: true}},
+		want:  []string{"FieldValueInvalid spec.services[0].linger"},
+	}, {
+		name:  "invalid user",
+		units: []servicesv1alpha1.Unit{unit("nginx.service", "Alice", started)},
+		want:  []string{"FieldValueInvalid spec.services[0].user"},
+	}, {
+		name:  "user too long",
+		units: []servicesv1alpha1.Unit{unit("nginx.service", "a23456789012345678901234567890123", started)},
+		want:  []string{"FieldValueTooLong spec.services[0].user"},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			errs := validateSpec(servicesv1alpha1.SystemdSpec{Units: tc.units}, sets.NewString("sshd.service"))
+			if got := errorStrings(errs); !sets.NewString(got...).Equal(sets.NewString(tc.want...)) || len(got) != len(tc.want) {
+				t.Errorf("got errors %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func errorStrings(errs field.ErrorList) []string {
+	var out []string
+	for _, err := range errs {
+		out = append(out, string(err.Type)+" "+err.Field)
+	}
+	return out
+}
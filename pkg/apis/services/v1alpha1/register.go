@@ -38,6 +38,14 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 	scheme.AddKnownTypes(SchemeGroupVersion,
 		&Systemd{},
 		&SystemdList{},
+		&SystemdPluginConfig{},
+		&SystemdPluginConfigList{},
+		&ProcessInventory{},
+		&ProcessInventoryList{},
+		&ProcessRule{},
+		&ProcessRuleList{},
+		&RunawayGuard{},
+		&RunawayGuardList{},
 	)
 	metav1.AddToGroupVersion(scheme, SchemeGroupVersion)
 	return nil
@@ -0,0 +1,112 @@
+package v1alpha1
+
+import (
+	conditionsv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/apis/conditions/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +crd
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Namespaced
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+// +kubebuilder:object:root=true
+
+// SystemdPluginConfig configures the systemd plugin. The agent reads the object
+// named after the plugin in the plugin namespace and applies changes without
+// restarting.
+type SystemdPluginConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   SystemdPluginConfigSpec   `json:"spec,omitempty"`
+	Status SystemdPluginConfigStatus `json:"status,omitempty"`
+}
+
+// SystemdPluginConfigSpec defines the settings of the plugin. Unset fields keep
+// the defaults the plugin process was started with.
+type SystemdPluginConfigSpec struct {
+	// ResyncPeriod is how often converged Systemd objects are reconciled again
+	// to correct changes made on the device
+	// +optional
+	ResyncPeriod *metav1.Duration `json:"resyncPeriod,omitempty"`
+
+	// ProtectedUnits are system units the agent refuses to manage, e.g. the
+	// units needed to reach the device. Units of user managers are not
+	// protected.
+	// +optional
+	ProtectedUnits []string `json:"protectedUnits,omitempty"`
+
+	// Transport selects how the agent connects to systemd
+	// +optional
+	Transport Transport `json:"transport,omitempty"`
+
+	// BusAddress is the dbus address for the address transport, or an
+	// alternative socket for the private transport
+	// +optional
+	BusAddress string `json:"busAddress,omitempty"`
+
+	// MaxConcurrentReconciles is the number of Systemd objects reconciled in parallel
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=16
+	// +optional
+	MaxConcurrentReconciles int `json:"maxConcurrentReconciles,omitempty"`
+
+	// MetricsBindAddress is the address of the metrics endpoint. Changes take
+	// effect on the next start of the plugin.
+	// +optional
+	MetricsBindAddress string `json:"metricsBindAddress,omitempty"`
+}
+
+// Transport selects how the agent connects to systemd.
+// +kubebuilder:validation:Enum=auto;system;private;user;address
+type Transport string
+
+func (s Transport) String() string {
+	return string(s)
+}
+
+const (
+	// TransportAuto uses the system bus and falls back to the private systemd
+	// socket when running as root.
+	TransportAuto Transport = "auto"
+	// TransportSystem connects through the system bus of dbus-daemon.
+	TransportSystem Transport = "system"
+	// TransportPrivate connects directly to the private systemd socket, which
+	// does not need dbus-daemon.
+	TransportPrivate Transport = "private"
+	// TransportUser connects to the session bus of the user running the agent.
+	TransportUser Transport = "user"
+	// TransportAddress connects to the bus at an arbitrary dbus address.
+	TransportAddress Transport = "address"
+)
+
+// SystemdPluginConfigStatus defines the observed state of the plugin configuration
+type SystemdPluginConfigStatus struct {
+	// Conditions report whether the configuration was applied.
+	// +optional
+	Conditions conditionsv1alpha1.Conditions `json:"conditions,omitempty"`
+
+	// ObservedGeneration is the generation of the spec the status was computed from
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+func (in *SystemdPluginConfig) SetConditions(c conditionsv1alpha1.Conditions) {
+	in.Status.Conditions = c
+}
+
+func (in *SystemdPluginConfig) GetConditions() conditionsv1alpha1.Conditions {
+	return in.Status.Conditions
+}
+
+// SystemdPluginConfigList contains a list of plugin configurations
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:object:root=true
+type SystemdPluginConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []SystemdPluginConfig `json:"items"`
+}
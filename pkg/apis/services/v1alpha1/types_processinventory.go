@@ -0,0 +1,189 @@
+package v1alpha1
+
+import (
+	conditionsv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/apis/conditions/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +crd
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Namespaced
+// +kubebuilder:printcolumn:name="Processes",type="integer",JSONPath=".status.matchedProcesses"
+// +kubebuilder:printcolumn:name="Updated",type="date",JSONPath=".status.lastUpdateTime"
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+// +kubebuilder:object:root=true
+
+// ProcessInventory lists the processes running on the device. The agent
+// refreshes the status periodically from /proc.
+type ProcessInventory struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ProcessInventorySpec   `json:"spec,omitempty"`
+	Status ProcessInventoryStatus `json:"status,omitempty"`
+}
+
+// ProcessInventorySpec selects the processes to list
+type ProcessInventorySpec struct {
+	// RefreshPeriod is how often the process list is refreshed, at least 10s.
+	// Defaults to 1m.
+	// +optional
+	RefreshPeriod *metav1.Duration `json:"refreshPeriod,omitempty"`
+
+	// Filter selects the processes to list, all user space processes when unset
+	// +optional
+	Filter *ProcessFilter `json:"filter,omitempty"`
+
+	// SortBy orders the processes before MaxEntries is applied
+	// +kubebuilder:default=memory
+	// +optional
+	SortBy ProcessSortOrder `json:"sortBy,omitempty"`
+
+	// MaxEntries caps the number of processes listed in the status
+	// +kubebuilder:default=200
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=1000
+	// +optional
+	MaxEntries int32 `json:"maxEntries,omitempty"`
+
+	// ListeningSockets lists the sockets each listed process listens on
+	// +optional
+	ListeningSockets bool `json:"listeningSockets,omitempty"`
+}
+
+// ProcessFilter selects processes. A process has to match all fields which
+// are set.
+type ProcessFilter struct {
+	// Users are the names of the users the processes run as
+	// +optional
+	Users []string `json:"users,omitempty"`
+
+	// Units are the systemd units owning the processes, system units or units
+	// of a user manager
+	// +optional
+	Units []string `json:"units,omitempty"`
+
+	// CommandPattern is a regular expression matched against the command line
+	// +optional
+	CommandPattern string `json:"commandPattern,omitempty"`
+
+	// MinRSSBytes is the smallest resident set size of listed processes
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MinRSSBytes int64 `json:"minRSSBytes,omitempty"`
+
+	// IncludeKernelThreads lists kernel threads too
+	// +optional
+	IncludeKernelThreads bool `json:"includeKernelThreads,omitempty"`
+}
+
+// ProcessSortOrder is the order of the listed processes.
+// +kubebuilder:validation:Enum=memory;cpu;pid
+type ProcessSortOrder string
+
+func (s ProcessSortOrder) String() string {
+	return string(s)
+}
+
+const (
+	// ProcessSortMemory lists processes with the largest RSS first.
+	ProcessSortMemory ProcessSortOrder = "memory"
+	// ProcessSortCPU lists processes with the most CPU time first.
+	ProcessSortCPU ProcessSortOrder = "cpu"
+	// ProcessSortPID lists processes by ascending pid.
+	ProcessSortPID ProcessSortOrder = "pid"
+)
+
+// ProcessInventoryStatus defines the observed processes
+type ProcessInventoryStatus struct {
+	// Conditions report whether the process list could be read.
+	// +optional
+	Conditions conditionsv1alpha1.Conditions `json:"conditions,omitempty"`
+
+	// ObservedGeneration is the generation of the spec the status was computed from
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// LastUpdateTime is when the process list was read
+	// +optional
+	LastUpdateTime *metav1.Time `json:"lastUpdateTime,omitempty"`
+
+	// TotalProcesses is the number of processes running on the device
+	// +optional
+	TotalProcesses int32 `json:"totalProcesses,omitempty"`
+
+	// MatchedProcesses is the number of processes matching the filter
+	// +optional
+	MatchedProcesses int32 `json:"matchedProcesses,omitempty"`
+
+	// Truncated is set when more processes matched than MaxEntries
+	// +optional
+	Truncated bool `json:"truncated,omitempty"`
+
+	// Processes are the matching processes, at most MaxEntries
+	// +optional
+	Processes []ProcessInfo `json:"processes,omitempty"`
+}
+
+// ProcessInfo describes a running process
+type ProcessInfo struct {
+	PID  int32 `json:"pid"`
+	PPID int32 `json:"ppid,omitempty"`
+
+	// User is the name of the user the process runs as
+	// +optional
+	User string `json:"user,omitempty"`
+
+	// Command is the name of the executable
+	Command string `json:"command"`
+
+	// Cmdline is the command line, truncated to 256 characters
+	// +optional
+	Cmdline string `json:"cmdline,omitempty"`
+
+	// StartTime is when the process started
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// CPUTimeMilliseconds is the user and system CPU time used by the process
+	// +optional
+	CPUTimeMilliseconds int64 `json:"cpuTimeMilliseconds,omitempty"`
+
+	// RSSBytes is the resident set size
+	// +optional
+	RSSBytes int64 `json:"rssBytes,omitempty"`
+
+	// Unit is the systemd unit owning the process, derived from its cgroup
+	// +optional
+	Unit string `json:"unit,omitempty"`
+
+	// UserUnit is the unit of a user manager owning the process, Unit is then
+	// the user manager itself, e.g. user@1000.service
+	// +optional
+	UserUnit string `json:"userUnit,omitempty"`
+
+	// Listening are the sockets the process listens on, when the spec asks
+	// for them
+	// +optional
+	Listening []ListeningSocket `json:"listening,omitempty"`
+}
+
+func (in *ProcessInventory) SetConditions(c conditionsv1alpha1.Conditions) {
+	in.Status.Conditions = c
+}
+
+func (in *ProcessInventory) GetConditions() conditionsv1alpha1.Conditions {
+	return in.Status.Conditions
+}
+
+// ProcessInventoryList contains a list of process inventories
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:object:root=true
+type ProcessInventoryList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ProcessInventory `json:"items"`
+}
@@ -0,0 +1,148 @@
+package v1alpha1
+
+import (
+	conditionsv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/apis/conditions/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +crd
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Namespaced
+// +kubebuilder:printcolumn:name="Expectation",type="string",JSONPath=".spec.expectation.type"
+// +kubebuilder:printcolumn:name="Processes",type="integer",JSONPath=".status.matchedProcesses"
+// +kubebuilder:printcolumn:name="Satisfied",type="string",JSONPath=`.status.conditions[?(@.type=="RuleSatisfied")].status`
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+// +kubebuilder:object:root=true
+
+// ProcessRule watches processes on the device which are not necessarily
+// managed by systemd, e.g. spawned by vendor software, and reports whether
+// they run as expected.
+type ProcessRule struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ProcessRuleSpec   `json:"spec,omitempty"`
+	Status ProcessRuleStatus `json:"status,omitempty"`
+}
+
+// ProcessRuleSpec defines the processes to watch and what is expected of them
+type ProcessRuleSpec struct {
+	// Match selects the processes the rule applies to
+	// +kubebuilder:validation:Required
+	Match ProcessMatcher `json:"match"`
+
+	// Expectation is checked against the matching processes
+	// +kubebuilder:validation:Required
+	Expectation ProcessExpectation `json:"expectation"`
+
+	// CheckPeriod is how often the rule is evaluated, at least 10s. Defaults
+	// to 30s.
+	// +optional
+	CheckPeriod *metav1.Duration `json:"checkPeriod,omitempty"`
+}
+
+// ProcessMatcher selects processes. A process has to match all fields which
+// are set, at least one has to be. Kernel threads never match.
+type ProcessMatcher struct {
+	// Executable is the absolute path of the executable
+	// +optional
+	Executable string `json:"executable,omitempty"`
+
+	// CommandPattern is a regular expression matched against the command line
+	// +optional
+	CommandPattern string `json:"commandPattern,omitempty"`
+
+	// User is the name of the user the processes run as
+	// +optional
+	User string `json:"user,omitempty"`
+}
+
+// ProcessExpectation is what is expected of the matching processes
+type ProcessExpectation struct {
+	// Type of the expectation
+	// +kubebuilder:validation:Required
+	Type ProcessExpectationType `json:"type"`
+
+	// MinCount is the least number of processes which must run, for MustRun.
+	// Defaults to 1.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MinCount *int32 `json:"minCount,omitempty"`
+
+	// MaxCount is the most processes which may run, for MustRun
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxCount *int32 `json:"maxCount,omitempty"`
+
+	// Kill sends KillSignal to processes which must not run, for MustNotRun
+	// +optional
+	Kill bool `json:"kill,omitempty"`
+
+	// KillSignal is the signal sent to forbidden processes
+	// +kubebuilder:default=SIGTERM
+	// +optional
+	KillSignal SignalName `json:"killSignal,omitempty"`
+}
+
+// ProcessExpectationType is the kind of a process expectation.
+// +kubebuilder:validation:Enum=MustRun;MustNotRun
+type ProcessExpectationType string
+
+func (s ProcessExpectationType) String() string {
+	return string(s)
+}
+
+const (
+	// ProcessMustRun expects between MinCount and MaxCount matching processes.
+	ProcessMustRun ProcessExpectationType = "MustRun"
+	// ProcessMustNotRun expects no matching process.
+	ProcessMustNotRun ProcessExpectationType = "MustNotRun"
+)
+
+// ProcessRuleStatus defines the observed state of the rule
+type ProcessRuleStatus struct {
+	// Conditions report whether the rule could be evaluated and is satisfied.
+	// +optional
+	Conditions conditionsv1alpha1.Conditions `json:"conditions,omitempty"`
+
+	// ObservedGeneration is the generation of the spec the status was computed from
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// LastCheckTime is when the rule was last evaluated
+	// +optional
+	LastCheckTime *metav1.Time `json:"lastCheckTime,omitempty"`
+
+	// MatchedProcesses is the number of processes matching the rule
+	// +optional
+	MatchedProcesses int32 `json:"matchedProcesses,omitempty"`
+
+	// PIDs of the matching processes, at most 50
+	// +optional
+	PIDs []int32 `json:"pids,omitempty"`
+}
+
+const (
+	// RuleSatisfiedCondition reports whether the processes matching a
+	// ProcessRule meet its expectation.
+	RuleSatisfiedCondition conditionsv1alpha1.ConditionType = "RuleSatisfied"
+)
+
+func (in *ProcessRule) SetConditions(c conditionsv1alpha1.Conditions) {
+	in.Status.Conditions = c
+}
+
+func (in *ProcessRule) GetConditions() conditionsv1alpha1.Conditions {
+	return in.Status.Conditions
+}
+
+// ProcessRuleList contains a list of process rules
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:object:root=true
+type ProcessRuleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ProcessRule `json:"items"`
+}
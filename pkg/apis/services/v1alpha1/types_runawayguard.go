@@ -0,0 +1,163 @@
+package v1alpha1
+
+import (
+	conditionsv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/apis/conditions/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +crd
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Namespaced
+// +kubebuilder:printcolumn:name="Action",type="string",JSONPath=".spec.action"
+// +kubebuilder:printcolumn:name="Runaway",type="string",JSONPath=`.status.conditions[?(@.type=="RunawayDetected")].status`
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+// +kubebuilder:object:root=true
+
+// RunawayGuard watches processes for sustained excessive resource usage and
+// acts on them, so a single misbehaving daemon can not lock up the device.
+type RunawayGuard struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   RunawayGuardSpec   `json:"spec,omitempty"`
+	Status RunawayGuardStatus `json:"status,omitempty"`
+}
+
+// RunawayGuardSpec selects the processes to watch, their limits and the
+// action taken when a process exceeds a limit for the whole window.
+type RunawayGuardSpec struct {
+	// Units selects the processes of these systemd units, system units or
+	// units of a user manager
+	// +optional
+	Units []string `json:"units,omitempty"`
+
+	// Match selects processes by executable, command line or user. Processes
+	// have to match Units too when both are set.
+	// +optional
+	Match *ProcessMatcher `json:"match,omitempty"`
+
+	// CPUPercent is the CPU usage limit, in percent of one core
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	CPUPercent *int32 `json:"cpuPercent,omitempty"`
+
+	// MaxRSSBytes is the resident set size limit
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxRSSBytes *int64 `json:"maxRSSBytes,omitempty"`
+
+	// MaxOpenFDs is the limit of open file descriptors
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxOpenFDs *int32 `json:"maxOpenFDs,omitempty"`
+
+	// Window is how long a limit has to be exceeded continuously before the
+	// process is considered a runaway, at least 30s. It is also the least
+	// time between two actions on the same process. Defaults to 1m.
+	// +optional
+	Window *metav1.Duration `json:"window,omitempty"`
+
+	// Action taken on runaway processes
+	// +kubebuilder:default=Event
+	// +optional
+	Action RunawayAction `json:"action,omitempty"`
+}
+
+// RunawayAction is the action taken on a runaway process.
+// +kubebuilder:validation:Enum=Event;Terminate;RestartUnit
+type RunawayAction string
+
+func (s RunawayAction) String() string {
+	return string(s)
+}
+
+const (
+	// RunawayActionEvent only records an event and reports the process.
+	RunawayActionEvent RunawayAction = "Event"
+	// RunawayActionTerminate sends SIGTERM to the process.
+	RunawayActionTerminate RunawayAction = "Terminate"
+	// RunawayActionRestartUnit restarts the systemd unit owning the process.
+	RunawayActionRestartUnit RunawayAction = "RestartUnit"
+)
+
+// RunawayResource is a resource a runaway process used too much of.
+type RunawayResource string
+
+const (
+	RunawayResourceCPU     RunawayResource = "cpu"
+	RunawayResourceMemory  RunawayResource = "memory"
+	RunawayResourceOpenFDs RunawayResource = "openFDs"
+)
+
+// RunawayGuardStatus defines the observed state of the guard
+type RunawayGuardStatus struct {
+	// Conditions report whether the guard is watching and found runaways.
+	// +optional
+	Conditions conditionsv1alpha1.Conditions `json:"conditions,omitempty"`
+
+	// ObservedGeneration is the generation of the spec the status was computed from
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// WatchedProcesses is the number of processes the guard selects
+	// +optional
+	WatchedProcesses int32 `json:"watchedProcesses,omitempty"`
+
+	// Runaways are the processes currently exceeding a limit for the whole
+	// window, at most 50
+	// +optional
+	Runaways []RunawayProcess `json:"runaways,omitempty"`
+}
+
+// RunawayProcess is a process exceeding a limit
+type RunawayProcess struct {
+	PID int32 `json:"pid"`
+	// Command is the name of the executable
+	Command string `json:"command"`
+	// Unit is the systemd unit owning the process
+	// +optional
+	Unit string `json:"unit,omitempty"`
+	// Resource is the limit exceeded
+	Resource RunawayResource `json:"resource"`
+	// Value is the usage when the process was detected, in percent of one
+	// core, bytes or file descriptors
+	Value int64 `json:"value"`
+	// Limit is the configured limit, in the unit of Value
+	Limit int64 `json:"limit"`
+	// Since is when the process started exceeding the limit
+	Since metav1.Time `json:"since"`
+	// Action is the action taken
+	// +optional
+	Action RunawayAction `json:"action,omitempty"`
+	// ActionTime is when the action was taken
+	// +optional
+	ActionTime *metav1.Time `json:"actionTime,omitempty"`
+	// ActionError explains why the action failed
+	// +optional
+	ActionError string `json:"actionError,omitempty"`
+}
+
+const (
+	// RunawayDetectedCondition reports whether a RunawayGuard found processes
+	// exceeding its limits.
+	RunawayDetectedCondition conditionsv1alpha1.ConditionType = "RunawayDetected"
+)
+
+func (in *RunawayGuard) SetConditions(c conditionsv1alpha1.Conditions) {
+	in.Status.Conditions = c
+}
+
+func (in *RunawayGuard) GetConditions() conditionsv1alpha1.Conditions {
+	return in.Status.Conditions
+}
+
+// RunawayGuardList contains a list of runaway guards
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:object:root=true
+type RunawayGuardList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []RunawayGuard `json:"items"`
+}
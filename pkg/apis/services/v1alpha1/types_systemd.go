@@ -10,6 +10,8 @@ import (
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 // +kubebuilder:subresource:status
 // +kubebuilder:resource:scope=Namespaced
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Units",type="string",JSONPath=".spec.services[*].name"
 // +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 // +kubebuilder:object:root=true
 
@@ -24,24 +26,284 @@ type Systemd struct {
 
 // SystemdSpec defines the desired state of plugin
 type SystemdSpec struct {
+	// Units are identified by their user and name, so the same unit can be
+	// managed for several users and the system.
+	// +listType=map
+	// +listMapKey=user
+	// +listMapKey=name
 	Units []Unit `json:"services,omitempty"`
 }
 
 type Unit struct {
-	// Name of the service
-	Name string `json:"name,omitempty"`
+	// Name of the service, including the unit type suffix (e.g. nginx.service)
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MaxLength=255
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z0-9:_.\\-]+(@[a-zA-Z0-9:_.\\-]*)?\.(service|socket|device|mount|automount|swap|target|path|timer|slice|scope)$`
+	Name string `json:"name"`
 	// DesiredStatus is desired status of the service
+	// +kubebuilder:default=started
+	// +optional
 	DesiredStatus ServiceStatus `json:"desiredState,omitempty"`
 
 	// ActivationMode of the service
+	// +kubebuilder:default=replace
 	// +optional
 	ActivationMode ActivationMode `json:"activationMode,omitempty"`
 
 	// EnableMode of the service
+	// +kubebuilder:default=runtime
 	// +optional
 	EnableMode EnableMode `json:"enableMode,omitempty"`
+
+	// User whose user manager (systemd --user) owns the unit. The unit is
+	// managed by the system manager when empty.
+	// +kubebuilder:default=""
+	// +kubebuilder:validation:MaxLength=32
+	// +kubebuilder:validation:Pattern=`^([a-z_][a-z0-9_.-]*\$?)?$`
+	// +optional
+	User string `json:"user,omitempty"`
+
+	// Linger enables lingering for User through logind, so the user manager is
+	// started at boot and keeps running after the user logs out.
+	// +optional
+	Linger bool `json:"linger,omitempty"`
+
+	// Accounting turns on CPU, memory, tasks, IO and IP accounting for the
+	// unit when it is off, so its resource usage is reported. It follows
+	// EnableMode, so it is reverted on reboot in runtime mode.
+	// +optional
+	Accounting bool `json:"accounting,omitempty"`
+
+	// Signal requests sending a signal to the processes of the unit, e.g. to
+	// make a daemon reopen its logs. It is sent once for every RequestID.
+	// +optional
+	Signal *UnitSignal `json:"signal,omitempty"`
+
+	// Probes check that the service works beyond systemd reporting it active.
+	// The agent runs them while the unit is active, the unit is healthy when
+	// all of them pass.
+	// +listType=map
+	// +listMapKey=name
+	// +kubebuilder:validation:MaxItems=8
+	// +optional
+	Probes []UnitProbe `json:"probes,omitempty"`
+
+	// Readiness delays reporting a started unit as converged until it stayed
+	// active for a while, so services crashing right after their start job
+	// finished are reported as failed. It requires DesiredStatus started or
+	// enabled-and-started.
+	// +optional
+	Readiness *UnitReadiness `json:"readiness,omitempty"`
+
+	// CrashLoop tunes the detection of services which systemd keeps
+	// restarting, and lets the agent stop them. Crash loops are detected with
+	// the defaults when unset.
+	// +optional
+	CrashLoop *CrashLoopPolicy `json:"crashLoop,omitempty"`
+
+	// Recovery lets the agent reset a failed unit and start it again with
+	// exponential backoff, e.g. when it hit its start limit at boot because
+	// the network was not up yet. It requires DesiredStatus started or
+	// enabled-and-started.
+	// +optional
+	Recovery *RecoveryPolicy `json:"recovery,omitempty"`
+}
+
+// RecoveryPolicy defines how often and when a failed unit is started again
+type RecoveryPolicy struct {
+	// MaxAttempts is the number of starts after which the agent gives up
+	// +kubebuilder:default=5
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	// +optional
+	MaxAttempts int32 `json:"maxAttempts,omitempty"`
+
+	// InitialBackoff is the delay after the first attempt, doubled after
+	// every further one. At least 1s, defaults to 10s.
+	// +optional
+	InitialBackoff *metav1.Duration `json:"initialBackoff,omitempty"`
+
+	// MaxBackoff caps the delay between attempts. Defaults to 5m.
+	// +optional
+	MaxBackoff *metav1.Duration `json:"maxBackoff,omitempty"`
+}
+
+// CrashLoopPolicy defines when a service is crash looping
+type CrashLoopPolicy struct {
+	// Window in which restarts are counted, between 1m and 1h. Defaults to 5m.
+	// +optional
+	Window *metav1.Duration `json:"window,omitempty"`
+
+	// Restarts within Window after which the service is crash looping
+	// +kubebuilder:default=3
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Restarts int32 `json:"restarts,omitempty"`
+
+	// StopAfter is the number of restarts within Window after which the agent
+	// stops the service to protect the device. It stays stopped until the
+	// unit is changed. The service is not stopped when unset.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	StopAfter *int32 `json:"stopAfter,omitempty"`
+}
+
+// UnitReadiness defines when a running unit is ready
+type UnitReadiness struct {
+	// StablePeriod is how long the unit has to be active, at most 10m.
+	// Defaults to 10s.
+	// +optional
+	StablePeriod *metav1.Duration `json:"stablePeriod,omitempty"`
+
+	// WaitForProbes also requires all probes of the unit to pass
+	// +optional
+	WaitForProbes bool `json:"waitForProbes,omitempty"`
 }
 
+// UnitProbe is a health check of a unit. Exactly one of HTTPGet, TCPSocket
+// and Exec has to be set.
+type UnitProbe struct {
+	// Name identifies the probe in the status
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	Name string `json:"name"`
+
+	// HTTPGet succeeds when a GET request returns a status below 400
+	// +optional
+	HTTPGet *HTTPGetProbe `json:"httpGet,omitempty"`
+
+	// TCPSocket succeeds when a TCP connection can be opened
+	// +optional
+	TCPSocket *TCPSocketProbe `json:"tcpSocket,omitempty"`
+
+	// Exec succeeds when the command exits with status 0
+	// +optional
+	Exec *ExecProbe `json:"exec,omitempty"`
+
+	// Period is how often the probe runs, at least 5s. Defaults to 30s.
+	// +optional
+	Period *metav1.Duration `json:"period,omitempty"`
+
+	// Timeout of a single run, at most Period. Defaults to 5s.
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+
+	// FailureThreshold is the number of consecutive failures after which the
+	// unit is reported unhealthy
+	// +kubebuilder:default=3
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	FailureThreshold int32 `json:"failureThreshold,omitempty"`
+
+	// RestartThreshold is the number of consecutive failures after which the
+	// agent restarts the unit. The unit is not restarted when unset.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	RestartThreshold *int32 `json:"restartThreshold,omitempty"`
+}
+
+// HTTPGetProbe probes a service with an HTTP GET request
+type HTTPGetProbe struct {
+	// Host to connect to. Defaults to 127.0.0.1.
+	// +optional
+	Host string `json:"host,omitempty"`
+
+	// Port to connect to
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	Port int32 `json:"port"`
+
+	// Path of the request. Defaults to /.
+	// +optional
+	Path string `json:"path,omitempty"`
+
+	// Scheme of the request. Certificates are not verified for HTTPS.
+	// +kubebuilder:default=HTTP
+	// +optional
+	Scheme URIScheme `json:"scheme,omitempty"`
+}
+
+// URIScheme is the scheme of an HTTP probe.
+// +kubebuilder:validation:Enum=HTTP;HTTPS
+type URIScheme string
+
+func (s URIScheme) String() string {
+	return string(s)
+}
+
+const (
+	URISchemeHTTP  URIScheme = "HTTP"
+	URISchemeHTTPS URIScheme = "HTTPS"
+)
+
+// TCPSocketProbe probes a service by opening a TCP connection
+type TCPSocketProbe struct {
+	// Host to connect to. Defaults to 127.0.0.1.
+	// +optional
+	Host string `json:"host,omitempty"`
+
+	// Port to connect to
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	Port int32 `json:"port"`
+}
+
+// ExecProbe probes a service by running a command on the device. The command
+// runs without a shell, as the agent for system units and as the user for user
+// units. It is killed with its process group on timeout.
+type ExecProbe struct {
+	// Command is the executable and its arguments
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinItems=1
+	Command []string `json:"command"`
+}
+
+// UnitSignal is a request to send a signal to the processes of a unit
+type UnitSignal struct {
+	// RequestID identifies the request. The signal is sent once, changing
+	// RequestID sends it again.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=63
+	RequestID string `json:"requestID"`
+
+	// Signal to send
+	// +kubebuilder:validation:Required
+	Signal SignalName `json:"signal"`
+
+	// Target selects the processes receiving the signal
+	// +kubebuilder:default=main
+	// +optional
+	Target SignalTarget `json:"target,omitempty"`
+}
+
+// SignalName is the name of a signal which can be sent to units.
+// +kubebuilder:validation:Enum=SIGHUP;SIGINT;SIGQUIT;SIGUSR1;SIGUSR2;SIGTERM;SIGKILL
+type SignalName string
+
+func (s SignalName) String() string {
+	return string(s)
+}
+
+// SignalTarget selects the processes of a unit receiving a signal.
+// +kubebuilder:validation:Enum=main;all
+type SignalTarget string
+
+func (s SignalTarget) String() string {
+	return string(s)
+}
+
+const (
+	// SignalTargetMain sends the signal to the main process of the unit only.
+	SignalTargetMain SignalTarget = "main"
+	// SignalTargetAll sends the signal to all processes of the unit.
+	SignalTargetAll SignalTarget = "all"
+)
+
+// +kubebuilder:validation:Enum=enabled;disabled;stopped;started;enabled-and-started;disabled-and-stopped;masked;preset
 type ServiceStatus string
 
 func (s ServiceStatus) String() string {
@@ -55,6 +317,10 @@ const (
 	ServiceStatusStarted            ServiceStatus = "started"
 	ServiceStatusEnabledAndStarted  ServiceStatus = "enabled-and-started"
 	ServiceStatusDisabledAndStopped ServiceStatus = "disabled-and-stopped"
+	// The unit is masked, so it can not be started at all, and stopped
+	ServiceStatusMasked ServiceStatus = "masked"
+	// The unit is enabled or disabled as the preset policy of the device says
+	ServiceStatusPreset ServiceStatus = "preset"
 )
 
 // Takes the unit to activate, plus a mode string. The mode needs to be one of
@@ -68,6 +334,7 @@ const (
 // If "ignore-requirements" it will start a unit but only ignore the
 // requirement dependencies. It is not recommended to make use of the latter
 // two options.
+// +kubebuilder:validation:Enum=replace;fail;isolate;ignore-dependencies;ignore-requirements
 type ActivationMode string
 
 func (s ActivationMode) String() string {
@@ -82,6 +349,7 @@ const (
 	ActivationModeIgnoreRequirements ActivationMode = "ignore-requirements"
 )
 
+// +kubebuilder:validation:Enum=runtime;persistent
 type EnableMode string
 
 func (s EnableMode) String() string {
@@ -101,6 +369,10 @@ type SystemdStatus struct {
 	// +optional
 	Conditions conditionsv1alpha1.Conditions `json:"conditions,omitempty"`
 
+	// ObservedGeneration is the generation of the spec the status was computed from
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
 	// Units is the list of units managed by the plugin
 	// +optional
 	Units []UnitStatus `json:"services,omitempty"`
@@ -109,6 +381,9 @@ type SystemdStatus struct {
 type UnitStatus struct {
 	// Name of the service
 	Name string `json:"name,omitempty"`
+	// User whose user manager owns the service, empty for system services
+	// +optional
+	User string `json:"user,omitempty"`
 	// State defines current state of the service
 	Status string `json:"state,omitempty"`
 	// DesiredStatus of the service
@@ -116,8 +391,225 @@ type UnitStatus struct {
 	// Error message if the service failed to start
 	// +optional
 	Error string `json:"error,omitempty"`
+	// LastTransitionTime is the last time the state or error of the service changed
+	// +optional
+	LastTransitionTime *metav1.Time `json:"lastTransitionTime,omitempty"`
+	// LastAppliedTime is the last time the agent applied the desired state to the service
+	// +optional
+	LastAppliedTime *metav1.Time `json:"lastAppliedTime,omitempty"`
+	// ProcessCount is the number of processes in the control group of the unit
+	// +optional
+	ProcessCount int32 `json:"processCount,omitempty"`
+	// Processes in the control group of the unit, including forked children,
+	// ordered by pid. At most 50 are listed.
+	// +optional
+	Processes []UnitProcess `json:"processes,omitempty"`
+	// Zombies is the number of processes of the unit which exited but were
+	// not reaped by their parent
+	// +optional
+	Zombies int32 `json:"zombies,omitempty"`
+	// Orphans is the number of processes of the unit whose parent exited, so
+	// they were reparented, not counting processes systemd started itself
+	// +optional
+	Orphans int32 `json:"orphans,omitempty"`
+	// Listening are the sockets the processes of the unit listen on, at most 32
+	// +optional
+	Listening []ListeningSocket `json:"listening,omitempty"`
+	// Resources is the resource usage of the unit as accounted by systemd. It
+	// is refreshed at most once a minute.
+	// +optional
+	Resources *UnitResources `json:"resources,omitempty"`
+	// Signal is the outcome of the last signal request
+	// +optional
+	Signal *SignalStatus `json:"signal,omitempty"`
+	// Restarts is the number of automatic restarts of a service, as counted
+	// by systemd
+	// +optional
+	Restarts int32 `json:"restarts,omitempty"`
+	// RecentRestarts is the number of restarts within the crash loop window
+	// +optional
+	RecentRestarts int32 `json:"recentRestarts,omitempty"`
+	// CrashLoopHoldUnitHash is the hash of the unit spec when the agent
+	// stopped the crash looping service, it is not started again until the
+	// unit changes
+	// +optional
+	CrashLoopHoldUnitHash string `json:"crashLoopHoldUnitHash,omitempty"`
+	// Recovery records the attempts to start the unit again after it failed
+	// +optional
+	Recovery *RecoveryStatus `json:"recovery,omitempty"`
+	// MainExit is how the main process of the unit last exited
+	// +optional
+	MainExit *ProcessExit `json:"mainExit,omitempty"`
+	// Conditions of the unit, e.g. Healthy when it has probes and Ready when
+	// it has a readiness policy
+	// +optional
+	Conditions conditionsv1alpha1.Conditions `json:"conditions,omitempty"`
+	// Probes are the results of the probes of the unit
+	// +optional
+	Probes []ProbeStatus `json:"probes,omitempty"`
+}
+
+// RecoveryStatus records the attempts to recover a failed unit
+type RecoveryStatus struct {
+	// Attempts is the number of times the failed unit was reset and started
+	Attempts int32 `json:"attempts"`
+	// LastAttemptTime is when the unit was last started
+	// +optional
+	LastAttemptTime *metav1.Time `json:"lastAttemptTime,omitempty"`
+	// NextAttemptTime is when the unit is started again if it is still failed
+	// +optional
+	NextAttemptTime *metav1.Time `json:"nextAttemptTime,omitempty"`
+	// GaveUp is set when all attempts were used and the unit still failed
+	// +optional
+	GaveUp bool `json:"gaveUp,omitempty"`
+	// UnitHash is the hash of the unit spec the attempts were made for, the
+	// attempts start over when the unit changes
+	// +optional
+	UnitHash string `json:"unitHash,omitempty"`
+}
+
+// ProcessExit describes how a process exited
+type ProcessExit struct {
+	// Result of the unit as reported by systemd, e.g. exit-code or signal
+	// +optional
+	Result string `json:"result,omitempty"`
+	// ExitCode of the process, when it exited on its own
+	// +optional
+	ExitCode *int32 `json:"exitCode,omitempty"`
+	// Signal which killed the process
+	// +optional
+	Signal string `json:"signal,omitempty"`
+}
+
+// ProbeStatus is the result of the last run of a probe
+type ProbeStatus struct {
+	// Name of the probe
+	Name string `json:"name"`
+	// Success is whether the last run passed
+	Success bool `json:"success"`
+	// ConsecutiveFailures is the number of failed runs since the last
+	// success or restart of the unit
+	// +optional
+	ConsecutiveFailures int32 `json:"consecutiveFailures,omitempty"`
+	// Message explains the last failure
+	// +optional
+	Message string `json:"message,omitempty"`
+	// LastProbeTime is when the probe last ran
+	// +optional
+	LastProbeTime *metav1.Time `json:"lastProbeTime,omitempty"`
+}
+
+// SignalStatus is the outcome of a signal request
+type SignalStatus struct {
+	// RequestID of the handled request
+	RequestID string       `json:"requestID"`
+	Signal    SignalName   `json:"signal"`
+	Target    SignalTarget `json:"target,omitempty"`
+	// Result is whether the signal was sent
+	Result SignalResult `json:"result"`
+	// Message explains a failure
+	// +optional
+	Message string `json:"message,omitempty"`
+	// Time is when the request was handled
+	// +optional
+	Time *metav1.Time `json:"time,omitempty"`
 }
 
+// SignalResult is the outcome of a signal request.
+// +kubebuilder:validation:Enum=Sent;Failed
+type SignalResult string
+
+const (
+	// SignalResultSent means systemd delivered the signal.
+	SignalResultSent SignalResult = "Sent"
+	// SignalResultFailed means the signal could not be sent, e.g. because the
+	// unit had no main process. Failed requests are not retried.
+	SignalResultFailed SignalResult = "Failed"
+)
+
+// ListeningSocket is a socket a process is listening on
+type ListeningSocket struct {
+	// Protocol is tcp, tcp6, udp, udp6 or unix
+	Protocol string `json:"protocol"`
+	// Address is the local IP address, or the path of a unix socket, with a
+	// leading @ for abstract ones
+	// +optional
+	Address string `json:"address,omitempty"`
+	// Port of IP sockets
+	// +optional
+	Port int32 `json:"port,omitempty"`
+	// PID of the process holding the socket, the lowest when it is shared
+	// +optional
+	PID int32 `json:"pid,omitempty"`
+}
+
+// UnitResources is the resource usage of a unit. Values systemd does not
+// account, e.g. because accounting is off for the unit, are not set.
+type UnitResources struct {
+	// CPUUsageNSec is the CPU time consumed by the unit in nanoseconds
+	// +optional
+	CPUUsageNSec *int64 `json:"cpuUsageNSec,omitempty"`
+	// MemoryCurrentBytes is the memory currently used by the unit
+	// +optional
+	MemoryCurrentBytes *int64 `json:"memoryCurrentBytes,omitempty"`
+	// MemoryPeakBytes is the most memory used by the unit since it started,
+	// reported by systemd 255 and newer
+	// +optional
+	MemoryPeakBytes *int64 `json:"memoryPeakBytes,omitempty"`
+	// TasksCurrent is the number of tasks (processes and threads) of the unit
+	// +optional
+	TasksCurrent *int64 `json:"tasksCurrent,omitempty"`
+	// IPIngressBytes is the IP traffic received by the unit
+	// +optional
+	IPIngressBytes *int64 `json:"ipIngressBytes,omitempty"`
+	// IPEgressBytes is the IP traffic sent by the unit
+	// +optional
+	IPEgressBytes *int64 `json:"ipEgressBytes,omitempty"`
+	// IOReadBytes is the data read from block devices by the unit
+	// +optional
+	IOReadBytes *int64 `json:"ioReadBytes,omitempty"`
+	// LastUpdateTime is when the values were read
+	// +optional
+	LastUpdateTime *metav1.Time `json:"lastUpdateTime,omitempty"`
+}
+
+// UnitProcess is a process running in the control group of a unit
+type UnitProcess struct {
+	PID int32 `json:"pid"`
+	// Command is the name of the executable
+	Command string `json:"command"`
+	// State is the state of the process as shown by ps, e.g. S for sleeping
+	// or Z for zombies
+	// +optional
+	State string `json:"state,omitempty"`
+	// RSSBytes is the resident set size
+	// +optional
+	RSSBytes int64 `json:"rssBytes,omitempty"`
+}
+
+const (
+	// SystemdConnectedCondition reports whether the agent is connected to systemd on the device.
+	SystemdConnectedCondition conditionsv1alpha1.ConditionType = "SystemdConnected"
+	// DisconnectedCondition is set on status the agent recorded while the hub
+	// was unreachable and reported later, so it may be stale.
+	DisconnectedCondition conditionsv1alpha1.ConditionType = "Disconnected"
+	// ZombiesDetectedCondition reports whether processes of managed units
+	// exited without being reaped by their parent.
+	ZombiesDetectedCondition conditionsv1alpha1.ConditionType = "ZombiesDetected"
+	// HealthyCondition is set on units with probes and reports whether all
+	// of them pass.
+	HealthyCondition conditionsv1alpha1.ConditionType = "Healthy"
+	// CrashLoopingCondition is set on services and on the Systemd object, and
+	// reports whether systemd keeps restarting them.
+	CrashLoopingCondition conditionsv1alpha1.ConditionType = "CrashLooping"
+	// CrashedAfterStartReason is the reason of the Ready condition of a unit
+	// which stopped running before it was stable.
+	CrashedAfterStartReason = "CrashedAfterStart"
+	// UnitsNotReadyReason is the reason of the Ready condition of a Systemd
+	// object while units with a readiness policy are not ready yet.
+	UnitsNotReadyReason = "UnitsNotReady"
+)
+
 func (in *Systemd) SetConditions(c conditionsv1alpha1.Conditions) {
 	in.Status.Conditions = c
 }
@@ -27,10 +27,26 @@ type FakeServicesV1alpha1 struct {
 	*testing.Fake
 }
 
+func (c *FakeServicesV1alpha1) ProcessInventories(namespace string) v1alpha1.ProcessInventoryInterface {
+	return &FakeProcessInventories{c, namespace}
+}
+
+func (c *FakeServicesV1alpha1) ProcessRules(namespace string) v1alpha1.ProcessRuleInterface {
+	return &FakeProcessRules{c, namespace}
+}
+
+func (c *FakeServicesV1alpha1) RunawayGuards(namespace string) v1alpha1.RunawayGuardInterface {
+	return &FakeRunawayGuards{c, namespace}
+}
+
 func (c *FakeServicesV1alpha1) Systemds(namespace string) v1alpha1.SystemdInterface {
 	return &FakeSystemds{c, namespace}
 }
 
+func (c *FakeServicesV1alpha1) SystemdPluginConfigs(namespace string) v1alpha1.SystemdPluginConfigInterface {
+	return &FakeSystemdPluginConfigs{c, namespace}
+}
+
 // RESTClient returns a RESTClient that is used to communicate
 // with API server by this client implementation.
 func (c *FakeServicesV1alpha1) RESTClient() rest.Interface {
@@ -17,4 +17,12 @@ limitations under the License.
 
 package v1alpha1
 
+type ProcessInventoryExpansion interface{}
+
+type ProcessRuleExpansion interface{}
+
+type RunawayGuardExpansion interface{}
+
 type SystemdExpansion interface{}
+
+type SystemdPluginConfigExpansion interface{}
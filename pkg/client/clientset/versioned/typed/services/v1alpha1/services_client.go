@@ -27,7 +27,11 @@ import (
 
 type ServicesV1alpha1Interface interface {
 	RESTClient() rest.Interface
+	ProcessInventoriesGetter
+	ProcessRulesGetter
+	RunawayGuardsGetter
 	SystemdsGetter
+	SystemdPluginConfigsGetter
 }
 
 // ServicesV1alpha1Client is used to interact with features provided by the services.plugins.faros.sh group.
@@ -35,10 +39,26 @@ type ServicesV1alpha1Client struct {
 	restClient rest.Interface
 }
 
+func (c *ServicesV1alpha1Client) ProcessInventories(namespace string) ProcessInventoryInterface {
+	return newProcessInventories(c, namespace)
+}
+
+func (c *ServicesV1alpha1Client) ProcessRules(namespace string) ProcessRuleInterface {
+	return newProcessRules(c, namespace)
+}
+
+func (c *ServicesV1alpha1Client) RunawayGuards(namespace string) RunawayGuardInterface {
+	return newRunawayGuards(c, namespace)
+}
+
 func (c *ServicesV1alpha1Client) Systemds(namespace string) SystemdInterface {
 	return newSystemds(c, namespace)
 }
 
+func (c *ServicesV1alpha1Client) SystemdPluginConfigs(namespace string) SystemdPluginConfigInterface {
+	return newSystemdPluginConfigs(c, namespace)
+}
+
 // NewForConfig creates a new ServicesV1alpha1Client for the given config.
 // NewForConfig is equivalent to NewForConfigAndClient(c, httpClient),
 // where httpClient was generated with rest.HTTPClientFor(c).
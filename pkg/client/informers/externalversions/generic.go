@@ -52,8 +52,16 @@ func (f *genericInformer) Lister() cache.GenericLister {
 func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource) (GenericInformer, error) {
 	switch resource {
 	// Group=services.plugins.faros.sh, Version=v1alpha1
+	case v1alpha1.SchemeGroupVersion.WithResource("processinventories"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Services().V1alpha1().ProcessInventories().Informer()}, nil
+	case v1alpha1.SchemeGroupVersion.WithResource("processrules"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Services().V1alpha1().ProcessRules().Informer()}, nil
+	case v1alpha1.SchemeGroupVersion.WithResource("runawayguards"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Services().V1alpha1().RunawayGuards().Informer()}, nil
 	case v1alpha1.SchemeGroupVersion.WithResource("systemds"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Services().V1alpha1().Systemds().Informer()}, nil
+	case v1alpha1.SchemeGroupVersion.WithResource("systemdpluginconfigs"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Services().V1alpha1().SystemdPluginConfigs().Informer()}, nil
 
 	}
 
@@ -23,8 +23,16 @@ import (
 
 // Interface provides access to all the informers in this group version.
 type Interface interface {
+	// ProcessInventories returns a ProcessInventoryInformer.
+	ProcessInventories() ProcessInventoryInformer
+	// ProcessRules returns a ProcessRuleInformer.
+	ProcessRules() ProcessRuleInformer
+	// RunawayGuards returns a RunawayGuardInformer.
+	RunawayGuards() RunawayGuardInformer
 	// Systemds returns a SystemdInformer.
 	Systemds() SystemdInformer
+	// SystemdPluginConfigs returns a SystemdPluginConfigInformer.
+	SystemdPluginConfigs() SystemdPluginConfigInformer
 }
 
 type version struct {
@@ -38,7 +46,27 @@ func New(f internalinterfaces.SharedInformerFactory, namespace string, tweakList
 	return &version{factory: f, namespace: namespace, tweakListOptions: tweakListOptions}
 }
 
+// ProcessInventories returns a ProcessInventoryInformer.
+func (v *version) ProcessInventories() ProcessInventoryInformer {
+	return &processInventoryInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
+// ProcessRules returns a ProcessRuleInformer.
+func (v *version) ProcessRules() ProcessRuleInformer {
+	return &processRuleInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
+// RunawayGuards returns a RunawayGuardInformer.
+func (v *version) RunawayGuards() RunawayGuardInformer {
+	return &runawayGuardInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
 // Systemds returns a SystemdInformer.
 func (v *version) Systemds() SystemdInformer {
 	return &systemdInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
 }
+
+// SystemdPluginConfigs returns a SystemdPluginConfigInformer.
+func (v *version) SystemdPluginConfigs() SystemdPluginConfigInformer {
+	return &systemdPluginConfigInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
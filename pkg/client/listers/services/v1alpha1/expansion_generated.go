@@ -17,6 +17,30 @@ limitations under the License.
 
 package v1alpha1
 
+// ProcessInventoryListerExpansion allows custom methods to be added to
+// ProcessInventoryLister.
+type ProcessInventoryListerExpansion interface{}
+
+// ProcessInventoryNamespaceListerExpansion allows custom methods to be added to
+// ProcessInventoryNamespaceLister.
+type ProcessInventoryNamespaceListerExpansion interface{}
+
+// ProcessRuleListerExpansion allows custom methods to be added to
+// ProcessRuleLister.
+type ProcessRuleListerExpansion interface{}
+
+// ProcessRuleNamespaceListerExpansion allows custom methods to be added to
+// ProcessRuleNamespaceLister.
+type ProcessRuleNamespaceListerExpansion interface{}
+
+// RunawayGuardListerExpansion allows custom methods to be added to
+// RunawayGuardLister.
+type RunawayGuardListerExpansion interface{}
+
+// RunawayGuardNamespaceListerExpansion allows custom methods to be added to
+// RunawayGuardNamespaceLister.
+type RunawayGuardNamespaceListerExpansion interface{}
+
 // SystemdListerExpansion allows custom methods to be added to
 // SystemdLister.
 type SystemdListerExpansion interface{}
@@ -24,3 +48,11 @@ type SystemdListerExpansion interface{}
 // SystemdNamespaceListerExpansion allows custom methods to be added to
 // SystemdNamespaceLister.
 type SystemdNamespaceListerExpansion interface{}
+
+// SystemdPluginConfigListerExpansion allows custom methods to be added to
+// SystemdPluginConfigLister.
+type SystemdPluginConfigListerExpansion interface{}
+
+// SystemdPluginConfigNamespaceListerExpansion allows custom methods to be added to
+// SystemdPluginConfigNamespaceLister.
+type SystemdPluginConfigNamespaceListerExpansion interface{}
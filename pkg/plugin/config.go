@@ -0,0 +1,73 @@
+package plugin
+
+import (
+	"context"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/rest"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+
+	"github.com/faroshq/plugin-services/pkg/agent/systemd"
+	servicesv1alpha1 "github.com/faroshq/plugin-services/pkg/apis/services/v1alpha1"
+)
+
+// configTimeout bounds how long reading the plugin configuration may delay
+// the start of the agent when the hub does not answer.
+const configTimeout = 10 * time.Second
+
+// defaultConfig returns the plugin configuration the process was started with.
+func (s *SystemD) defaultConfig() servicesv1alpha1.SystemdPluginConfigSpec {
+	return servicesv1alpha1.SystemdPluginConfigSpec{
+		Transport:          s.Options.Transport,
+		BusAddress:         s.Options.BusAddress,
+		MetricsBindAddress: s.Options.MetricsBindAddress,
+	}
+}
+
+// loadConfig reads the SystemdPluginConfig named after the plugin before the
+// manager starts, so settings which can not change at runtime are honoured.
+// Any failure falls back to defaults, the config controller reports problems
+// on the object once it runs. It gives up after configTimeout, the hub may be
+// unreachable.
+func loadConfig(ctx context.Context, config *rest.Config, name, namespace string, defaults servicesv1alpha1.SystemdPluginConfigSpec) servicesv1alpha1.SystemdPluginConfigSpec {
+	ctx, cancel := context.WithTimeout(ctx, configTimeout)
+	defer cancel()
+	config = rest.CopyConfig(config)
+	config.Timeout = configTimeout
+
+	mapper, err := lazyRESTMapper(config)
+	if err != nil {
+		klog.Warningf("unable to create client to read plugin configuration: %v", err)
+		return defaults
+	}
+	c, err := client.New(config, client.Options{Scheme: scheme, Mapper: mapper})
+	if err != nil {
+		klog.Warningf("unable to create client to read plugin configuration: %v", err)
+		return defaults
+	}
+
+	var pluginConfig servicesv1alpha1.SystemdPluginConfig
+	if err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, &pluginConfig); err != nil {
+		if !apierrors.IsNotFound(err) {
+			klog.Warningf("unable to read plugin configuration %s/%s: %v", namespace, name, err)
+		}
+		return defaults
+	}
+
+	spec := systemd.MergeConfig(defaults, pluginConfig.Spec)
+	if errs := systemd.ValidateConfig(spec); len(errs) > 0 {
+		klog.Warningf("ignoring invalid plugin configuration %s/%s: %v", namespace, name, errs.ToAggregate())
+		return defaults
+	}
+	return spec
+}
+
+// lazyRESTMapper returns a RESTMapper which runs discovery on the first
+// request instead of when it is created.
+func lazyRESTMapper(config *rest.Config) (meta.RESTMapper, error) {
+	return apiutil.NewDynamicRESTMapper(config, apiutil.WithLazyDiscovery)
+}
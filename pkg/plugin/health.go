@@ -0,0 +1,34 @@
+package plugin
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+
+	servicesv1alpha1 "github.com/faroshq/plugin-services/pkg/apis/services/v1alpha1"
+)
+
+const hubCheckTimeout = 5 * time.Second
+
+// hubChecker returns a health check which fails when the hub API does not
+// serve the plugin's API group.
+func hubChecker(config *rest.Config) (healthz.Checker, error) {
+	config = rest.CopyConfig(config)
+	config.Timeout = hubCheckTimeout
+
+	client, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(_ *http.Request) error {
+		if _, err := client.ServerResourcesForGroupVersion(servicesv1alpha1.SchemeGroupVersion.String()); err != nil {
+			return fmt.Errorf("failed to reach hub API: %w", err)
+		}
+		return nil
+	}, nil
+}
@@ -0,0 +1,52 @@
+package plugin
+
+import (
+	"os"
+
+	servicesv1alpha1 "github.com/faroshq/plugin-services/pkg/apis/services/v1alpha1"
+)
+
+const (
+	// EnvMetricsBindAddress overrides the address the metrics endpoint binds to.
+	EnvMetricsBindAddress = "FAROS_SYSTEMD_METRICS_BIND_ADDRESS"
+	// EnvTransport selects how the agent connects to systemd.
+	EnvTransport = "FAROS_SYSTEMD_TRANSPORT"
+	// EnvBusAddress is the dbus address used by the private and address transports.
+	EnvBusAddress = "FAROS_SYSTEMD_BUS_ADDRESS"
+	// EnvStateFile overrides where the agent caches desired state and status.
+	EnvStateFile = "FAROS_SYSTEMD_STATE_FILE"
+
+	// DefaultStateFile is where the agent caches desired state and status.
+	DefaultStateFile = "/var/lib/faros/systemd/state.json"
+)
+
+// Options are settings of the plugin process. The plugin interface has no way
+// to pass them from faros-hub, so they are read from the environment the
+// plugin binary is started with.
+type Options struct {
+	// MetricsBindAddress is the address the Prometheus metrics endpoint listens
+	// on, e.g. ":9100". A random free port is used when empty.
+	MetricsBindAddress string
+
+	// Transport selects how the agent connects to systemd. Defaults to
+	// servicesv1alpha1.TransportAuto.
+	Transport servicesv1alpha1.Transport
+	// BusAddress is the dbus address for the address transport, or an
+	// alternative socket for the private transport.
+	BusAddress string
+
+	// StateFile is where Systemd objects and their status are cached, so they
+	// are enforced after a restart while the hub is unreachable. Defaults to
+	// DefaultStateFile.
+	StateFile string
+}
+
+// OptionsFromEnv returns Options populated from the process environment.
+func OptionsFromEnv() Options {
+	return Options{
+		MetricsBindAddress: os.Getenv(EnvMetricsBindAddress),
+		Transport:          servicesv1alpha1.Transport(os.Getenv(EnvTransport)),
+		BusAddress:         os.Getenv(EnvBusAddress),
+		StateFile:          os.Getenv(EnvStateFile),
+	}
+}
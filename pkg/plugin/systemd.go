@@ -1,11 +1,16 @@
 package plugin
 
 import (
+	"bytes"
 	"context"
 	"embed"
+	"errors"
 	"fmt"
+	"io"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/phayes/freeport"
 	corev1 "k8s.io/api/core/v1"
@@ -21,8 +26,10 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 
 	"github.com/faroshq/faros-hub/pkg/plugins"
+	"github.com/faroshq/plugin-services/pkg/agent/process"
 	"github.com/faroshq/plugin-services/pkg/agent/systemd"
 	servicesv1alpha1 "github.com/faroshq/plugin-services/pkg/apis/services/v1alpha1"
+	"github.com/faroshq/plugin-services/pkg/util/proc"
 	utiltemplate "github.com/faroshq/plugin-services/pkg/util/template"
 	"github.com/faroshq/plugin-services/pkg/util/version"
 )
@@ -33,6 +40,15 @@ var (
 	pluginName = "systemds.services.plugins.faros.sh"
 )
 
+const (
+	// shutdownTimeout bounds how long Stop waits for in-flight reconciles and
+	// systemd jobs when the caller does not set an earlier deadline.
+	shutdownTimeout = 30 * time.Second
+	// flushTimeout is the extra time given to reconciles to patch their status
+	// after pending systemd jobs were abandoned.
+	flushTimeout = 5 * time.Second
+)
+
 func init() {
 	utilruntime.Must(servicesv1alpha1.AddToScheme(scheme))
 	utilruntime.Must(corev1.AddToScheme(scheme))
@@ -41,11 +57,21 @@ func init() {
 var _ plugins.Interface = &SystemD{}
 
 type SystemD struct {
-	name      string
-	namespace string
-	client    client.Client
-	schema    *runtime.Scheme
-	manager   manager.Manager
+	// Options configure the plugin process. They are applied in Init.
+	Options Options
+
+	name       string
+	namespace  string
+	client     client.Client
+	schema     *runtime.Scheme
+	manager    manager.Manager
+	conn       *systemd.Connection
+	userConns  *systemd.UserConnections
+	reconciler *systemd.Reconciler
+
+	lock    sync.Mutex
+	cancel  context.CancelFunc
+	stopped chan struct{}
 }
 
 func (s *SystemD) GetName(context.Context) (string, error) {
@@ -57,17 +83,41 @@ func (s *SystemD) GetVersion(context.Context) (string, error) {
 }
 
 func (s *SystemD) Init(ctx context.Context, name, namespace string, config *rest.Config) error {
-	port, err := freeport.GetFreePort()
+	ports, err := freeport.GetFreePorts(3)
 	if err != nil {
 		return err
 	}
 
+	// The cache is what the agent enforces while the hub is unreachable, load
+	// it before anything talks to the hub.
+	stateFile := s.Options.StateFile
+	if stateFile == "" {
+		stateFile = DefaultStateFile
+	}
+	cache, err := systemd.NewCache(stateFile)
+	if err != nil {
+		// Managing units while the hub is reachable does not depend on it.
+		klog.Warningf("unable to load state cache %s, running without it: %v", stateFile, err)
+	}
+
+	pluginConfig := loadConfig(ctx, config, name, namespace, s.defaultConfig())
+
+	metricsBindAddress := pluginConfig.MetricsBindAddress
+	if metricsBindAddress == "" {
+		metricsBindAddress = ":" + strconv.Itoa(ports[0])
+	}
+
+	gracefulShutdownTimeout := shutdownTimeout
 	options := ctrl.Options{
-		Scheme:                 scheme,
-		MetricsBindAddress:     ":" + strconv.Itoa(port),
-		Port:                   port,
-		HealthProbeBindAddress: ":" + strconv.Itoa(port),
-		LeaderElection:         false,
+		Scheme:                  scheme,
+		MetricsBindAddress:      metricsBindAddress,
+		Port:                    ports[1],
+		HealthProbeBindAddress:  ":" + strconv.Itoa(ports[2]),
+		LeaderElection:          false,
+		GracefulShutdownTimeout: &gracefulShutdownTimeout,
+		// Discovery waits for the first request, so the manager can be
+		// created and started while the hub is unreachable.
+		MapperProvider: lazyRESTMapper,
 	}
 
 	mgr, err := ctrl.NewManager(config, options)
@@ -80,34 +130,173 @@ func (s *SystemD) Init(ctx context.Context, name, namespace string, config *rest
 	s.name = name
 	s.namespace = namespace
 
-	if err = (&systemd.Reconciler{
-		Client: s.client,
-		Scheme: s.schema,
-	}).SetupWithManager(mgr); err != nil {
+	s.conn = systemd.NewConnection(nil)
+	if err := mgr.Add(s.conn); err != nil {
+		klog.Error(err, "unable to set up systemd connection")
+		return err
+	}
+
+	s.userConns = systemd.NewUserConnections()
+
+	processes, err := proc.NewReader("")
+	if err != nil {
+		klog.Error(err, "unable to read processes")
+		return err
+	}
+
+	reconciler := &systemd.Reconciler{
+		Client:          s.client,
+		Scheme:          s.schema,
+		Recorder:        mgr.GetEventRecorderFor("faros-systemd"),
+		Connection:      s.conn,
+		UserConnections: s.userConns,
+		Cache:           cache,
+		APIReader:       mgr.GetAPIReader(),
+		Processes:       processes,
+	}
+	if err = reconciler.SetupWithManager(mgr); err != nil {
 		klog.Error(err, "unable to create controller", pluginName)
 		return err
 	}
+	if err := mgr.Add(reconciler.CacheReplayer()); err != nil {
+		klog.Error(err, "unable to set up state cache")
+		return err
+	}
+
+	configReconciler := &systemd.ConfigReconciler{
+		Client:     s.client,
+		Name:       name,
+		Namespace:  namespace,
+		Defaults:   s.defaultConfig(),
+		Reconciler: reconciler,
+		Connection: s.conn,
+	}
+	if err := configReconciler.Apply(pluginConfig); err != nil {
+		return err
+	}
+	if err = configReconciler.SetupWithManager(mgr); err != nil {
+		klog.Error(err, "unable to create config controller", pluginName)
+		return err
+	}
+
+	inventoryReconciler := &process.InventoryReconciler{
+		Client:    s.client,
+		Processes: processes,
+	}
+	if err = inventoryReconciler.SetupWithManager(mgr); err != nil {
+		klog.Error(err, "unable to create process inventory controller", pluginName)
+		return err
+	}
+	ruleReconciler := &process.RuleReconciler{
+		Client:         s.client,
+		Recorder:       mgr.GetEventRecorderFor("faros-systemd"),
+		Processes:      processes,
+		ProtectedUnits: reconciler.ProtectedUnits,
+	}
+	if err = ruleReconciler.SetupWithManager(mgr); err != nil {
+		klog.Error(err, "unable to create process rule controller", pluginName)
+		return err
+	}
+	guardReconciler := &process.GuardReconciler{
+		Client:          s.client,
+		Recorder:        mgr.GetEventRecorderFor("faros-systemd"),
+		Processes:       processes,
+		Connection:      s.conn,
+		UserConnections: s.userConns,
+		ProtectedUnits:  reconciler.ProtectedUnits,
+	}
+	if err = guardReconciler.SetupWithManager(mgr); err != nil {
+		klog.Error(err, "unable to create runaway guard controller", pluginName)
+		return err
+	}
+
+	hubCheck, err := hubChecker(config)
+	if err != nil {
+		klog.Error(err, "unable to create hub health check")
+		return err
+	}
 
+	// Checks are named after the dependency they verify, so a failing probe
+	// reports which one is broken, e.g. "[-]systemd failed". Dependencies only
+	// gate readiness, restarting the agent does not bring systemd back.
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		klog.Error(err, "unable to set up health check")
 		return err
 	}
-	if err := mgr.AddReadyzCheck("readyz", healthz.Ping); err != nil {
+	if err := mgr.AddReadyzCheck("systemd", reconciler.SystemdChecker()); err != nil {
+		klog.Error(err, "unable to set up ready check")
+		return err
+	}
+	if err := mgr.AddReadyzCheck("hub", hubCheck); err != nil {
 		klog.Error(err, "unable to set up ready check")
 		return err
 	}
 
 	s.manager = mgr
+	s.reconciler = reconciler
 
 	return nil
 }
 
 func (s *SystemD) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	stopped := make(chan struct{})
+	defer close(stopped)
+
+	s.lock.Lock()
+	s.cancel = cancel
+	s.stopped = stopped
+	s.lock.Unlock()
+
+	// The manager only reconciles once its caches synced with the hub, which
+	// never happens while the hub is unreachable.
+	go s.reconciler.EnforceCache(ctx)
+
 	return s.manager.Start(ctx)
 }
 
+// Stop stops the manager and waits for in-flight reconciles, including the
+// systemd jobs they queued and their final status patches, before closing the
+// systemd connections. If that takes longer than the deadline of ctx, or
+// shutdownTimeout, pending jobs are abandoned and recorded as failed.
 func (s *SystemD) Stop(ctx context.Context) error {
-	return nil
+	ctx, cancel := context.WithTimeout(ctx, shutdownTimeout)
+	defer cancel()
+
+	s.lock.Lock()
+	stop, stopped := s.cancel, s.stopped
+	s.lock.Unlock()
+
+	var err error
+	if stop != nil {
+		stop()
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			err = fmt.Errorf("timed out waiting for manager to stop: %w", ctx.Err())
+		}
+	}
+
+	if s.reconciler != nil {
+		if waitErr := s.reconciler.Wait(ctx); waitErr != nil {
+			klog.Warning("abandoning pending systemd jobs on shutdown")
+			s.reconciler.Abort()
+			// Give reconciles a moment to record the abandoned jobs.
+			flushCtx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+			defer cancel()
+			if flushErr := s.reconciler.Wait(flushCtx); flushErr != nil {
+				err = fmt.Errorf("in-flight reconciles did not finish: %w", flushErr)
+			}
+		}
+	}
+
+	if s.conn != nil {
+		s.conn.Close()
+	}
+	if s.userConns != nil {
+		s.userConns.Close()
+	}
+	return err
 }
 
 //go:embed data/*
@@ -144,16 +333,25 @@ func (s *SystemD) GetAPIExportSchema(ctx context.Context) ([]byte, error) {
 		return nil, fmt.Errorf("apiexport or apiresourceschema not found")
 	}
 
-	// get name for apiresourceschemas
+	// get names for apiresourceschemas, the file holds one document per kind
 	data, err := content.ReadFile("data/" + apiResourceSchemaName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read apiresourceschema: %w", err)
 	}
 
-	var unstructured unstructured.Unstructured
-	err = yaml.Unmarshal(data, &unstructured)
-	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal apiresourceschema: %w", err)
+	var schemaNames []string
+	decoder := yaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), 4096)
+	for {
+		var unstructured unstructured.Unstructured
+		if err := decoder.Decode(&unstructured); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("failed to unmarshal apiresourceschema: %w", err)
+		}
+		if unstructured.GetName() != "" {
+			schemaNames = append(schemaNames, unstructured.GetName())
+		}
 	}
 
 	data, err = content.ReadFile("data/" + apiExportName)
@@ -162,8 +360,8 @@ func (s *SystemD) GetAPIExportSchema(ctx context.Context) ([]byte, error) {
 	}
 
 	args := utiltemplate.TemplateArgs{
-		Name:                 fmt.Sprintf("%s.%s", version.Get().Version, pluginName),
-		LatestResourceSchema: unstructured.GetName(),
+		Name:                  fmt.Sprintf("%s.%s", version.Get().Version, pluginName),
+		LatestResourceSchemas: schemaNames,
 	}
 	apiExportBytes, err := utiltemplate.RenderTemplate(data, args)
 	if err != nil {
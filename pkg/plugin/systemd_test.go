@@ -0,0 +1,125 @@
+package plugin
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/util/conditions"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+
+	"github.com/faroshq/plugin-services/pkg/agent/systemd"
+	servicesv1alpha1 "github.com/faroshq/plugin-services/pkg/apis/services/v1alpha1"
+)
+
+// TestOfflineStart checks that the agent starts and enforces the cached
+// desired state while the hub can not be reached.
+func TestOfflineStart(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		hub  func(t *testing.T) string
+	}{{
+		name: "connection refused",
+		hub: func(t *testing.T) string {
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			addr := l.Addr().String()
+			l.Close()
+			return addr
+		},
+	}, {
+		name: "no response",
+		hub: func(t *testing.T) string {
+			// Accepts connections and never answers.
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { l.Close() })
+			go func() {
+				var conns []net.Conn
+				defer func() {
+					for _, c := range conns {
+						c.Close()
+					}
+				}()
+				for {
+					c, err := l.Accept()
+					if err != nil {
+						return
+					}
+					conns = append(conns, c)
+				}
+			}()
+			return l.Addr().String()
+		},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			stateFile := filepath.Join(t.TempDir(), "state.json")
+			cache, err := systemd.NewCache(stateFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cached := &servicesv1alpha1.Systemd{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web", Generation: 1},
+				Spec: servicesv1alpha1.SystemdSpec{Units: []servicesv1alpha1.Unit{{
+					Name:          "nginx.service",
+					DesiredStatus: servicesv1alpha1.ServiceStatusStarted,
+				}}},
+			}
+			if err := cache.Store("root", cached, false); err != nil {
+				t.Fatal(err)
+			}
+
+			s := &SystemD{Options: Options{
+				// systemd is not reachable either, applying fails but is
+				// still recorded in the cache.
+				Transport:  servicesv1alpha1.TransportAddress,
+				BusAddress: "unix:path=" + filepath.Join(t.TempDir(), "bus"),
+				StateFile:  stateFile,
+			}}
+			config := &rest.Config{Host: "https://" + tc.hub(t)}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			start := time.Now()
+			if err := s.Init(ctx, "systemd", "default", config); err != nil {
+				t.Fatalf("Init failed: %v", err)
+			}
+			// Reading the configuration gives up after configTimeout.
+			if elapsed := time.Since(start); elapsed > configTimeout+2*time.Second {
+				t.Errorf("Init took %v", elapsed)
+			}
+
+			runErr := make(chan error, 1)
+			go func() { runErr <- s.Run(ctx) }()
+			defer func() {
+				if err := s.Stop(context.Background()); err != nil {
+					t.Errorf("Stop failed: %v", err)
+				}
+			}()
+
+			for {
+				reloaded, err := systemd.NewCache(stateFile)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got, ok := reloaded.Get("root", "default", "web"); ok && conditions.IsTrue(got.Object, servicesv1alpha1.DisconnectedCondition) {
+					return
+				}
+				select {
+				case err := <-runErr:
+					t.Fatalf("Run returned before the cached state was enforced: %v", err)
+				case <-ctx.Done():
+					t.Fatal("cached state was not enforced")
+				case <-time.After(100 * time.Millisecond):
+				}
+			}
+		})
+	}
+}
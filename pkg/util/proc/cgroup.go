@@ -0,0 +1,101 @@
+package proc
+
+import (
+	"bufio"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"syscall"
+)
+
+// cgroupRoots are where the hierarchy systemd organizes processes in is
+// mounted: the unified hierarchy, or the name=systemd one on cgroup v1.
+var cgroupRoots = []string{"/sys/fs/cgroup", "/sys/fs/cgroup/systemd"}
+
+// CgroupPIDs returns the pids of the processes in the cgroup at path, as
+// reported in the ControlGroup property of a unit, and in all cgroups below
+// it, e.g. of a service with delegation.
+func CgroupPIDs(path string) ([]int, error) {
+	root, err := cgroupRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	var pids []int
+	err = filepath.WalkDir(filepath.Join(root, path), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Cgroups of processes exiting meanwhile disappear.
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		cgroupPIDs, err := readPIDs(filepath.Join(path, "cgroup.procs"))
+		if errors.Is(err, fs.ErrNotExist) || errors.Is(err, syscall.ENODEV) {
+			return nil
+		}
+		pids = append(pids, cgroupPIDs...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Ints(pids)
+	return pids, nil
+}
+
+// CgroupProcesses returns the processes in the cgroup at path and below it,
+// see CgroupPIDs. Processes exiting while they are read are skipped.
+func (r *Reader) CgroupProcesses(path string) ([]Process, error) {
+	pids, err := CgroupPIDs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	processes := make([]Process, 0, len(pids))
+	for _, pid := range pids {
+		process, err := r.Get(pid)
+		if errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrPermission) || errors.Is(err, syscall.ESRCH) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		processes = append(processes, process)
+	}
+	return processes, nil
+}
+
+func cgroupRoot() (string, error) {
+	for _, root := range cgroupRoots {
+		if _, err := os.Stat(filepath.Join(root, "cgroup.procs")); err == nil {
+			return root, nil
+		}
+	}
+	return "", errors.New("no cgroup hierarchy managed by systemd found")
+}
+
+func readPIDs(path string) ([]int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var pids []int
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		pid, err := strconv.Atoi(scanner.Text())
+		if err != nil {
+			return nil, err
+		}
+		pids = append(pids, pid)
+	}
+	return pids, scanner.Err()
+}
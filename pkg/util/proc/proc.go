@@ -0,0 +1,244 @@
+// Package proc reads information about running processes from procfs.
+package proc
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os/user"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/procfs"
+)
+
+// userHZ is the unit of the CPU and start times in /proc/<pid>/stat. It is
+// 100 on all architectures Linux supports, procfs makes the same assumption.
+const userHZ = 100
+
+// unitSuffixes are the types of units which own processes.
+var unitSuffixes = []string{".service", ".scope"}
+
+// Process is a snapshot of a running process.
+type Process struct {
+	PID  int
+	PPID int
+	// PGID is the process group of the process.
+	PGID int
+	// UID is the effective user id and User its name, or the id if it has none.
+	UID  string
+	User string
+	// Comm is the command name, Cmdline the full command line. Cmdline is
+	// empty for kernel threads and zombies.
+	Comm    string
+	Cmdline []string
+	// Executable is the path of the executable, empty when it can not be read,
+	// e.g. for kernel threads or processes of other users without privileges.
+	Executable string
+	// State is the state letter shown by ps, e.g. R, S or Z.
+	State     string
+	StartTime time.Time
+	// CPUTime is the user and system time spent by the process.
+	CPUTime time.Duration
+	// RSS is the resident set size in bytes.
+	RSS     uint64
+	Threads int
+	// Cgroup is the path of the systemd control group of the process.
+	Cgroup string
+	// Unit is the system unit owning the process, e.g. nginx.service, and
+	// UserUnit the unit of a user manager (systemd --user) within it.
+	Unit     string
+	UserUnit string
+}
+
+// KernelThread reports whether the process is a kernel thread.
+func (p Process) KernelThread() bool {
+	return p.PID == 2 || p.PPID == 2
+}
+
+// Reader reads processes from a procfs mount.
+type Reader struct {
+	fs procfs.FS
+
+	lock     sync.Mutex
+	bootTime time.Time
+	users    map[string]string
+}
+
+// NewReader returns a Reader for the procfs mounted at mountPoint, or the
+// default /proc when it is empty.
+func NewReader(mountPoint string) (*Reader, error) {
+	if mountPoint == "" {
+		mountPoint = procfs.DefaultMountPoint
+	}
+	fs, err := procfs.NewFS(mountPoint)
+	if err != nil {
+		return nil, err
+	}
+	return &Reader{
+		fs:    fs,
+		users: map[string]string{},
+	}, nil
+}
+
+// List returns all processes. Processes exiting while they are read, or
+// hidden from the reader, are skipped.
+func (r *Reader) List() ([]Process, error) {
+	procs, err := r.fs.AllProcs()
+	if err != nil {
+		return nil, err
+	}
+
+	processes := make([]Process, 0, len(procs))
+	for _, p := range procs {
+		process, err := r.read(p)
+		if errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrPermission) || errors.Is(err, syscall.ESRCH) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read process %d: %w", p.PID, err)
+		}
+		processes = append(processes, process)
+	}
+	return processes, nil
+}
+
+// Get returns the process with pid.
+func (r *Reader) Get(pid int) (Process, error) {
+	p, err := r.fs.Proc(pid)
+	if err != nil {
+		return Process{}, err
+	}
+	return r.read(p)
+}
+
+// OpenFiles returns the number of open file descriptors of the process with pid.
+func (r *Reader) OpenFiles(pid int) (int, error) {
+	p, err := r.fs.Proc(pid)
+	if err != nil {
+		return 0, err
+	}
+	return p.FileDescriptorsLen()
+}
+
+func (r *Reader) read(p procfs.Proc) (Process, error) {
+	stat, err := p.Stat()
+	if err != nil {
+		return Process{}, err
+	}
+	status, err := p.NewStatus()
+	if err != nil {
+		return Process{}, err
+	}
+	cmdline, err := p.CmdLine()
+	if err != nil {
+		return Process{}, err
+	}
+	cgroups, err := p.Cgroups()
+	if err != nil {
+		return Process{}, err
+	}
+	executable, _ := p.Executable()
+	bootTime, err := r.boot()
+	if err != nil {
+		return Process{}, err
+	}
+
+	process := Process{
+		PID:        stat.PID,
+		PPID:       stat.PPID,
+		PGID:       stat.PGRP,
+		UID:        status.UIDs[1],
+		User:       r.userName(status.UIDs[1]),
+		Comm:       stat.Comm,
+		Cmdline:    cmdline,
+		Executable: executable,
+		State:      stat.State,
+		StartTime:  bootTime.Add(time.Duration(stat.Starttime) * (time.Second / userHZ)),
+		CPUTime:    time.Duration(stat.UTime+stat.STime) * (time.Second / userHZ),
+		RSS:        uint64(stat.ResidentMemory()),
+		Threads:    stat.NumThreads,
+		Cgroup:     systemdCgroup(cgroups),
+	}
+	process.Unit, process.UserUnit = UnitsFromCgroup(process.Cgroup)
+	return process, nil
+}
+
+// boot returns the boot time, it is read once.
+func (r *Reader) boot() (time.Time, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.bootTime.IsZero() {
+		stat, err := r.fs.Stat()
+		if err != nil {
+			return time.Time{}, err
+		}
+		r.bootTime = time.Unix(int64(stat.BootTime), 0)
+	}
+	return r.bootTime, nil
+}
+
+// userName returns the name of uid, caching lookups.
+func (r *Reader) userName(uid string) string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if name, ok := r.users[uid]; ok {
+		return name
+	}
+	name := uid
+	if u, err := user.LookupId(uid); err == nil {
+		name = u.Username
+	}
+	r.users[uid] = name
+	return name
+}
+
+// systemdCgroup returns the path of the cgroup systemd placed the process in:
+// the unified hierarchy, or the name=systemd one on cgroup v1.
+func systemdCgroup(cgroups []procfs.Cgroup) string {
+	var unified string
+	for _, cgroup := range cgroups {
+		for _, controller := range cgroup.Controllers {
+			if controller == "name=systemd" {
+				return cgroup.Path
+			}
+		}
+		if cgroup.HierarchyID == 0 {
+			unified = cgroup.Path
+		}
+	}
+	return unified
+}
+
+// UnitsFromCgroup returns the system unit owning the cgroup at path, and for
+// cgroups below a user manager the user unit, e.g. user@1000.service and
+// app.service for /user.slice/user-1000.slice/user@1000.service/app.slice/app.service.
+func UnitsFromCgroup(path string) (unit, userUnit string) {
+	for _, element := range strings.Split(strings.Trim(path, "/"), "/") {
+		if !isUnit(element) {
+			continue
+		}
+		switch {
+		case unit == "":
+			unit = element
+		case strings.HasPrefix(unit, "user@"):
+			return unit, element
+		default:
+			return unit, ""
+		}
+	}
+	return unit, ""
+}
+
+func isUnit(name string) bool {
+	for _, suffix := range unitSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
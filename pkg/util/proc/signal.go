@@ -0,0 +1,45 @@
+package proc
+
+import (
+	"fmt"
+	"sort"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// signals are the signals the agent sends to processes on request.
+var signals = map[string]syscall.Signal{
+	"SIGHUP":  syscall.SIGHUP,
+	"SIGINT":  syscall.SIGINT,
+	"SIGQUIT": syscall.SIGQUIT,
+	"SIGUSR1": syscall.SIGUSR1,
+	"SIGUSR2": syscall.SIGUSR2,
+	"SIGTERM": syscall.SIGTERM,
+	"SIGKILL": syscall.SIGKILL,
+}
+
+// ParseSignal returns the signal with name, e.g. SIGHUP. It reports false for
+// signals the agent does not send.
+func ParseSignal(name string) (syscall.Signal, bool) {
+	signal, ok := signals[name]
+	return signal, ok
+}
+
+// SignalNames returns the names of the signals ParseSignal accepts.
+func SignalNames() []string {
+	names := make([]string, 0, len(signals))
+	for name := range signals {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SignalName returns the name of any signal, e.g. SIGSEGV for 11.
+func SignalName(signal syscall.Signal) string {
+	if name := unix.SignalName(signal); name != "" {
+		return name
+	}
+	return fmt.Sprintf("signal %d", int(signal))
+}
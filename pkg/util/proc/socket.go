@@ -0,0 +1,210 @@
+package proc
+
+import (
+	"errors"
+	"io/fs"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/prometheus/procfs"
+)
+
+const (
+	// tcpListen is the state of listening TCP sockets in /proc/net/tcp.
+	tcpListen = 0x0a
+	// udpUnconnected is the state of UDP sockets which are bound but not
+	// connected, the ones receiving from any peer.
+	udpUnconnected = 0x07
+	// unixAcceptConnections flags listening unix stream sockets.
+	unixAcceptConnections = 1 << 16
+	unixDatagram          = 2
+)
+
+// Socket is a socket a process is listening on.
+type Socket struct {
+	// Protocol is tcp, tcp6, udp, udp6 or unix.
+	Protocol string
+	// Address is the local IP address, or the path of unix sockets, with a
+	// leading @ for abstract ones.
+	Address string
+	Port    int
+	// PID is the process holding the socket, the lowest pid when it is shared,
+	// e.g. by the workers of a preforking server.
+	PID int
+}
+
+// ListeningSockets returns the sockets the processes with pids are listening
+// on, ordered by protocol, address and port. Processes which exited meanwhile
+// are skipped.
+func (r *Reader) ListeningSockets(pids []int) ([]Socket, error) {
+	listening, err := r.listening()
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Ints(pids)
+	var sockets []Socket
+	seen := map[uint64]bool{}
+	for _, pid := range pids {
+		inodes, err := r.socketInodes(pid)
+		if errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrPermission) || errors.Is(err, syscall.ESRCH) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, inode := range inodes {
+			socket, ok := listening[inode]
+			if !ok || seen[inode] {
+				continue
+			}
+			seen[inode] = true
+			socket.PID = pid
+			sockets = append(sockets, socket)
+		}
+	}
+
+	sort.Slice(sockets, func(i, j int) bool {
+		a, b := sockets[i], sockets[j]
+		if a.Protocol != b.Protocol {
+			return a.Protocol < b.Protocol
+		}
+		if a.Address != b.Address {
+			return a.Address < b.Address
+		}
+		return a.Port < b.Port
+	})
+	return sockets, nil
+}
+
+// listening returns the listening sockets of the network namespace of the
+// reader by inode. Tables of disabled protocols, e.g. IPv6, are skipped.
+func (r *Reader) listening() (map[uint64]Socket, error) {
+	sockets := map[uint64]Socket{}
+
+	ipTables := []struct {
+		protocol string
+		state    uint64
+		read     func() ([]ipSocket, error)
+	}{
+		{"tcp", tcpListen, func() ([]ipSocket, error) { return tcpSockets(r.fs.NetTCP()) }},
+		{"tcp6", tcpListen, func() ([]ipSocket, error) { return tcpSockets(r.fs.NetTCP6()) }},
+		{"udp", udpUnconnected, func() ([]ipSocket, error) { return udpSockets(r.fs.NetUDP()) }},
+		{"udp6", udpUnconnected, func() ([]ipSocket, error) { return udpSockets(r.fs.NetUDP6()) }},
+	}
+	for _, table := range ipTables {
+		lines, err := table.read()
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range lines {
+			if line.state != table.state || line.inode == 0 {
+				continue
+			}
+			// Connected UDP sockets share the state of unconnected ones, they
+			// only receive from their peer.
+			if !line.remoteAddress.IsUnspecified() || line.remotePort != 0 {
+				continue
+			}
+			sockets[line.inode] = Socket{
+				Protocol: table.protocol,
+				Address:  line.address.String(),
+				Port:     int(line.port),
+			}
+		}
+	}
+
+	unix, err := r.fs.NetUNIX()
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	if unix != nil {
+		for _, line := range unix.Rows {
+			listening := line.Flags&unixAcceptConnections != 0 || (line.Type == unixDatagram && line.Path != "")
+			if !listening || line.Inode == 0 {
+				continue
+			}
+			sockets[line.Inode] = Socket{
+				Protocol: "unix",
+				Address:  line.Path,
+			}
+		}
+	}
+	return sockets, nil
+}
+
+// socketInodes returns the inodes of the sockets open in the process with pid.
+func (r *Reader) socketInodes(pid int) ([]uint64, error) {
+	p, err := r.fs.Proc(pid)
+	if err != nil {
+		return nil, err
+	}
+	targets, err := p.FileDescriptorTargets()
+	if err != nil {
+		return nil, err
+	}
+
+	var inodes []uint64
+	for _, target := range targets {
+		if !strings.HasPrefix(target, "socket:[") {
+			continue
+		}
+		inode, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(target, "socket:["), "]"), 10, 64)
+		if err == nil {
+			inodes = append(inodes, inode)
+		}
+	}
+	return inodes, nil
+}
+
+// ipSocket is a line of /proc/net/{tcp,udp}{,6}.
+type ipSocket struct {
+	address       net.IP
+	port          uint64
+	remoteAddress net.IP
+	remotePort    uint64
+	state         uint64
+	inode         uint64
+}
+
+func tcpSockets(table procfs.NetTCP, err error) ([]ipSocket, error) {
+	if err != nil {
+		return nil, err
+	}
+	sockets := make([]ipSocket, 0, len(table))
+	for _, line := range table {
+		sockets = append(sockets, ipSocket{
+			address:       line.LocalAddr,
+			port:          line.LocalPort,
+			remoteAddress: line.RemAddr,
+			remotePort:    line.RemPort,
+			state:         line.St,
+			inode:         line.Inode,
+		})
+	}
+	return sockets, nil
+}
+
+func udpSockets(table procfs.NetUDP, err error) ([]ipSocket, error) {
+	if err != nil {
+		return nil, err
+	}
+	sockets := make([]ipSocket, 0, len(table))
+	for _, line := range table {
+		sockets = append(sockets, ipSocket{
+			address:       line.LocalAddr,
+			port:          line.LocalPort,
+			remoteAddress: line.RemAddr,
+			remotePort:    line.RemPort,
+			state:         line.St,
+			inode:         line.Inode,
+		})
+	}
+	return sockets, nil
+}
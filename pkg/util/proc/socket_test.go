@@ -0,0 +1,143 @@
+package proc
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// allFixtureSockets are the inodes of every socket in testdata/net, listening
+// or not.
+var allFixtureSockets = []string{
+	"socket:[1001]", "socket:[1002]", "socket:[1003]", "socket:[1004]",
+	"socket:[2001]", "socket:[2002]", "socket:[2003]",
+	"socket:[3001]", "socket:[3002]", "socket:[3003]",
+	"socket:[4001]", "socket:[4002]",
+	"socket:[5001]", "socket:[5002]", "socket:[5003]", "socket:[5004]", "socket:[5005]", "socket:[5006]",
+}
+
+func TestListeningSockets(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		// tables are the files of testdata/net present, all when nil.
+		tables []string
+		// fds are the targets of the file descriptors of each process.
+		fds  map[int][]string
+		pids []int
+		want []Socket
+	}{{
+		name: "listening sockets of all protocols",
+		fds:  map[int][]string{100: append([]string{"/dev/null", "pipe:[7]", "anon_inode:[eventpoll]", "socket:[9999]"}, allFixtureSockets...)},
+		pids: []int{100},
+		want: []Socket{
+			{Protocol: "tcp", Address: "0.0.0.0", Port: 22, PID: 100},
+			{Protocol: "tcp", Address: "127.0.0.1", Port: 8080, PID: 100},
+			{Protocol: "tcp", Address: "127.0.0.1", Port: 8081, PID: 100},
+			{Protocol: "tcp6", Address: "::", Port: 22, PID: 100},
+			{Protocol: "tcp6", Address: "::1", Port: 8080, PID: 100},
+			{Protocol: "udp", Address: "0.0.0.0", Port: 53, PID: 100},
+			{Protocol: "udp6", Address: "::", Port: 53, PID: 100},
+			{Protocol: "unix", Address: "/run/app.sock", PID: 100},
+			{Protocol: "unix", Address: "/run/log.sock", PID: 100},
+			{Protocol: "unix", Address: "@app", PID: 100},
+		},
+	}, {
+		name: "connected and unbound sockets",
+		fds: map[int][]string{100: {
+			"socket:[1003]", "socket:[2003]", "socket:[3002]", "socket:[3003]", "socket:[4002]",
+			"socket:[5002]", "socket:[5004]", "socket:[5006]",
+		}},
+		pids: []int{100},
+	}, {
+		name: "shared socket is reported for the lowest pid",
+		fds: map[int][]string{
+			200: {"socket:[1004]"},
+			300: {"socket:[1004]", "socket:[5001]"},
+		},
+		pids: []int{300, 200},
+		want: []Socket{
+			{Protocol: "tcp", Address: "127.0.0.1", Port: 8081, PID: 200},
+			{Protocol: "unix", Address: "/run/app.sock", PID: 300},
+		},
+	}, {
+		name: "exited processes are skipped",
+		fds:  map[int][]string{100: {"socket:[1001]"}},
+		pids: []int{100, 101},
+		want: []Socket{{Protocol: "tcp", Address: "0.0.0.0", Port: 22, PID: 100}},
+	}, {
+		name: "processes without sockets",
+		fds:  map[int][]string{100: {"/dev/null"}, 101: nil},
+		pids: []int{100, 101},
+	}, {
+		name:   "ipv6 disabled",
+		tables: []string{"tcp", "udp", "unix"},
+		fds:    map[int][]string{100: allFixtureSockets},
+		pids:   []int{100},
+		want: []Socket{
+			{Protocol: "tcp", Address: "0.0.0.0", Port: 22, PID: 100},
+			{Protocol: "tcp", Address: "127.0.0.1", Port: 8080, PID: 100},
+			{Protocol: "tcp", Address: "127.0.0.1", Port: 8081, PID: 100},
+			{Protocol: "udp", Address: "0.0.0.0", Port: 53, PID: 100},
+			{Protocol: "unix", Address: "/run/app.sock", PID: 100},
+			{Protocol: "unix", Address: "/run/log.sock", PID: 100},
+			{Protocol: "unix", Address: "@app", PID: 100},
+		},
+	}, {
+		name:   "no tables",
+		tables: []string{},
+		fds:    map[int][]string{100: allFixtureSockets},
+		pids:   []int{100},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := NewReader(fixtureProc(t, tc.tables, tc.fds))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := r.ListeningSockets(tc.pids)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+// fixtureProc returns a procfs mount point holding the tables of testdata/net
+// and processes with the given file descriptors.
+func fixtureProc(t *testing.T, tables []string, fds map[int][]string) string {
+	t.Helper()
+	root := t.TempDir()
+
+	if tables == nil {
+		tables = []string{"tcp", "tcp6", "udp", "udp6", "unix"}
+	}
+	if err := os.Mkdir(filepath.Join(root, "net"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, table := range tables {
+		data, err := os.ReadFile(filepath.Join("testdata", "net", table))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(root, "net", table), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for pid, targets := range fds {
+		dir := filepath.Join(root, strconv.Itoa(pid), "fd")
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		for i, target := range targets {
+			if err := os.Symlink(target, filepath.Join(dir, strconv.Itoa(i))); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	return root
+}
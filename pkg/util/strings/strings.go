@@ -0,0 +1,15 @@
+// Package strings holds string helpers shared by the agent controllers.
+package strings
+
+import "unicode/utf8"
+
+// Truncate shortens s to at most n bytes, without splitting a character.
+func Truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,25 @@
+package strings
+
+import "testing"
+
+func TestTruncate(t *testing.T) {
+	for _, tc := range []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{"", 3, ""},
+		{"abc", 3, "abc"},
+		{"abcd", 3, "abc"},
+		{"abc", 0, ""},
+		// é is 2 bytes, ✓ is 3.
+		{"aé", 2, "a"},
+		{"aé", 3, "aé"},
+		{"✓✓", 4, "✓"},
+		{"✓", 2, ""},
+	} {
+		if got := Truncate(tc.s, tc.n); got != tc.want {
+			t.Errorf("Truncate(%q, %d) = %q, want %q", tc.s, tc.n, got, tc.want)
+		}
+	}
+}
@@ -7,8 +7,8 @@ import (
 
 // TemplateArgs represents the full set of arguments required to render the resources
 type TemplateArgs struct {
-	Name                 string
-	LatestResourceSchema string
+	Name                  string
+	LatestResourceSchemas []string
 }
 
 // RenderTemplate renders the resources rendered